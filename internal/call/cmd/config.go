@@ -0,0 +1,13 @@
+package main
+
+import (
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/tracing"
+)
+
+// Config is the configuration of call service.
+type Config struct {
+	Port     int             `config:"port" env:"CALL_PORT" required:"true" min:"1" max:"65535" usage:"HTTP port"`
+	Tracing  tracing.Config  `config:"tracing"`
+	Shutdown graceful.Config `config:"shutdown"`
+}
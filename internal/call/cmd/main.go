@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/mux"
 	"golang.org/x/exp/slog"
@@ -8,28 +9,56 @@ import (
 	"net/http"
 	"os"
 	"our-little-chatik/internal/call/internal"
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/health"
+	"our-little-chatik/internal/pkg/metrics"
+	"our-little-chatik/internal/pkg/tracing"
+	"strconv"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
 	internal.AllRooms.Init()
 
-	port := os.Getenv("CALL_PORT")
-	if port == "" {
-		panic("empty CALL_PORT provided")
-	}
+	cfg := Config{}
+	config.MustLoad("call", &cfg)
 
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 
+	shutdownTracing, err := tracing.Init(context.Background(), "call", cfg.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	//http.HandleFunc("/create", internal.CreateRoomRequestHandler)
 	//http.HandleFunc("/join", internal.JoinRoomRequestHandler)
 
 	r := mux.NewRouter()
+	r.Use(metrics.MuxMiddleware, tracing.MuxMiddleware)
+	r.Handle(metrics.Path, metrics.Handler())
+	// The rooms live in the memory of the service, it has no dependencies to check.
+	r.Handle(health.LivePath, health.LiveHandler())
+	r.Handle(health.ReadyPath, health.NewChecker())
 	r.HandleFunc("/video/call", internal.CreateOrJoinRoomHandler)
 	r.HandleFunc("/video/finish", internal.DeleteRoomHandler).Methods("DELETE")
 
-	slog.Info(fmt.Sprintf("Starting Server on Port %s", port))
-	err := http.ListenAndServe(":"+port, r)
-	if err != nil {
-		log.Fatal(err)
+	srv := &http.Server{
+		Handler: r,
+		Addr:    ":" + strconv.Itoa(cfg.Port),
 	}
+	// The websockets are hijacked from the server, so it does not wait for them.
+	srv.RegisterOnShutdown(internal.AllRooms.CloseAll)
+
+	ctx, stop := graceful.NotifyContext(context.Background())
+	defer stop()
+
+	slog.Info(fmt.Sprintf("Starting Server on Port %d", cfg.Port))
+	return graceful.Run(ctx, cfg.Shutdown.Timeout, graceful.HTTP("http", srv))
 }
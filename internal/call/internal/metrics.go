@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The gauges are computed from AllRooms on every scrape.
+var (
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "call_live_rooms",
+		Help: "Number of the live call rooms.",
+	}, func() float64 {
+		rooms, _ := AllRooms.Count()
+		return float64(rooms)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "call_participants",
+		Help: "Number of the participants in all the call rooms.",
+	}, func() float64 {
+		_, participants := AllRooms.Count()
+		return float64(participants)
+	})
+)
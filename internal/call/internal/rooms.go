@@ -4,6 +4,7 @@ import (
 	"github.com/gorilla/websocket"
 	"log"
 	"sync"
+	"time"
 )
 
 // Participant describes a single entity in the hashmap
@@ -67,6 +68,17 @@ func (r *RoomMap) InsertIntoRoom(roomID string, host bool, conn *websocket.Conn)
 	r.Map[roomID] = append(r.Map[roomID], p)
 }
 
+// Count returns the number of the rooms and the participants in all of them
+func (r *RoomMap) Count() (rooms int, participants int) {
+	r.Mutex.RLock()
+	defer r.Mutex.RUnlock()
+
+	for _, roomParticipants := range r.Map {
+		participants += len(roomParticipants)
+	}
+	return len(r.Map), participants
+}
+
 // DeleteRoom deletes the room with the roomID
 func (r *RoomMap) DeleteRoom(roomID string) {
 	r.Mutex.Lock()
@@ -74,3 +86,24 @@ func (r *RoomMap) DeleteRoom(roomID string) {
 
 	delete(r.Map, roomID)
 }
+
+// CloseAll sends the going away close frame to every participant, so that they
+// rejoin the call on another instance, and removes all the rooms
+func (r *RoomMap) CloseAll() {
+	r.Mutex.Lock()
+	defer r.Mutex.Unlock()
+
+	goingAway := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+	for roomID, participants := range r.Map {
+		for _, p := range participants {
+			p.Mutex.Lock()
+			err := p.Conn.WriteControl(websocket.CloseMessage, goingAway, time.Now().Add(time.Second))
+			p.Mutex.Unlock()
+			if err != nil {
+				log.Println("failed to write close frame", err)
+			}
+			p.Conn.Close()
+		}
+		delete(r.Map, roomID)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/tracing"
+	"our-little-chatik/internal/pkg/validator"
+)
+
+// Config is the configuration of chat service.
+type Config struct {
+	Port     int             `config:"port" env:"CHAT_PORT" required:"true" min:"1" max:"65535" usage:"HTTP port"`
+	GRPCPort string          `config:"grpc_port" env:"GRPC_CHATS_SERVER_PORT" required:"true" usage:"gRPC server address in the :port form"`
+	Auth     config.Auth     `config:"auth"`
+	Database config.Database `config:"database"`
+	Redis    config.Redis    `config:"redis"`
+	Users    UsersConfig     `config:"users"`
+	Tracing  tracing.Config  `config:"tracing"`
+	Shutdown graceful.Config `config:"shutdown"`
+}
+
+// UsersConfig is the address of the gRPC server of users service.
+type UsersConfig struct {
+	Host string `config:"host" env:"GRPC_USERS_SERVER_HOST" required:"true"`
+	Port string `config:"port" env:"GRPC_USERS_SERVER_PORT" required:"true" usage:"port in the :port form"`
+}
+
+func (c *Config) Validate(v *validator.Validator) {
+	v.Check(c.GRPCPort == "" || config.IsPortAddr(c.GRPCPort), "grpc_port", "must be in the :port form")
+	v.Check(c.Users.Port == "" || config.IsPortAddr(c.Users.Port), "users.port", "must be in the :port form")
+}
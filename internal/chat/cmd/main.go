@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
@@ -14,9 +14,14 @@ import (
 	"os"
 	middleware2 "our-little-chatik/internal/middleware"
 	"our-little-chatik/internal/pkg"
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/health"
+	"our-little-chatik/internal/pkg/metrics"
+	"our-little-chatik/internal/pkg/proto/chats"
 	"our-little-chatik/internal/pkg/proto/users"
+	"our-little-chatik/internal/pkg/tracing"
 	"strconv"
-	"time"
 
 	"our-little-chatik/internal/chat/internal/delivery"
 	"our-little-chatik/internal/chat/internal/repo"
@@ -25,133 +30,40 @@ import (
 	"golang.org/x/exp/slog"
 )
 
-type AppConfig struct {
-	Port  int
-	Redis RedisConfig
-}
-
-type RedisConfig struct {
-	Host     string
-	Port     string
-	Password string
-}
-
-type dbConfig struct {
-	dsn          string
-	maxOpenConns int
-	maxIdleConns int
-	maxIdleTime  time.Duration
-}
-
-var (
-	defaultMaxOpenConns = 10
-	defaultMaxIdleConns = 10
-	defaultMaxIdleTime  = time.Minute * 10
-)
-
-func lookUpDatabaseConfig() *dbConfig {
-	dbCfg := &dbConfig{}
-	key, ok := os.LookupEnv("DATABASE_URL")
-	if !ok {
-		panic(errors.New("connection string not found"))
-	}
-	dbCfg.dsn = key
-
-	key, ok = os.LookupEnv("DATABASE_MAX_OPEN_CONNS")
-	if !ok {
-		dbCfg.maxOpenConns = defaultMaxOpenConns
-	} else {
-		val, err := strconv.Atoi(key)
-		if err != nil {
-			panic(err.Error())
-		}
-		dbCfg.maxOpenConns = val
-	}
-
-	key, ok = os.LookupEnv("DATABASE_MAX_IDLE_CONNS")
-	if !ok {
-		dbCfg.maxIdleConns = defaultMaxIdleConns
-	} else {
-		val, err := strconv.Atoi(key)
-		if err != nil {
-			panic(err.Error())
-		}
-		dbCfg.maxIdleConns = val
-	}
-
-	key, ok = os.LookupEnv("DATABASE_MAX_IDLE_TIME")
-	if !ok {
-		dbCfg.maxIdleTime = defaultMaxIdleTime
-	} else {
-		duration, err := time.ParseDuration(key)
-		if err != nil {
-			panic(err.Error())
-		}
-		dbCfg.maxIdleTime = duration
-	}
-
-	return dbCfg
-}
-
 func main() {
-	log.Fatal(run())
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func run() error {
-	var err error
-	appConfig := AppConfig{}
-	appConfig.Port, err = strconv.Atoi(os.Getenv("CHAT_PORT"))
-	if err != nil {
-		panic(err.Error())
-	}
-	redisHost := os.Getenv("REDIS_HOST")
-	if redisHost == "" {
-		panic("empty redis host")
-	}
-	redisPort := os.Getenv("REDIS_PORT")
-	if redisPort == "" {
-		panic("empty redis port")
-	}
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	if redisPassword == "" {
-		panic("empty redis password")
-	}
-
-	appConfig.Redis.Port = redisPort
-	appConfig.Redis.Host = redisHost
-	appConfig.Redis.Password = redisPassword
+	cfg := Config{}
+	config.MustLoad("chat", &cfg)
 
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 
+	shutdownTracing, err := tracing.Init(context.Background(), "chat", cfg.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	redisClient := redis.NewClient(&redis.Options{
-		Addr:     appConfig.Redis.Host + ":" + appConfig.Redis.Port,
-		Password: appConfig.Redis.Password,
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
 	})
 
-	dbCfg := lookUpDatabaseConfig()
-	db, err := sql.Open("pgx", dbCfg.dsn)
+	db, err := sql.Open("pgx", cfg.Database.URL)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
+	cfg.Database.Configure(db)
 
-	db.SetConnMaxIdleTime(dbCfg.maxIdleTime)
-	db.SetMaxIdleConns(dbCfg.maxIdleConns)
-	db.SetMaxOpenConns(dbCfg.maxOpenConns)
-
-	usersGRPCHost := os.Getenv("GRPC_USERS_SERVER_HOST")
-	if usersGRPCHost == "" {
-		panic("no variable GRPC_USERS_SERVER_HOST passed")
-	}
-	usersGRPCPort := os.Getenv("GRPC_USERS_SERVER_PORT")
-	if usersGRPCPort == "" {
-		panic("no variable GRPC_USERS_SERVER_PORT passed")
-	}
-
-	usersGRPCServerAddr := usersGRPCHost + usersGRPCPort
 	// Set up a connection to the server.
-	conn, err := grpc.Dial(usersGRPCServerAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(cfg.Users.Host+cfg.Users.Port,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor(), tracing.UnaryClientInterceptor()))
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -161,8 +73,9 @@ func run() error {
 	usersClient := repo.NewUserDataClient(usersGRPCCl)
 	repoPostgres := repo.NewPostgresRepo(db)
 	repoRedis := repo.NewRedisRepo(redisClient)
-	uc := usecase.NewChatUseCase(repoPostgres, repoRedis, usersClient)
+	uc := usecase.NewChatUseCase(repoPostgres, repoRedis, usersClient, repoRedis)
 	handler := delivery.NewChatEchoHandler(uc)
+	grpcHandler := delivery.NewChatGRPCHandler(uc)
 
 	e := echo.New()
 	// Middleware
@@ -170,31 +83,48 @@ func run() error {
 		Format: "method=${method}, uri=${uri}, status=${status}\n",
 	}))
 	e.Use(middleware.Recover())
+	e.Use(metrics.EchoMiddleware())
+	e.Use(tracing.EchoMiddleware())
+	e.GET(metrics.Path, echo.WrapHandler(metrics.Handler()))
+
+	checker := health.NewChecker()
+	checker.Add("postgres", health.SQL(db))
+	checker.Add("redis", health.Redis(redisClient))
+	checker.Add("users", health.GRPC(conn, users.Users_ServiceDesc.ServiceName))
+	e.GET(health.LivePath, echo.WrapHandler(health.LiveHandler()))
+	e.GET(health.ReadyPath, echo.WrapHandler(checker))
 
 	// Restricted group
 	r := e.Group("/api/v1")
 
-	key, err := pkg.GetSignedKey()
-	if err != nil {
-		panic(err.Error())
-	}
-
 	// Configure middleware with the custom claims type
-	config := echojwt.Config{
+	jwtConfig := echojwt.Config{
 		NewClaimsFunc: func(c echo.Context) jwt.Claims {
 			return new(pkg.JwtCustomClaims)
 		},
-		SigningKey:  []byte(key),
+		SigningKey:  []byte(cfg.Auth.JWTKey),
 		TokenLookup: "cookie:Token",
 	}
-	r.Use(echojwt.WithConfig(config), middleware2.Auth)
+	r.Use(echojwt.WithConfig(jwtConfig), middleware2.Auth)
 
 	chatRouter := r.Group("/chat")
 
 	// Get chat info
 	chatRouter.GET("/:id", handler.GetChat)
+	// Delete the chat
+	chatRouter.DELETE("/:id", handler.DeleteChat)
+	// Leave the chat
+	chatRouter.POST("/:id/leave", handler.LeaveChat)
+	// Rename the chat
+	chatRouter.PUT("/:id/name", handler.RenameChat)
 	// Get chat messages
 	chatRouter.GET("/:id/messages", handler.GetChatMessages)
+	// Delete the message
+	chatRouter.DELETE("/:id/messages/:msg_id", handler.DeleteMessage)
+	// Get participants who have read the message
+	chatRouter.GET("/:id/messages/:msg_id/seen", handler.GetSeenBy)
+	// Move the read cursor of the user
+	chatRouter.POST("/:id/read", handler.MarkRead)
 	// Get the list of users chats
 	chatRouter.GET("/list", handler.GetChatList)
 	// Create a new chat
@@ -203,7 +133,23 @@ func run() error {
 	chatRouter.POST("/photo", handler.ChangeChatPhoto)
 	// Add users to chat
 	chatRouter.POST("/users", handler.AddUsersToChat)
-
-	e.Logger.Fatal(e.Start(":" + strconv.Itoa(appConfig.Port)))
-	return nil
+	// Remove users from chat
+	chatRouter.DELETE("/users", handler.RemoveUsersFromChat)
+	// Make the member an admin
+	chatRouter.POST("/:id/admins", handler.PromoteParticipant)
+	// Make the admin a plain member
+	chatRouter.DELETE("/:id/admins/:user_id", handler.DemoteParticipant)
+	// Transfer the ownership of the chat
+	chatRouter.PUT("/:id/owner", handler.TransferOwnership)
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		metrics.UnaryServerInterceptor(), tracing.UnaryServerInterceptor()))
+	chats.RegisterChatsServer(s, grpcHandler)
+	health.RegisterGRPC(s, checker, chats.Chats_ServiceDesc.ServiceName)
+
+	ctx, stop := graceful.NotifyContext(context.Background())
+	defer stop()
+	return graceful.Run(ctx, cfg.Shutdown.Timeout,
+		graceful.Echo("http", e, ":"+strconv.Itoa(cfg.Port)),
+		graceful.GRPC("grpc", s, cfg.GRPCPort))
 }
@@ -0,0 +1,3 @@
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS last_read_msg_id,
+    DROP COLUMN IF EXISTS last_read_at;
\ No newline at end of file
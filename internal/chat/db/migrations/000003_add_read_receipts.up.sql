@@ -0,0 +1,3 @@
+ALTER TABLE chat_participants
+    ADD COLUMN IF NOT EXISTS last_read_msg_id uuid DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS last_read_at bigint DEFAULT NULL;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE chat_participants
+    ADD COLUMN IF NOT EXISTS last_read_at bigint DEFAULT NULL;
+
+UPDATE chat_participants AS cp
+SET last_read_at = m.created_at
+FROM messages AS m
+WHERE m.msg_id = cp.last_read_msg_id;
+
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS last_read_seq;
+
+DROP INDEX IF EXISTS messages_chat_id_seq_idx;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,25 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS seq bigint;
+
+-- Existing messages are numbered in the order they used to be shown.
+UPDATE messages AS m
+SET seq = numbered.seq
+FROM (SELECT msg_id, row_number() OVER (PARTITION BY chat_id ORDER BY created_at, msg_id) AS seq
+      FROM messages) AS numbered
+WHERE m.msg_id = numbered.msg_id;
+
+ALTER TABLE messages
+    ALTER COLUMN seq SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS messages_chat_id_seq_idx ON messages (chat_id, seq);
+
+ALTER TABLE chat_participants
+    ADD COLUMN IF NOT EXISTS last_read_seq bigint DEFAULT NULL;
+
+UPDATE chat_participants AS cp
+SET last_read_seq = m.seq
+FROM messages AS m
+WHERE m.msg_id = cp.last_read_msg_id;
+
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS last_read_at;
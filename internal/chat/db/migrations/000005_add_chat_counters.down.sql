@@ -0,0 +1,8 @@
+ALTER TABLE chats
+    DROP CONSTRAINT IF EXISTS chats_last_msg_id_fkey;
+ALTER TABLE chats
+    ADD CONSTRAINT chats_last_msg_id_fkey FOREIGN KEY (last_msg_id) REFERENCES messages (msg_id);
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS messages_count,
+    DROP COLUMN IF EXISTS last_msg_seq;
@@ -0,0 +1,18 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS last_msg_seq bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS messages_count bigint NOT NULL DEFAULT 0;
+
+-- Deleting the last message of the chat must not be blocked by the reference to it.
+ALTER TABLE chats
+    DROP CONSTRAINT IF EXISTS chats_last_msg_id_fkey;
+ALTER TABLE chats
+    ADD CONSTRAINT chats_last_msg_id_fkey FOREIGN KEY (last_msg_id) REFERENCES messages (msg_id) ON DELETE SET NULL;
+
+UPDATE chats AS c
+SET last_msg_id    = last.msg_id,
+    last_msg_seq   = last.seq,
+    messages_count = last.messages_count
+FROM (SELECT DISTINCT ON (chat_id) chat_id, msg_id, seq, COUNT(*) OVER (PARTITION BY chat_id) AS messages_count
+      FROM messages
+      ORDER BY chat_id, seq DESC) AS last
+WHERE c.chat_id = last.chat_id;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS chat_participants_owner_idx;
+
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS role;
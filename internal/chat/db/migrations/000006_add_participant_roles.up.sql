@@ -0,0 +1,20 @@
+ALTER TABLE chat_participants
+    ADD COLUMN IF NOT EXISTS role varchar NOT NULL DEFAULT 'member'
+        CONSTRAINT chat_participants_role_check CHECK (role IN ('owner', 'admin', 'member'));
+
+-- The creators of the existing chats are unknown, so every chat gets a single owner: the participant
+-- who wrote to it first, or the participant with the smallest id if no participant has written yet,
+-- as the order they joined in is not stored. The rest of the participants stay members.
+UPDATE chat_participants AS cp
+SET role = 'owner'
+FROM (SELECT DISTINCT ON (p.chat_id) p.chat_id, p.participant_id
+      FROM chat_participants AS p
+               LEFT JOIN (SELECT chat_id, sender_id, MIN(seq) AS first_seq
+                          FROM messages
+                          GROUP BY chat_id, sender_id) AS sent
+                         ON sent.chat_id = p.chat_id AND sent.sender_id = p.participant_id
+      ORDER BY p.chat_id, sent.first_seq NULLS LAST, p.participant_id) AS owners
+WHERE cp.chat_id = owners.chat_id
+  AND cp.participant_id = owners.participant_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS chat_participants_owner_idx ON chat_participants (chat_id) WHERE role = 'owner';
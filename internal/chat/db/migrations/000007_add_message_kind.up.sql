@@ -0,0 +1,3 @@
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS kind varchar NOT NULL DEFAULT 'text'
+        CONSTRAINT messages_kind_check CHECK (kind IN ('text', 'chat_created', 'users_added', 'users_removed', 'photo_changed'));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS chats_personal_key_idx;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS personal_key,
+    DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,28 @@
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS kind varchar NOT NULL DEFAULT 'group'
+        CONSTRAINT chats_kind_check CHECK (kind IN ('direct', 'group', 'saved_messages', 'channel')),
+    ADD COLUMN IF NOT EXISTS personal_key varchar DEFAULT NULL;
+
+-- The kinds of the existing chats are told by the number of their participants, the way they used to be.
+UPDATE chats AS c
+SET kind = CASE counted.participants WHEN 1 THEN 'saved_messages' ELSE 'direct' END
+FROM (SELECT chat_id, COUNT(*) AS participants
+      FROM chat_participants
+      GROUP BY chat_id) AS counted
+WHERE c.chat_id = counted.chat_id
+  AND counted.participants <= 2;
+
+-- The key is the sorted ids of the participants. Out of the duplicate chats of the same users
+-- the oldest one gets the key, so it is the one a repeat create returns.
+UPDATE chats AS c
+SET personal_key = oldest.personal_key
+FROM (SELECT DISTINCT ON (keys.personal_key) keys.chat_id, keys.personal_key
+      FROM (SELECT chat_id, string_agg(participant_id::text, ':' ORDER BY participant_id::text) AS personal_key
+            FROM chat_participants
+            GROUP BY chat_id) AS keys
+               JOIN chats AS kc ON kc.chat_id = keys.chat_id
+      WHERE kc.kind IN ('direct', 'saved_messages')
+      ORDER BY keys.personal_key, kc.created_at, keys.chat_id) AS oldest
+WHERE c.chat_id = oldest.chat_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS chats_personal_key_idx ON chats (personal_key);
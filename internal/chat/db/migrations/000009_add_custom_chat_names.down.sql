@@ -0,0 +1,2 @@
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS chat_name_custom;
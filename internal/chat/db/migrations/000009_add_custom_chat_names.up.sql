@@ -0,0 +1,4 @@
+-- The users name their personal chats for themselves, such a name takes precedence over the profile
+-- of the other participant. The names stored so far are the ones set when the chats were created.
+ALTER TABLE chat_participants
+    ADD COLUMN IF NOT EXISTS chat_name_custom boolean NOT NULL DEFAULT false;
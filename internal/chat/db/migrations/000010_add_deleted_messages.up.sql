@@ -0,0 +1,8 @@
+-- The deleted messages are remembered, so that the flusher does not persist again a message
+-- it fetched from the queue before the message was deleted.
+CREATE TABLE IF NOT EXISTS deleted_messages
+(
+    msg_id     uuid   NOT NULL PRIMARY KEY,
+    chat_id    uuid   NOT NULL,
+    deleted_at bigint NOT NULL
+);
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// testDatabaseURLEnv names the postgres the migrations are tested against, the tests are skipped without it.
+const testDatabaseURLEnv = "CHAT_TEST_DATABASE_URL"
+
+// migrationsDB connects to the test database and switches to a schema of its own,
+// which is dropped once the test is over.
+func migrationsDB(t *testing.T) *pgx.Conn {
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" || testing.Short() {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "migrations_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s", schema, schema)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = conn.Exec(ctx, fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		_ = conn.Close(ctx)
+	})
+	return conn
+}
+
+// migrate applies the up migrations with the versions in (from, to].
+func migrate(t *testing.T, conn *pgx.Conn, from, to int) {
+	files, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		var version int
+		if _, err := fmt.Sscanf(filepath.Base(file), "%d_", &version); err != nil {
+			t.Fatal(err)
+		}
+		if version <= from || version > to {
+			continue
+		}
+		query, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Exec(context.Background(), string(query)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+}
+
+func TestAddParticipantRoles(t *testing.T) {
+	conn := migrationsDB(t)
+	ctx := context.Background()
+	migrate(t, conn, 0, 5)
+
+	// the ids are ordered, so the participant with the smallest id is known
+	first, second, third := uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+		uuid.MustParse("00000000-0000-0000-0000-000000000003")
+	withMessages, withoutMessages, firstSenderLeft := uuid.New(), uuid.New(), uuid.New()
+	participants := map[uuid.UUID][]uuid.UUID{
+		withMessages:    {first, second, third},
+		withoutMessages: {third, second},
+		firstSenderLeft: {first, second},
+	}
+	for chatID, users := range participants {
+		if _, err := conn.Exec(ctx, "INSERT INTO chats (chat_id, created_at) VALUES ($1, 0)", chatID); err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range users {
+			if _, err := conn.Exec(ctx, "INSERT INTO chat_participants (chat_id, participant_id, chat_name) VALUES ($1, $2, '')",
+				chatID, user); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	messages := []struct {
+		chatID uuid.UUID
+		sender uuid.UUID
+		seq    int64
+	}{
+		{withMessages, second, 1},
+		{withMessages, first, 2},
+		{firstSenderLeft, third, 1},
+		{firstSenderLeft, second, 2},
+	}
+	for _, msg := range messages {
+		if _, err := conn.Exec(ctx, "INSERT INTO messages (msg_id, chat_id, sender_id, payload, created_at, seq) VALUES ($1, $2, $3, '', 0, $4)",
+			uuid.New(), msg.chatID, msg.sender, msg.seq); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrate(t, conn, 5, 6)
+
+	tests := []struct {
+		name   string
+		chatID uuid.UUID
+		owner  uuid.UUID
+	}{
+		{name: "author of the first message", chatID: withMessages, owner: second},
+		{name: "chat without messages", chatID: withoutMessages, owner: second},
+		{name: "author of the first message left the chat", chatID: firstSenderLeft, owner: second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := conn.Query(ctx, "SELECT participant_id, role FROM chat_participants WHERE chat_id=$1", tt.chatID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			owners := 0
+			for rows.Next() {
+				var user uuid.UUID
+				var role string
+				if err := rows.Scan(&user, &role); err != nil {
+					t.Fatal(err)
+				}
+				switch {
+				case role == "owner":
+					owners++
+					if user != tt.owner {
+						t.Errorf("owner = %v, want %v", user, tt.owner)
+					}
+				case role != "member":
+					t.Errorf("role of %v = %v, want member", user, role)
+				}
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if owners != 1 {
+				t.Errorf("owners = %d, want 1", owners)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"our-little-chatik/internal/chat/internal"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/proto/chats"
+)
+
+type ChatGRPCHandler struct {
+	useCase internal.ChatUseCase
+	chats.UnimplementedChatsServer
+}
+
+func NewChatGRPCHandler(useCase internal.ChatUseCase) *ChatGRPCHandler {
+	return &ChatGRPCHandler{
+		useCase: useCase,
+	}
+}
+
+func (h ChatGRPCHandler) IsChatMember(ctx context.Context,
+	request *chats.ChatMemberRequest) (*chats.ChatMemberResponse, error) {
+	chatID, err := uuid.Parse(request.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// the kind and the role tell the peers whether the user may post to the chat
+	kind, role, status := h.useCase.GetMembership(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID})
+	switch status {
+	case models.OK:
+		return &chats.ChatMemberResponse{IsMember: true, Kind: string(kind), Role: string(role)}, nil
+	case models.NotFound:
+		return &chats.ChatMemberResponse{IsMember: false}, nil
+	default:
+		return nil, fmt.Errorf("failed to check chat membership")
+	}
+}
+
+func (h ChatGRPCHandler) GetUserChats(ctx context.Context,
+	request *chats.GetUserChatsRequest) (*chats.GetUserChatsResponse, error) {
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	chatIDs, status := h.useCase.GetUserChatIDs(ctx, models.User{ID: userID})
+	if status != models.OK {
+		return nil, fmt.Errorf("failed to get user chats")
+	}
+	resp := &chats.GetUserChatsResponse{ChatIDs: make([]string, 0, len(chatIDs))}
+	for _, chatID := range chatIDs {
+		resp.ChatIDs = append(resp.ChatIDs, chatID.String())
+	}
+	return resp, nil
+}
+
+func (h ChatGRPCHandler) MarkRead(ctx context.Context,
+	request *chats.MarkReadRequest) (*chats.MarkReadResponse, error) {
+	chatID, err := uuid.Parse(request.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	msgID, err := uuid.Parse(request.MsgID)
+	if err != nil {
+		return nil, err
+	}
+	// Peers tell apart the messages that do not exist from the failures.
+	switch h.useCase.MarkRead(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: msgID}) {
+	case models.OK:
+		return &chats.MarkReadResponse{}, nil
+	case models.NotFound:
+		return nil, status.Error(codes.NotFound, "message not found in the chat")
+	default:
+		return nil, fmt.Errorf("failed to mark chat as read")
+	}
+}
+
+func (h ChatGRPCHandler) GetLastSeq(ctx context.Context,
+	request *chats.GetLastSeqRequest) (*chats.GetLastSeqResponse, error) {
+	chatID, err := uuid.Parse(request.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	seq, status := h.useCase.GetLastSeq(ctx, models.Chat{ChatID: chatID})
+	if status != models.OK {
+		return nil, fmt.Errorf("failed to get last message seq")
+	}
+	return &chats.GetLastSeqResponse{Seq: seq}, nil
+}
+
+// DeleteMessage deletes the message on behalf of the user, the peers tell apart the messages
+// that do not exist and the messages the user may not delete from the failures.
+func (h ChatGRPCHandler) DeleteMessage(ctx context.Context,
+	request *chats.DeleteMessageRequest) (*chats.DeleteMessageResponse, error) {
+	chatID, err := uuid.Parse(request.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	msgID, err := uuid.Parse(request.MsgID)
+	if err != nil {
+		return nil, err
+	}
+	switch h.useCase.DeleteMessage(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: msgID}) {
+	case models.Deleted:
+		return &chats.DeleteMessageResponse{}, nil
+	case models.NotFound:
+		return nil, status.Error(codes.NotFound, "message not found in the chat")
+	case models.Forbidden:
+		return nil, status.Error(codes.PermissionDenied, "the user may not delete the message")
+	default:
+		return nil, fmt.Errorf("failed to delete message")
+	}
+}
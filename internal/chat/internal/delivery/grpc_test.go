@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"our-little-chatik/internal/chat/internal/mocks/chat"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/proto/chats"
+	"testing"
+)
+
+func TestChatGRPCHandler_DeleteMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testMsg := models.Message{MsgID: uuid.New()}
+	request := &chats.DeleteMessageRequest{
+		ChatID: testChat.ChatID.String(),
+		UserID: testUser.ID.String(),
+		MsgID:  testMsg.MsgID.String(),
+	}
+
+	tests := []struct {
+		name     string
+		request  *chats.DeleteMessageRequest
+		pre      func(usecase *chat.MockChatUseCase)
+		wantErr  bool
+		wantCode codes.Code
+	}{
+		{
+			name:    "deleted",
+			request: request,
+			pre: func(usecase *chat.MockChatUseCase) {
+				usecase.EXPECT().DeleteMessage(gomock.Any(), testChat, testUser, testMsg).Return(models.Deleted)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:    "unknown message",
+			request: request,
+			pre: func(usecase *chat.MockChatUseCase) {
+				usecase.EXPECT().DeleteMessage(gomock.Any(), testChat, testUser, testMsg).Return(models.NotFound)
+			},
+			wantErr:  true,
+			wantCode: codes.NotFound,
+		},
+		{
+			name:    "message of another user",
+			request: request,
+			pre: func(usecase *chat.MockChatUseCase) {
+				usecase.EXPECT().DeleteMessage(gomock.Any(), testChat, testUser, testMsg).Return(models.Forbidden)
+			},
+			wantErr:  true,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "malformed message id",
+			request: &chats.DeleteMessageRequest{
+				ChatID: testChat.ChatID.String(),
+				UserID: testUser.ID.String(),
+				MsgID:  "not-a-uuid",
+			},
+			pre:      func(usecase *chat.MockChatUseCase) {},
+			wantErr:  true,
+			wantCode: codes.Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := chat.NewMockChatUseCase(ctrl)
+			tt.pre(usecase)
+			h := NewChatGRPCHandler(usecase)
+			_, err := h.DeleteMessage(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("DeleteMessage() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+}
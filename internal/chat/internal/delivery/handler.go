@@ -2,6 +2,7 @@ package delivery
 
 import (
 	"context"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/exp/slog"
@@ -15,6 +16,11 @@ import (
 	"time"
 )
 
+var (
+	errNotAllowed     = errors.New("your role does not allow to change the chat")
+	errDirectChatLeft = errors.New("a direct chat cannot be left, delete it instead")
+)
+
 type ChatEchoHandler struct {
 	usecase internal.ChatUseCase
 }
@@ -27,7 +33,7 @@ func NewChatEchoHandler(usecase internal.ChatUseCase) *ChatEchoHandler {
 
 // GetChat godoc
 // @Summary Get chat for its id.
-// @Description get chat for its id.
+// @Description get chat for its id, the chat is not found for the users outside of it.
 // @Param id path int true "Chat ID"
 // @Produce json
 // @Tags chat
@@ -55,25 +61,53 @@ func (ch *ChatEchoHandler) GetChat(c echo.Context) error {
 	}
 
 	chat := models.Chat{ChatID: chatID}
+	user := models.User{ID: c.Get("user_id").(uuid.UUID)}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	var status models.StatusCode
-	chat, status = ch.usecase.GetChat(ctx, chat)
+	chat, status = ch.usecase.GetChat(ctx, chat, user)
 	if status != models.OK {
-		return pkg.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		switch status {
+		case models.NotFound:
+			return pkg.NotFoundResponse(c)
+		default:
+			return pkg.ErrorResponse(c, http.StatusInternalServerError, "internal issue")
+		}
 	}
 
 	response := models.EnvelopIntoHttpResponse(chat, "chat", http.StatusOK)
 	return c.JSON(http.StatusOK, &response)
 }
 
+const (
+	defaultMessagesLimit = 10
+	maxMessagesLimit     = 100
+)
+
+// parseCursor parses the message cursor given either as a seq or as a message id.
+func parseCursor(cursorStr string) (models.Cursor, bool) {
+	if cursorStr == "" {
+		return models.Cursor{}, true
+	}
+	if seq, err := strconv.ParseInt(cursorStr, 10, 64); err == nil {
+		return models.Cursor{Seq: seq}, seq > 0
+	}
+	msgID, err := uuid.Parse(cursorStr)
+	if err != nil {
+		return models.Cursor{}, false
+	}
+	return models.Cursor{MsgID: msgID}, msgID != uuid.Nil
+}
+
 // GetChatMessages godoc
 // @Summary Get chat messages.
-// @Description get chat messages.
-// @Param id path int true "Chat ID"
-// @Param offset query int false "offset"
+// @Description get the window of chat messages from the newest to the oldest, next_cursor continues the window.
+// @Description the chat is not found for the users outside of it.
+// @Param id path string true "Chat ID"
+// @Param before query string false "seq or id of the message to get the older messages for"
+// @Param after query string false "seq or id of the message to get the newer messages for"
 // @Param limit query int false "limit"
 // @Produce json
 // @Tags chat
@@ -95,34 +129,34 @@ func (ch *ChatEchoHandler) GetChatMessages(c echo.Context) error {
 	idStr := c.Param("id")
 	v.Check(idStr != "", "id", "must be provided")
 
-	offsetStr := c.QueryParam("offset")
-	if offsetStr == "" {
-		offsetStr = "0"
-	}
-	limitStr := c.QueryParam("limit")
-	if limitStr == "" {
-		limitStr = "10"
+	limit := int64(defaultMessagesLimit)
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		v.Check(err == nil, "limit", "must be a correct integer value")
+		v.Check(limit > 0 && limit <= maxMessagesLimit, "limit", "must be between 1 and 100")
 	}
 
-	offset, err := strconv.ParseInt(offsetStr, 10, 64)
-	v.Check(err == nil, "offset", "must be a correct integer value")
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	v.Check(err == nil, "limit", "must be a correct integer value")
+	before, ok := parseCursor(c.QueryParam("before"))
+	v.Check(ok, "before", "must be a positive seq or a correct uuid value")
+	after, ok := parseCursor(c.QueryParam("after"))
+	v.Check(ok, "after", "must be a positive seq or a correct uuid value")
+	v.Check(!before.IsSet() || !after.IsSet(), "after", "can't be used together with before")
 
 	if !v.Valid() {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
 
-	opts := models.Opts{Limit: limit, Page: offset}
+	opts := models.Opts{Limit: limit, Before: before, After: after}
 	chatID, err := uuid.Parse(idStr)
 	if err != nil {
 		return pkg.BadRequestResponse(c, err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	chat := models.Chat{ChatID: chatID}
-	msgs, status := ch.usecase.GetChatMessages(ctx, chat, opts)
+	user := models.User{ID: c.Get("user_id").(uuid.UUID)}
+	msgs, status := ch.usecase.GetChatMessages(ctx, chat, user, opts)
 	if status != models.OK {
 		switch status {
 		case models.NotFound:
@@ -133,6 +167,7 @@ func (ch *ChatEchoHandler) GetChatMessages(c echo.Context) error {
 	}
 
 	response := models.EnvelopIntoHttpResponse(msgs, "message_list", http.StatusOK)
+	response.Properties["next_cursor"] = msgs.NextCursor(opts)
 	return c.JSON(http.StatusOK, &response)
 }
 
@@ -156,7 +191,7 @@ func (ch *ChatEchoHandler) GetChatList(c echo.Context) error {
 	userID := c.Get("user_id").(uuid.UUID)
 	user := models.User{ID: userID}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	chats, status := ch.usecase.GetChatList(ctx, user)
@@ -180,8 +215,9 @@ func (ch *ChatEchoHandler) GetChatList(c echo.Context) error {
 // @Produce json
 // @Tags chat
 // @Param request body models.CreateChatRequest true "create chat request"
-// @Success 200 {object} models.HttpResponse
-// @Failure 409 {object} models.HttpResponse
+// @Success 200 {object} models.HttpResponse "the direct chat or the saved messages already exist"
+// @Success 201 {object} models.HttpResponse
+// @Failure 400 {object} models.HttpResponse
 // @Failure 500 {object} models.HttpResponse
 // @Router /chat/new [post]
 func (ch *ChatEchoHandler) PostNewChat(c echo.Context) error {
@@ -204,18 +240,21 @@ func (ch *ChatEchoHandler) PostNewChat(c echo.Context) error {
 	if !v.Valid() {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	input.IssuerID = userID
 	createdChat, status := ch.usecase.CreateChat(ctx, input)
-	if status != models.OK {
-		switch status {
-		case models.Conflict:
-			return pkg.ErrorResponse(c, http.StatusConflict, err.Error())
-		default:
-			return pkg.ErrorResponse(c, http.StatusInternalServerError, err.Error())
-		}
+	switch status {
+	case models.OK:
+	case models.Conflict:
+		// a personal chat is never created twice, the existing one is returned instead
+		response := models.EnvelopIntoHttpResponse(createdChat, "created_chat", http.StatusOK)
+		return c.JSON(http.StatusOK, &response)
+	case models.BadRequest:
+		return pkg.ErrorResponse(c, http.StatusBadRequest, "participants do not match the kind of the chat")
+	default:
+		return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to create chat")
 	}
 
 	response := models.EnvelopIntoHttpResponse(createdChat, "created_chat", http.StatusCreated)
@@ -230,6 +269,8 @@ func (ch *ChatEchoHandler) PostNewChat(c echo.Context) error {
 // @Tags chat
 // @Param request body models.AddUsersToChatRequest true "add users to chat request"
 // @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
 // @Failure 422 {object} models.HttpResponse
 // @Failure 500 {object} models.HttpResponse
 // @Router /chat/users [post]
@@ -240,6 +281,7 @@ func (ch *ChatEchoHandler) AddUsersToChat(c echo.Context) error {
 			slog.Error(err.Error())
 		}
 	}()
+	userID := c.Get("user_id").(uuid.UUID)
 
 	input := models2.AddUsersToChatRequest{}
 	err = c.Bind(&input)
@@ -253,7 +295,7 @@ func (ch *ChatEchoHandler) AddUsersToChat(c echo.Context) error {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	users := make([]models.User, len(input.Participants))
@@ -265,9 +307,11 @@ func (ch *ChatEchoHandler) AddUsersToChat(c echo.Context) error {
 
 	chat := models.Chat{ChatID: *input.ChatID}
 
-	status := ch.usecase.AddUsersToChat(ctx, chat, users...)
+	status := ch.usecase.AddUsersToChat(ctx, chat, models.User{ID: userID}, users...)
 	if status != models.OK {
 		switch status {
+		case models.Forbidden:
+			return pkg.ForbiddenResponse(c, errNotAllowed)
 		case models.NotFound:
 			return pkg.NotFoundResponse(c)
 		default:
@@ -278,6 +322,19 @@ func (ch *ChatEchoHandler) AddUsersToChat(c echo.Context) error {
 	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
 }
 
+// RemoveUsersFromChat godoc
+// @Summary Remove users from chat.
+// @Description remove users from chat, the admins may remove the members and the owner may remove anyone.
+// @Accept json
+// @Produce json
+// @Tags chat
+// @Param request body models.RemoveUsersFromChatRequest true "remove users from chat request"
+// @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/users [delete]
 func (ch *ChatEchoHandler) RemoveUsersFromChat(c echo.Context) error {
 	var err error
 	defer func() {
@@ -285,6 +342,7 @@ func (ch *ChatEchoHandler) RemoveUsersFromChat(c echo.Context) error {
 			slog.Error(err.Error())
 		}
 	}()
+	userID := c.Get("user_id").(uuid.UUID)
 
 	input := models2.RemoveUsersFromChatRequest{}
 	err = c.Bind(&input)
@@ -298,7 +356,7 @@ func (ch *ChatEchoHandler) RemoveUsersFromChat(c echo.Context) error {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	users := make([]models.User, len(input.Participants))
@@ -310,9 +368,16 @@ func (ch *ChatEchoHandler) RemoveUsersFromChat(c echo.Context) error {
 
 	chat := models.Chat{ChatID: *input.ChatID}
 
-	status := ch.usecase.RemoveUserFromChat(ctx, chat, users...)
+	status := ch.usecase.RemoveUserFromChat(ctx, chat, models.User{ID: userID}, users...)
 	if status != models.OK {
-		return pkg.ErrorResponse(c, http.StatusBadRequest, "Failed to add users")
+		switch status {
+		case models.Forbidden:
+			return pkg.ForbiddenResponse(c, errNotAllowed)
+		case models.NotFound:
+			return pkg.NotFoundResponse(c)
+		default:
+			return pkg.ErrorResponse(c, http.StatusBadRequest, "Failed to remove users")
+		}
 	}
 
 	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
@@ -326,6 +391,8 @@ func (ch *ChatEchoHandler) RemoveUsersFromChat(c echo.Context) error {
 // @Tags chat
 // @Param request body models.UpdateChatPhotoURLRequest true "change chat photo url request"
 // @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
 // @Failure 422 {object} models.HttpResponse
 // @Failure 500 {object} models.HttpResponse
 // @Router /chat/photo [post]
@@ -336,6 +403,7 @@ func (ch *ChatEchoHandler) ChangeChatPhoto(c echo.Context) error {
 			slog.Error(err.Error())
 		}
 	}()
+	userID := c.Get("user_id").(uuid.UUID)
 
 	input := models2.UpdateChatPhotoURLRequest{}
 	err = c.Bind(&input)
@@ -349,14 +417,16 @@ func (ch *ChatEchoHandler) ChangeChatPhoto(c echo.Context) error {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	chat := models.Chat{ChatID: *input.ChatID}
 
-	status := ch.usecase.UpdateChatPhotoURL(ctx, chat, *input.PhotoURL)
+	status := ch.usecase.UpdateChatPhotoURL(ctx, chat, models.User{ID: userID}, *input.PhotoURL)
 	if status != models.OK {
 		switch status {
+		case models.Forbidden:
+			return pkg.ForbiddenResponse(c, errNotAllowed)
 		case models.NotFound:
 			return pkg.NotFoundResponse(c)
 		default:
@@ -366,66 +436,404 @@ func (ch *ChatEchoHandler) ChangeChatPhoto(c echo.Context) error {
 	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
 }
 
+// DeleteChat godoc
+// @Summary Delete chat.
+// @Description delete chat along with its messages, only the owner may delete it.
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id} [delete]
 func (ch *ChatEchoHandler) DeleteChat(c echo.Context) error {
-	var err error
-	defer func() {
-		if err != nil {
-			slog.Error(err.Error())
-		}
-	}()
+	userID := c.Get("user_id").(uuid.UUID)
 
 	v := validator.New()
-	idStr := c.QueryParam("chat_id")
-	v.Check(idStr != "", "chat_id", "must be provided")
-
-	chatID, err := uuid.Parse(idStr)
-	v.Check(err == nil, "chat_id", "must be a correct uuid value")
-
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
 	if !v.Valid() {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
 
 	chat := models.Chat{ChatID: chatID}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
-	status := ch.usecase.DeleteChat(ctx, chat)
+	status := ch.usecase.DeleteChat(ctx, chat, models.User{ID: userID})
 	if status != models.Deleted {
-		return pkg.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		switch status {
+		case models.Forbidden:
+			return pkg.ForbiddenResponse(c, errNotAllowed)
+		case models.NotFound:
+			return pkg.NotFoundResponse(c)
+		default:
+			return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to delete chat")
+		}
 	}
 
-	return c.JSON(http.StatusOK, &chat)
+	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
 }
 
+// DeleteMessage godoc
+// @Summary Delete message.
+// @Description delete message of the chat, the sender may delete their messages and the admins may delete any.
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param msg_id path string true "Message ID"
+// @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/messages/{msg_id} [delete]
 func (ch *ChatEchoHandler) DeleteMessage(c echo.Context) error {
+	userID := c.Get("user_id").(uuid.UUID)
+
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	msgID, err := uuid.Parse(c.Param("msg_id"))
+	v.Check(err == nil, "msg_id", "must be a correct uuid value")
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	status := ch.usecase.DeleteMessage(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: msgID})
+	if status != models.Deleted {
+		switch status {
+		case models.Forbidden:
+			return pkg.ForbiddenResponse(c, errNotAllowed)
+		case models.NotFound:
+			return pkg.NotFoundResponse(c)
+		default:
+			return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to delete message")
+		}
+	}
+
+	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
+}
+
+// LeaveChat godoc
+// @Summary Leave chat.
+// @Description leave chat, the owner has to transfer the ownership first unless no one else is in the chat.
+// @Description a direct chat cannot be left, it is deleted instead.
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 409 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/leave [post]
+func (ch *ChatEchoHandler) LeaveChat(c echo.Context) error {
+	userID := c.Get("user_id").(uuid.UUID)
+
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	status := ch.usecase.LeaveChat(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID})
+	switch status {
+	case models.OK, models.Deleted:
+		return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
+	case models.Forbidden:
+		return pkg.ForbiddenResponse(c, errDirectChatLeft)
+	case models.NotFound:
+		return pkg.NotFoundResponse(c)
+	case models.Conflict:
+		return pkg.ErrorResponse(c, http.StatusConflict, "the owner has to transfer the ownership before leaving")
+	default:
+		return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to leave chat")
+	}
+}
+
+// RenameChat godoc
+// @Summary Rename chat.
+// @Description rename chat, a group is renamed for everyone by its admins, a direct chat only for the issuer.
+// @Accept json
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param request body models.RenameChatRequest true "rename chat request"
+// @Success 200 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/name [put]
+func (ch *ChatEchoHandler) RenameChat(c echo.Context) error {
+	userID := c.Get("user_id").(uuid.UUID)
+
+	input := models2.RenameChatRequest{}
+	if err := c.Bind(&input); err != nil {
+		return pkg.ErrorResponse(c, http.StatusBadRequest, "bad body")
+	}
+
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	models2.ValidateRenameChatRequest(v, input)
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	status := ch.usecase.RenameChat(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID}, *input.Name)
+	switch status {
+	case models.OK:
+		return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
+	case models.Forbidden:
+		return pkg.ForbiddenResponse(c, errNotAllowed)
+	case models.NotFound:
+		return pkg.NotFoundResponse(c)
+	default:
+		return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to rename chat")
+	}
+}
+
+// MarkRead godoc
+// @Summary Mark chat as read up to the message.
+// @Description move the read cursor of the user to the message.
+// @Accept json
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param request body models.MarkReadRequest true "mark read request"
+// @Success 200 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/read [post]
+func (ch *ChatEchoHandler) MarkRead(c echo.Context) error {
 	var err error
 	defer func() {
 		if err != nil {
 			slog.Error(err.Error())
 		}
 	}()
+	userID := c.Get("user_id").(uuid.UUID)
+
+	input := models2.MarkReadRequest{}
+	err = c.Bind(&input)
+	if err != nil {
+		return pkg.ErrorResponse(c, http.StatusBadRequest, "bad body")
+	}
 
 	v := validator.New()
-	idStr := c.QueryParam("msg_id")
-	v.Check(idStr == "", "msg_id", "must be provided")
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	models2.ValidateMarkReadRequest(v, input)
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
 
-	msgID, err := uuid.Parse(idStr)
+	status := ch.usecase.MarkRead(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: *input.MsgID})
+	if status != models.OK {
+		switch status {
+		case models.NotFound:
+			return pkg.NotFoundResponse(c)
+		default:
+			return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to mark chat as read")
+		}
+	}
+	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
+}
+
+// GetSeenBy godoc
+// @Summary Get participants who have read the message.
+// @Description get participants who have read the message.
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param msg_id path string true "Message ID"
+// @Success 200 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/messages/{msg_id}/seen [get]
+func (ch *ChatEchoHandler) GetSeenBy(c echo.Context) error {
+	var err error
+	defer func() {
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+	userID := c.Get("user_id").(uuid.UUID)
+
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	msgID, err := uuid.Parse(c.Param("msg_id"))
 	v.Check(err == nil, "msg_id", "must be a correct uuid value")
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	receipts, status := ch.usecase.GetSeenBy(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: msgID})
+	if status != models.OK {
+		switch status {
+		case models.NotFound:
+			return pkg.NotFoundResponse(c)
+		default:
+			return pkg.ErrorResponse(c, http.StatusInternalServerError, "internal issue")
+		}
+	}
+
+	response := models.EnvelopIntoHttpResponse(receipts, "seen_by", http.StatusOK)
+	return c.JSON(http.StatusOK, &response)
+}
 
+// PromoteParticipant godoc
+// @Summary Make the member an admin of the chat.
+// @Description make the member an admin of the chat, only the owner may promote the members.
+// @Accept json
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param request body models.ParticipantRequest true "participant request"
+// @Success 200 {object} models.HttpResponse
+// @Failure 400 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/admins [post]
+func (ch *ChatEchoHandler) PromoteParticipant(c echo.Context) error {
+	userID := c.Get("user_id").(uuid.UUID)
+
+	input := models2.ParticipantRequest{}
+	if err := c.Bind(&input); err != nil {
+		return pkg.ErrorResponse(c, http.StatusBadRequest, "bad body")
+	}
+
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	models2.ValidateParticipantRequest(v, input)
 	if !v.Valid() {
 		return pkg.FailedValidationResponse(c, v.Errors)
 	}
 
-	msg := models.Message{MsgID: msgID}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	status := ch.usecase.PromoteParticipant(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.User{ID: *input.UserID})
+	return roleChangeResponse(c, status)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+// DemoteParticipant godoc
+// @Summary Make the admin a plain member of the chat.
+// @Description make the admin a plain member of the chat, only the owner may demote the admins.
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.HttpResponse
+// @Failure 400 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/admins/{user_id} [delete]
+func (ch *ChatEchoHandler) DemoteParticipant(c echo.Context) error {
+	userID := c.Get("user_id").(uuid.UUID)
+
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	adminID, err := uuid.Parse(c.Param("user_id"))
+	v.Check(err == nil, "user_id", "must be a correct uuid value")
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
-	status := ch.usecase.DeleteMessage(ctx, msg)
-	if status != models.Deleted {
-		return pkg.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	status := ch.usecase.DemoteParticipant(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.User{ID: adminID})
+	return roleChangeResponse(c, status)
+}
+
+// TransferOwnership godoc
+// @Summary Transfer the ownership of the chat.
+// @Description make the participant the owner of the chat, the former owner becomes an admin.
+// @Accept json
+// @Produce json
+// @Tags chat
+// @Param id path string true "Chat ID"
+// @Param request body models.ParticipantRequest true "participant request"
+// @Success 200 {object} models.HttpResponse
+// @Failure 400 {object} models.HttpResponse
+// @Failure 403 {object} models.HttpResponse
+// @Failure 404 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /chat/{id}/owner [put]
+func (ch *ChatEchoHandler) TransferOwnership(c echo.Context) error {
+	userID := c.Get("user_id").(uuid.UUID)
+
+	input := models2.ParticipantRequest{}
+	if err := c.Bind(&input); err != nil {
+		return pkg.ErrorResponse(c, http.StatusBadRequest, "bad body")
 	}
 
-	return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
+	v := validator.New()
+	chatID, err := uuid.Parse(c.Param("id"))
+	v.Check(err == nil, "id", "must be a correct uuid value")
+	models2.ValidateParticipantRequest(v, input)
+	if !v.Valid() {
+		return pkg.FailedValidationResponse(c, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	status := ch.usecase.TransferOwnership(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.User{ID: *input.UserID})
+	return roleChangeResponse(c, status)
+}
+
+// roleChangeResponse replies to a change of a role with its outcome.
+func roleChangeResponse(c echo.Context, status models.StatusCode) error {
+	switch status {
+	case models.OK:
+		return c.JSON(http.StatusOK, &models.HttpResponse{Message: "OK"})
+	case models.Forbidden:
+		return pkg.ForbiddenResponse(c, errNotAllowed)
+	case models.NotFound:
+		return pkg.NotFoundResponse(c)
+	case models.BadRequest:
+		return pkg.ErrorResponse(c, http.StatusBadRequest, "the role of the participant can't be changed so")
+	default:
+		return pkg.ErrorResponse(c, http.StatusInternalServerError, "failed to change the role")
+	}
 }
@@ -33,6 +33,10 @@ func TestChatEchoHandler_AddUsersToChat(t *testing.T) {
 	testUser := models2.User{
 		ID: userID1,
 	}
+	issuerID := uuid.New()
+	testIssuer := models2.User{
+		ID: issuerID,
+	}
 
 	tests := []struct {
 		name           string
@@ -63,10 +67,11 @@ func TestChatEchoHandler_AddUsersToChat(t *testing.T) {
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
 				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.Set("user_id", issuerID)
 				return testEchoCtx, rec
 			},
 			prepare: func(f *fields, input models.AddUsersToChatRequest) {
-				f.usecase.EXPECT().AddUsersToChat(gomock.Any(), tetsChat, testUser).Return(models2.OK)
+				f.usecase.EXPECT().AddUsersToChat(gomock.Any(), tetsChat, testIssuer, testUser).Return(models2.OK)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) error {
 				if recorder.Code != http.StatusOK {
@@ -77,6 +82,40 @@ func TestChatEchoHandler_AddUsersToChat(t *testing.T) {
 			args:    args{},
 			wantErr: false,
 		},
+		{
+			name: "issuer is a plain member",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			prepareRequest: func() models.AddUsersToChatRequest {
+				input := models.AddUsersToChatRequest{
+					ChatID:       &chatID,
+					Participants: []uuid.UUID{userID1},
+				}
+				return input
+			},
+			prepareEchoCtx: func(input models.AddUsersToChatRequest) (echo.Context, *httptest.ResponseRecorder) {
+				inputByte, _ := json.Marshal(&input)
+				e := echo.New()
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(inputByte)))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.Set("user_id", issuerID)
+				return testEchoCtx, rec
+			},
+			prepare: func(f *fields, input models.AddUsersToChatRequest) {
+				f.usecase.EXPECT().AddUsersToChat(gomock.Any(), tetsChat, testIssuer, testUser).Return(models2.Forbidden)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) error {
+				if recorder.Code != http.StatusForbidden {
+					return fmt.Errorf("wrong status code")
+				}
+				return nil
+			},
+			args:    args{},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -112,6 +151,7 @@ func TestChatEchoHandler_ChangeChatPhoto(t *testing.T) {
 	tetsChat := models2.Chat{
 		ChatID: chatID,
 	}
+	issuerID := uuid.New()
 
 	tests := []struct {
 		name           string
@@ -142,10 +182,12 @@ func TestChatEchoHandler_ChangeChatPhoto(t *testing.T) {
 				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 				rec := httptest.NewRecorder()
 				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.Set("user_id", issuerID)
 				return testEchoCtx, rec
 			},
 			prepare: func(f *fields, input models.UpdateChatPhotoURLRequest) {
-				f.usecase.EXPECT().UpdateChatPhotoURL(gomock.Any(), tetsChat, testURL).Return(models2.OK)
+				f.usecase.EXPECT().UpdateChatPhotoURL(gomock.Any(), tetsChat, models2.User{ID: issuerID}, testURL).
+					Return(models2.OK)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) error {
 				if recorder.Code != http.StatusOK {
@@ -252,6 +294,39 @@ func TestChatEchoHandler_PostNewChat(t *testing.T) {
 			args:    args{},
 			wantErr: false,
 		},
+		{
+			name: "direct chat already exists",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			prepareRequest: func() models.CreateChatRequest {
+				return models.CreateChatRequest{
+					Participants: []uuid.UUID{userID, chatID},
+				}
+			},
+			prepareEchoCtx: func(input models.CreateChatRequest) (echo.Context, *httptest.ResponseRecorder) {
+				inputByte, _ := json.Marshal(&input)
+				e := echo.New()
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(inputByte)))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.Set("user_id", userID)
+				return testEchoCtx, rec
+			},
+			prepare: func(f *fields, input models.CreateChatRequest) {
+				f.usecase.EXPECT().CreateChat(gomock.Any(), gomock.Any()).Return(testChat, models2.Conflict)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) error {
+				if recorder.Code != http.StatusOK {
+					return fmt.Errorf("wrong status code %d", recorder.Code)
+				}
+				if !strings.Contains(recorder.Body.String(), chatID.String()) {
+					return fmt.Errorf("existing chat is not returned")
+				}
+				return nil
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -286,6 +361,7 @@ func TestChatEchoHandler_GetChat(t *testing.T) {
 	testChat := models2.Chat{
 		ChatID: chatID,
 	}
+	userID := uuid.New()
 
 	tests := []struct {
 		name           string
@@ -309,11 +385,12 @@ func TestChatEchoHandler_GetChat(t *testing.T) {
 				testEchoCtx := e.NewContext(req, rec)
 				testEchoCtx.SetParamNames("id")
 				testEchoCtx.SetParamValues(chatID.String())
+				testEchoCtx.Set("user_id", userID)
 				return testEchoCtx, rec
 			},
 			prepare: func(f *fields) {
 				f.usecase.EXPECT().
-					GetChat(gomock.Any(), models2.Chat{ChatID: chatID}).
+					GetChat(gomock.Any(), models2.Chat{ChatID: chatID}, models2.User{ID: userID}).
 					Return(testChat, models2.OK)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) error {
@@ -324,6 +401,35 @@ func TestChatEchoHandler_GetChat(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "not a member",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			args: args{},
+			prepareEchoCtx: func() (echo.Context, *httptest.ResponseRecorder) {
+				e := echo.New()
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				rec := httptest.NewRecorder()
+				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.SetParamNames("id")
+				testEchoCtx.SetParamValues(chatID.String())
+				testEchoCtx.Set("user_id", userID)
+				return testEchoCtx, rec
+			},
+			prepare: func(f *fields) {
+				f.usecase.EXPECT().
+					GetChat(gomock.Any(), models2.Chat{ChatID: chatID}, models2.User{ID: userID}).
+					Return(models2.Chat{}, models2.NotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) error {
+				if recorder.Code != http.StatusNotFound {
+					return fmt.Errorf("wrong status code")
+				}
+				return nil
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -425,9 +531,12 @@ func TestChatEchoHandler_GetChatMessages(t *testing.T) {
 	defer ctrl.Finish()
 
 	testOpts := models2.Opts{
-		Page:  0,
 		Limit: 1,
 	}
+	beforeOpts := models2.Opts{
+		Limit:  1,
+		Before: models2.Cursor{Seq: 5},
+	}
 
 	userID := uuid.New()
 
@@ -439,6 +548,7 @@ func TestChatEchoHandler_GetChatMessages(t *testing.T) {
 	testMsg := models2.Message{
 		MsgID:  uuid.New(),
 		ChatID: chatID,
+		Seq:    3,
 	}
 
 	tests := []struct {
@@ -462,19 +572,72 @@ func TestChatEchoHandler_GetChatMessages(t *testing.T) {
 				testEchoCtx := e.NewContext(req, rec)
 				testEchoCtx.SetParamNames("id")
 				testEchoCtx.SetParamValues(chatID.String())
-				testEchoCtx.QueryParams().Set("offset", "0")
 				testEchoCtx.QueryParams().Set("limit", "1")
 				testEchoCtx.Set("user_id", userID)
 				return testEchoCtx, rec
 			},
 			prepare: func(f *fields) {
-				f.usecase.EXPECT().GetChatMessages(gomock.Any(), testChat, testOpts).
+				f.usecase.EXPECT().GetChatMessages(gomock.Any(), testChat, models2.User{ID: userID}, testOpts).
+					Return(models2.Messages{testMsg}, models2.OK)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) error {
+				if recorder.Code != http.StatusOK {
+					return fmt.Errorf("wrong status code")
+				}
+				return nil
+			},
+			wantErr: false,
+		},
+		{
+			name: "older messages",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			prepareEchoCtx: func() (echo.Context, *httptest.ResponseRecorder) {
+				e := echo.New()
+				req := httptest.NewRequest(http.MethodGet, "/?before=5&limit=1", nil)
+				rec := httptest.NewRecorder()
+				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.SetParamNames("id")
+				testEchoCtx.SetParamValues(chatID.String())
+				testEchoCtx.Set("user_id", userID)
+				return testEchoCtx, rec
+			},
+			prepare: func(f *fields) {
+				f.usecase.EXPECT().GetChatMessages(gomock.Any(), testChat, models2.User{ID: userID}, beforeOpts).
 					Return(models2.Messages{testMsg}, models2.OK)
 			},
 			checkResponse: func(recorder *httptest.ResponseRecorder) error {
 				if recorder.Code != http.StatusOK {
 					return fmt.Errorf("wrong status code")
 				}
+				if !strings.Contains(recorder.Body.String(), `"next_cursor":3`) {
+					return fmt.Errorf("wrong next cursor %s", recorder.Body.String())
+				}
+				return nil
+			},
+			wantErr: false,
+		},
+		{
+			name: "both cursors",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			prepareEchoCtx: func() (echo.Context, *httptest.ResponseRecorder) {
+				e := echo.New()
+				req := httptest.NewRequest(http.MethodGet, "/?before=5&after=2", nil)
+				rec := httptest.NewRecorder()
+				testEchoCtx := e.NewContext(req, rec)
+				testEchoCtx.SetParamNames("id")
+				testEchoCtx.SetParamValues(chatID.String())
+				testEchoCtx.Set("user_id", userID)
+				return testEchoCtx, rec
+			},
+			prepare: func(f *fields) {},
+			checkResponse: func(recorder *httptest.ResponseRecorder) error {
+				if recorder.Code != http.StatusUnprocessableEntity {
+					return fmt.Errorf("wrong status code")
+				}
 				return nil
 			},
 			wantErr: false,
@@ -496,3 +659,344 @@ func TestChatEchoHandler_GetChatMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestChatEchoHandler_PromoteParticipant(t *testing.T) {
+	type fields struct {
+		usecase *chat.MockChatUseCase
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	chatID := uuid.New()
+	ownerID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		fields   fields
+		chatID   string
+		body     string
+		prepare  func(f *fields)
+		wantCode int
+	}{
+		{
+			name: "success",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			chatID: chatID.String(),
+			body:   fmt.Sprintf(`{"user_id":"%s"}`, userID),
+			prepare: func(f *fields) {
+				f.usecase.EXPECT().PromoteParticipant(gomock.Any(), models2.Chat{ChatID: chatID},
+					models2.User{ID: ownerID}, models2.User{ID: userID}).Return(models2.OK)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "issuer is not the owner",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			chatID: chatID.String(),
+			body:   fmt.Sprintf(`{"user_id":"%s"}`, userID),
+			prepare: func(f *fields) {
+				f.usecase.EXPECT().PromoteParticipant(gomock.Any(), models2.Chat{ChatID: chatID},
+					models2.User{ID: ownerID}, models2.User{ID: userID}).Return(models2.Forbidden)
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "no user",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			chatID:   chatID.String(),
+			body:     `{}`,
+			prepare:  func(f *fields) {},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "wrong chat id",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			chatID:   "chat",
+			body:     fmt.Sprintf(`{"user_id":"%s"}`, userID),
+			prepare:  func(f *fields) {},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatEchoHandler{
+				usecase: tt.fields.usecase,
+			}
+			tt.prepare(&tt.fields)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.chatID)
+			c.Set("user_id", ownerID)
+			if err := ch.PromoteParticipant(c); err != nil {
+				t.Errorf("PromoteParticipant() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("PromoteParticipant() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatEchoHandler_RenameChat(t *testing.T) {
+	type fields struct {
+		usecase *chat.MockChatUseCase
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	chatID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		fields   fields
+		body     string
+		prepare  func(f *fields)
+		wantCode int
+	}{
+		{
+			name: "success",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			body: `{"name":"renamed"}`,
+			prepare: func(f *fields) {
+				f.usecase.EXPECT().RenameChat(gomock.Any(), models2.Chat{ChatID: chatID},
+					models2.User{ID: userID}, "renamed").Return(models2.OK)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "member renames a group",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			body: `{"name":"renamed"}`,
+			prepare: func(f *fields) {
+				f.usecase.EXPECT().RenameChat(gomock.Any(), models2.Chat{ChatID: chatID},
+					models2.User{ID: userID}, "renamed").Return(models2.Forbidden)
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "blank name",
+			fields: fields{
+				usecase: chat.NewMockChatUseCase(ctrl),
+			},
+			body:     `{"name":"  "}`,
+			prepare:  func(f *fields) {},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatEchoHandler{
+				usecase: tt.fields.usecase,
+			}
+			tt.prepare(&tt.fields)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(chatID.String())
+			c.Set("user_id", userID)
+			if err := ch.RenameChat(c); err != nil {
+				t.Errorf("RenameChat() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("RenameChat() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatEchoHandler_LeaveChat(t *testing.T) {
+	type fields struct {
+		usecase *chat.MockChatUseCase
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	chatID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		fields   fields
+		status   models2.StatusCode
+		wantCode int
+	}{
+		{
+			name:     "success",
+			fields:   fields{usecase: chat.NewMockChatUseCase(ctrl)},
+			status:   models2.OK,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "last participant deletes the chat",
+			fields:   fields{usecase: chat.NewMockChatUseCase(ctrl)},
+			status:   models2.Deleted,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "owner leaves a group",
+			fields:   fields{usecase: chat.NewMockChatUseCase(ctrl)},
+			status:   models2.Conflict,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "direct chat",
+			fields:   fields{usecase: chat.NewMockChatUseCase(ctrl)},
+			status:   models2.Forbidden,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "not a participant",
+			fields:   fields{usecase: chat.NewMockChatUseCase(ctrl)},
+			status:   models2.NotFound,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatEchoHandler{
+				usecase: tt.fields.usecase,
+			}
+			tt.fields.usecase.EXPECT().LeaveChat(gomock.Any(), models2.Chat{ChatID: chatID},
+				models2.User{ID: userID}).Return(tt.status)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(chatID.String())
+			c.Set("user_id", userID)
+			if err := ch.LeaveChat(c); err != nil {
+				t.Errorf("LeaveChat() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("LeaveChat() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatEchoHandler_MarkRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	chatID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+
+	tests := []struct {
+		name     string
+		status   models2.StatusCode
+		wantCode int
+	}{
+		{
+			name:     "success",
+			status:   models2.OK,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "not a participant",
+			status:   models2.NotFound,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "failure",
+			status:   models2.InternalError,
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := chat.NewMockChatUseCase(ctrl)
+			ch := &ChatEchoHandler{
+				usecase: usecase,
+			}
+			usecase.EXPECT().MarkRead(gomock.Any(), models2.Chat{ChatID: chatID},
+				models2.User{ID: userID}, models2.Message{MsgID: msgID}).Return(tt.status)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/",
+				strings.NewReader(fmt.Sprintf(`{"msg_id": "%s"}`, msgID)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(chatID.String())
+			c.Set("user_id", userID)
+			if err := ch.MarkRead(c); err != nil {
+				t.Errorf("MarkRead() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("MarkRead() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatEchoHandler_GetSeenBy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	chatID := uuid.New()
+	userID := uuid.New()
+	msgID := uuid.New()
+
+	tests := []struct {
+		name     string
+		status   models2.StatusCode
+		wantCode int
+	}{
+		{
+			name:     "success",
+			status:   models2.OK,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "not a participant",
+			status:   models2.NotFound,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := chat.NewMockChatUseCase(ctrl)
+			ch := &ChatEchoHandler{
+				usecase: usecase,
+			}
+			usecase.EXPECT().GetSeenBy(gomock.Any(), models2.Chat{ChatID: chatID},
+				models2.User{ID: userID}, models2.Message{MsgID: msgID}).Return(nil, tt.status)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "msg_id")
+			c.SetParamValues(chatID.String(), msgID.String())
+			c.Set("user_id", userID)
+			if err := ch.GetSeenBy(c); err != nil {
+				t.Errorf("GetSeenBy() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("GetSeenBy() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -2,15 +2,18 @@ package internal
 
 import (
 	"context"
+	"github.com/google/uuid"
 	models2 "our-little-chatik/internal/chat/internal/models"
 	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/seq"
 )
 
 type ChatRepo interface {
 	GetChatMessages(ctx context.Context, chat models.Chat, opts models.Opts) (models.Messages, models.StatusCode)
 	FetchChatList(ctx context.Context, user models.User) ([]models.Chat, models.StatusCode)
 	CreateChat(ctx context.Context, chat models.Chat, chatNames map[string]string) models.StatusCode
-	GetChat(ctx context.Context, chat models.Chat) (models.Chat, models.StatusCode)
+	GetPersonalChat(ctx context.Context, chat models.Chat, user models.User) (models.Chat, models.StatusCode)
+	GetChat(ctx context.Context, chat models.Chat, user models.User) (models.Chat, models.StatusCode)
 	DeleteMessage(ctx context.Context, message models.Message) models.StatusCode
 	DeleteChat(ctx context.Context, chat models.Chat) models.StatusCode
 	RemoveUserFromChat(ctx context.Context,
@@ -19,27 +22,78 @@ type ChatRepo interface {
 		chat models.Chat, chatNames map[string]string, users ...models.User) models.StatusCode
 	UpdateChatPhotoURL(ctx context.Context, chat models.Chat,
 		photoURL string) models.StatusCode
+	IsChatParticipant(ctx context.Context, chat models.Chat,
+		user models.User) (bool, models.StatusCode)
+	GetMessage(ctx context.Context, message models.Message) (models.Message, models.StatusCode)
+	UpdateLastRead(ctx context.Context, chat models.Chat,
+		user models.User, message models.Message) (bool, models.StatusCode)
+	GetSeenBy(ctx context.Context, chat models.Chat,
+		message models.Message) ([]models.ReadReceipt, models.StatusCode)
+	GetLastSeq(ctx context.Context, chat models.Chat) (int64, models.StatusCode)
+	GetParticipantRole(ctx context.Context, chat models.Chat,
+		user models.User) (models.ChatRole, models.StatusCode)
+	GetMembership(ctx context.Context, chat models.Chat,
+		user models.User) (models.ChatKind, models.ChatRole, models.StatusCode)
+	GetUserChatIDs(ctx context.Context, user models.User) ([]uuid.UUID, models.StatusCode)
+	UpdateParticipantRole(ctx context.Context, chat models.Chat,
+		user models.User, role models.ChatRole) models.StatusCode
+	TransferOwnership(ctx context.Context, chat models.Chat,
+		owner models.User, user models.User) models.StatusCode
+	UpdateChatName(ctx context.Context, chat models.Chat,
+		name string, users ...models.User) models.StatusCode
 }
 
 type QueueRepo interface {
 	GetChatMessages(chat models.Chat, opts models.Opts) (models.Messages, models.StatusCode)
+	GetMessage(ctx context.Context, message models.Message) (models.Message, models.StatusCode)
+	GetQueuedMessages(ctx context.Context, chats []models.Chat) ([]models.Messages, models.StatusCode)
+	DeleteMessage(ctx context.Context, message models.Message) models.StatusCode
+	DeleteChatMessages(ctx context.Context, chat models.Chat) models.StatusCode
+	SaveMessage(ctx context.Context, message models.Message) models.StatusCode
+	seq.Counter
+}
+
+type EventBus interface {
+	PublishChatEvent(ctx context.Context, event models.ChatEvent) models.StatusCode
+	PublishChatNotification(ctx context.Context, chat models.Chat,
+		notification models.Notification) models.StatusCode
 }
 
 type ChatUseCase interface {
 	CreateChat(ctx context.Context, chat models2.CreateChatRequest) (models.Chat, models.StatusCode)
-	GetChatMessages(ctx context.Context, chat models.Chat, opts models.Opts) (models.Messages, models.StatusCode)
+	GetChatMessages(ctx context.Context, chat models.Chat, user models.User,
+		opts models.Opts) (models.Messages, models.StatusCode)
 	GetChatList(ctx context.Context, user models.User) ([]models.Chat, models.StatusCode)
-	GetChat(ctx context.Context, chat models.Chat) (models.Chat, models.StatusCode)
-	DeleteChat(ctx context.Context, chat models.Chat) models.StatusCode
-	DeleteMessage(ctx context.Context, message models.Message) models.StatusCode
+	GetChat(ctx context.Context, chat models.Chat, user models.User) (models.Chat, models.StatusCode)
+	DeleteChat(ctx context.Context, chat models.Chat, issuer models.User) models.StatusCode
+	DeleteMessage(ctx context.Context, chat models.Chat,
+		issuer models.User, message models.Message) models.StatusCode
 	RemoveUserFromChat(ctx context.Context,
-		chat models.Chat, users ...models.User) models.StatusCode
+		chat models.Chat, issuer models.User, users ...models.User) models.StatusCode
+	LeaveChat(ctx context.Context, chat models.Chat, user models.User) models.StatusCode
+	RenameChat(ctx context.Context, chat models.Chat, issuer models.User, name string) models.StatusCode
 	AddUsersToChat(ctx context.Context,
-		chat models.Chat, users ...models.User) models.StatusCode
+		chat models.Chat, issuer models.User, users ...models.User) models.StatusCode
 	UpdateChatPhotoURL(ctx context.Context, chat models.Chat,
-		photoURL string) models.StatusCode
+		issuer models.User, photoURL string) models.StatusCode
+	PromoteParticipant(ctx context.Context, chat models.Chat,
+		issuer models.User, user models.User) models.StatusCode
+	DemoteParticipant(ctx context.Context, chat models.Chat,
+		issuer models.User, user models.User) models.StatusCode
+	TransferOwnership(ctx context.Context, chat models.Chat,
+		issuer models.User, user models.User) models.StatusCode
+	GetMembership(ctx context.Context, chat models.Chat,
+		user models.User) (models.ChatKind, models.ChatRole, models.StatusCode)
+	GetUserChatIDs(ctx context.Context, user models.User) ([]uuid.UUID, models.StatusCode)
+	MarkRead(ctx context.Context, chat models.Chat,
+		user models.User, message models.Message) models.StatusCode
+	GetSeenBy(ctx context.Context, chat models.Chat,
+		user models.User, message models.Message) ([]models.ReadReceipt, models.StatusCode)
+	GetLastSeq(ctx context.Context, chat models.Chat) (int64, models.StatusCode)
 }
 
 type UserDataInteractor interface {
-	GetUser(user models.User) (models.User, models.StatusCode)
+	GetUser(ctx context.Context, user models.User) (models.User, models.StatusCode)
+	// GetUsers returns the current profiles of the users, the ones not found are missing from the map.
+	GetUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.User, models.StatusCode)
 }
@@ -14,6 +14,7 @@ import (
 	models0 "our-little-chatik/internal/models"
 	reflect "reflect"
 
+	uuid "github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -117,18 +118,18 @@ func (mr *MockChatRepoMockRecorder) FetchChatList(ctx, user any) *gomock.Call {
 }
 
 // GetChat mocks base method.
-func (m *MockChatRepo) GetChat(ctx context.Context, chat models0.Chat) (models0.Chat, models0.StatusCode) {
+func (m *MockChatRepo) GetChat(ctx context.Context, chat models0.Chat, user models0.User) (models0.Chat, models0.StatusCode) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetChat", ctx, chat)
+	ret := m.ctrl.Call(m, "GetChat", ctx, chat, user)
 	ret0, _ := ret[0].(models0.Chat)
 	ret1, _ := ret[1].(models0.StatusCode)
 	return ret0, ret1
 }
 
 // GetChat indicates an expected call of GetChat.
-func (mr *MockChatRepoMockRecorder) GetChat(ctx, chat any) *gomock.Call {
+func (mr *MockChatRepoMockRecorder) GetChat(ctx, chat, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChat", reflect.TypeOf((*MockChatRepo)(nil).GetChat), ctx, chat)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChat", reflect.TypeOf((*MockChatRepo)(nil).GetChat), ctx, chat, user)
 }
 
 // GetChatMessages mocks base method.
@@ -146,6 +147,127 @@ func (mr *MockChatRepoMockRecorder) GetChatMessages(ctx, chat, opts any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChatMessages", reflect.TypeOf((*MockChatRepo)(nil).GetChatMessages), ctx, chat, opts)
 }
 
+// GetLastSeq mocks base method.
+func (m *MockChatRepo) GetLastSeq(ctx context.Context, chat models0.Chat) (int64, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastSeq", ctx, chat)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetLastSeq indicates an expected call of GetLastSeq.
+func (mr *MockChatRepoMockRecorder) GetLastSeq(ctx, chat any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastSeq", reflect.TypeOf((*MockChatRepo)(nil).GetLastSeq), ctx, chat)
+}
+
+// GetMembership mocks base method.
+func (m *MockChatRepo) GetMembership(ctx context.Context, chat models0.Chat, user models0.User) (models0.ChatKind, models0.ChatRole, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembership", ctx, chat, user)
+	ret0, _ := ret[0].(models0.ChatKind)
+	ret1, _ := ret[1].(models0.ChatRole)
+	ret2, _ := ret[2].(models0.StatusCode)
+	return ret0, ret1, ret2
+}
+
+// GetMembership indicates an expected call of GetMembership.
+func (mr *MockChatRepoMockRecorder) GetMembership(ctx, chat, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembership", reflect.TypeOf((*MockChatRepo)(nil).GetMembership), ctx, chat, user)
+}
+
+// GetMessage mocks base method.
+func (m *MockChatRepo) GetMessage(ctx context.Context, message models0.Message) (models0.Message, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessage", ctx, message)
+	ret0, _ := ret[0].(models0.Message)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetMessage indicates an expected call of GetMessage.
+func (mr *MockChatRepoMockRecorder) GetMessage(ctx, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessage", reflect.TypeOf((*MockChatRepo)(nil).GetMessage), ctx, message)
+}
+
+// GetParticipantRole mocks base method.
+func (m *MockChatRepo) GetParticipantRole(ctx context.Context, chat models0.Chat, user models0.User) (models0.ChatRole, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetParticipantRole", ctx, chat, user)
+	ret0, _ := ret[0].(models0.ChatRole)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetParticipantRole indicates an expected call of GetParticipantRole.
+func (mr *MockChatRepoMockRecorder) GetParticipantRole(ctx, chat, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetParticipantRole", reflect.TypeOf((*MockChatRepo)(nil).GetParticipantRole), ctx, chat, user)
+}
+
+// GetPersonalChat mocks base method.
+func (m *MockChatRepo) GetPersonalChat(ctx context.Context, chat models0.Chat, user models0.User) (models0.Chat, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPersonalChat", ctx, chat, user)
+	ret0, _ := ret[0].(models0.Chat)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetPersonalChat indicates an expected call of GetPersonalChat.
+func (mr *MockChatRepoMockRecorder) GetPersonalChat(ctx, chat, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPersonalChat", reflect.TypeOf((*MockChatRepo)(nil).GetPersonalChat), ctx, chat, user)
+}
+
+// GetSeenBy mocks base method.
+func (m *MockChatRepo) GetSeenBy(ctx context.Context, chat models0.Chat, message models0.Message) ([]models0.ReadReceipt, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeenBy", ctx, chat, message)
+	ret0, _ := ret[0].([]models0.ReadReceipt)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetSeenBy indicates an expected call of GetSeenBy.
+func (mr *MockChatRepoMockRecorder) GetSeenBy(ctx, chat, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeenBy", reflect.TypeOf((*MockChatRepo)(nil).GetSeenBy), ctx, chat, message)
+}
+
+// GetUserChatIDs mocks base method.
+func (m *MockChatRepo) GetUserChatIDs(ctx context.Context, user models0.User) ([]uuid.UUID, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserChatIDs", ctx, user)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetUserChatIDs indicates an expected call of GetUserChatIDs.
+func (mr *MockChatRepoMockRecorder) GetUserChatIDs(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserChatIDs", reflect.TypeOf((*MockChatRepo)(nil).GetUserChatIDs), ctx, user)
+}
+
+// IsChatParticipant mocks base method.
+func (m *MockChatRepo) IsChatParticipant(ctx context.Context, chat models0.Chat, user models0.User) (bool, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsChatParticipant", ctx, chat, user)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// IsChatParticipant indicates an expected call of IsChatParticipant.
+func (mr *MockChatRepoMockRecorder) IsChatParticipant(ctx, chat, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsChatParticipant", reflect.TypeOf((*MockChatRepo)(nil).IsChatParticipant), ctx, chat, user)
+}
+
 // RemoveUserFromChat mocks base method.
 func (m *MockChatRepo) RemoveUserFromChat(ctx context.Context, chat models0.Chat, users ...models0.User) models0.StatusCode {
 	m.ctrl.T.Helper()
@@ -165,6 +287,39 @@ func (mr *MockChatRepoMockRecorder) RemoveUserFromChat(ctx, chat any, users ...a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserFromChat", reflect.TypeOf((*MockChatRepo)(nil).RemoveUserFromChat), varargs...)
 }
 
+// TransferOwnership mocks base method.
+func (m *MockChatRepo) TransferOwnership(ctx context.Context, chat models0.Chat, owner, user models0.User) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", ctx, chat, owner, user)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockChatRepoMockRecorder) TransferOwnership(ctx, chat, owner, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockChatRepo)(nil).TransferOwnership), ctx, chat, owner, user)
+}
+
+// UpdateChatName mocks base method.
+func (m *MockChatRepo) UpdateChatName(ctx context.Context, chat models0.Chat, name string, users ...models0.User) models0.StatusCode {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, chat, name}
+	for _, a := range users {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateChatName", varargs...)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// UpdateChatName indicates an expected call of UpdateChatName.
+func (mr *MockChatRepoMockRecorder) UpdateChatName(ctx, chat, name any, users ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, chat, name}, users...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChatName", reflect.TypeOf((*MockChatRepo)(nil).UpdateChatName), varargs...)
+}
+
 // UpdateChatPhotoURL mocks base method.
 func (m *MockChatRepo) UpdateChatPhotoURL(ctx context.Context, chat models0.Chat, photoURL string) models0.StatusCode {
 	m.ctrl.T.Helper()
@@ -179,6 +334,35 @@ func (mr *MockChatRepoMockRecorder) UpdateChatPhotoURL(ctx, chat, photoURL any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChatPhotoURL", reflect.TypeOf((*MockChatRepo)(nil).UpdateChatPhotoURL), ctx, chat, photoURL)
 }
 
+// UpdateLastRead mocks base method.
+func (m *MockChatRepo) UpdateLastRead(ctx context.Context, chat models0.Chat, user models0.User, message models0.Message) (bool, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastRead", ctx, chat, user, message)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// UpdateLastRead indicates an expected call of UpdateLastRead.
+func (mr *MockChatRepoMockRecorder) UpdateLastRead(ctx, chat, user, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastRead", reflect.TypeOf((*MockChatRepo)(nil).UpdateLastRead), ctx, chat, user, message)
+}
+
+// UpdateParticipantRole mocks base method.
+func (m *MockChatRepo) UpdateParticipantRole(ctx context.Context, chat models0.Chat, user models0.User, role models0.ChatRole) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateParticipantRole", ctx, chat, user, role)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// UpdateParticipantRole indicates an expected call of UpdateParticipantRole.
+func (mr *MockChatRepoMockRecorder) UpdateParticipantRole(ctx, chat, user, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateParticipantRole", reflect.TypeOf((*MockChatRepo)(nil).UpdateParticipantRole), ctx, chat, user, role)
+}
+
 // MockQueueRepo is a mock of QueueRepo interface.
 type MockQueueRepo struct {
 	ctrl     *gomock.Controller
@@ -202,6 +386,34 @@ func (m *MockQueueRepo) EXPECT() *MockQueueRepoMockRecorder {
 	return m.recorder
 }
 
+// DeleteChatMessages mocks base method.
+func (m *MockQueueRepo) DeleteChatMessages(ctx context.Context, chat models0.Chat) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteChatMessages", ctx, chat)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// DeleteChatMessages indicates an expected call of DeleteChatMessages.
+func (mr *MockQueueRepoMockRecorder) DeleteChatMessages(ctx, chat any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChatMessages", reflect.TypeOf((*MockQueueRepo)(nil).DeleteChatMessages), ctx, chat)
+}
+
+// DeleteMessage mocks base method.
+func (m *MockQueueRepo) DeleteMessage(ctx context.Context, message models0.Message) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessage", ctx, message)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// DeleteMessage indicates an expected call of DeleteMessage.
+func (mr *MockQueueRepoMockRecorder) DeleteMessage(ctx, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockQueueRepo)(nil).DeleteMessage), ctx, message)
+}
+
 // GetChatMessages mocks base method.
 func (m *MockQueueRepo) GetChatMessages(chat models0.Chat, opts models0.Opts) (models0.Messages, models0.StatusCode) {
 	m.ctrl.T.Helper()
@@ -217,6 +429,130 @@ func (mr *MockQueueRepoMockRecorder) GetChatMessages(chat, opts any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChatMessages", reflect.TypeOf((*MockQueueRepo)(nil).GetChatMessages), chat, opts)
 }
 
+// GetMessage mocks base method.
+func (m *MockQueueRepo) GetMessage(ctx context.Context, message models0.Message) (models0.Message, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMessage", ctx, message)
+	ret0, _ := ret[0].(models0.Message)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetMessage indicates an expected call of GetMessage.
+func (mr *MockQueueRepoMockRecorder) GetMessage(ctx, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessage", reflect.TypeOf((*MockQueueRepo)(nil).GetMessage), ctx, message)
+}
+
+// GetQueuedMessages mocks base method.
+func (m *MockQueueRepo) GetQueuedMessages(ctx context.Context, chats []models0.Chat) ([]models0.Messages, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQueuedMessages", ctx, chats)
+	ret0, _ := ret[0].([]models0.Messages)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetQueuedMessages indicates an expected call of GetQueuedMessages.
+func (mr *MockQueueRepoMockRecorder) GetQueuedMessages(ctx, chats any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQueuedMessages", reflect.TypeOf((*MockQueueRepo)(nil).GetQueuedMessages), ctx, chats)
+}
+
+// NextMessageSeq mocks base method.
+func (m *MockQueueRepo) NextMessageSeq(ctx context.Context, seqKey string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NextMessageSeq", ctx, seqKey)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NextMessageSeq indicates an expected call of NextMessageSeq.
+func (mr *MockQueueRepoMockRecorder) NextMessageSeq(ctx, seqKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextMessageSeq", reflect.TypeOf((*MockQueueRepo)(nil).NextMessageSeq), ctx, seqKey)
+}
+
+// SaveMessage mocks base method.
+func (m *MockQueueRepo) SaveMessage(ctx context.Context, message models0.Message) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMessage", ctx, message)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// SaveMessage indicates an expected call of SaveMessage.
+func (mr *MockQueueRepoMockRecorder) SaveMessage(ctx, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMessage", reflect.TypeOf((*MockQueueRepo)(nil).SaveMessage), ctx, message)
+}
+
+// SeedMessageSeq mocks base method.
+func (m *MockQueueRepo) SeedMessageSeq(ctx context.Context, chatID uuid.UUID, lastSeq int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SeedMessageSeq", ctx, chatID, lastSeq)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SeedMessageSeq indicates an expected call of SeedMessageSeq.
+func (mr *MockQueueRepoMockRecorder) SeedMessageSeq(ctx, chatID, lastSeq any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SeedMessageSeq", reflect.TypeOf((*MockQueueRepo)(nil).SeedMessageSeq), ctx, chatID, lastSeq)
+}
+
+// MockEventBus is a mock of EventBus interface.
+type MockEventBus struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventBusMockRecorder
+}
+
+// MockEventBusMockRecorder is the mock recorder for MockEventBus.
+type MockEventBusMockRecorder struct {
+	mock *MockEventBus
+}
+
+// NewMockEventBus creates a new mock instance.
+func NewMockEventBus(ctrl *gomock.Controller) *MockEventBus {
+	mock := &MockEventBus{ctrl: ctrl}
+	mock.recorder = &MockEventBusMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEventBus) EXPECT() *MockEventBusMockRecorder {
+	return m.recorder
+}
+
+// PublishChatEvent mocks base method.
+func (m *MockEventBus) PublishChatEvent(ctx context.Context, event models0.ChatEvent) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishChatEvent", ctx, event)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// PublishChatEvent indicates an expected call of PublishChatEvent.
+func (mr *MockEventBusMockRecorder) PublishChatEvent(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishChatEvent", reflect.TypeOf((*MockEventBus)(nil).PublishChatEvent), ctx, event)
+}
+
+// PublishChatNotification mocks base method.
+func (m *MockEventBus) PublishChatNotification(ctx context.Context, chat models0.Chat, notification models0.Notification) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishChatNotification", ctx, chat, notification)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// PublishChatNotification indicates an expected call of PublishChatNotification.
+func (mr *MockEventBusMockRecorder) PublishChatNotification(ctx, chat, notification any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishChatNotification", reflect.TypeOf((*MockEventBus)(nil).PublishChatNotification), ctx, chat, notification)
+}
+
 // MockChatUseCase is a mock of ChatUseCase interface.
 type MockChatUseCase struct {
 	ctrl     *gomock.Controller
@@ -241,9 +577,9 @@ func (m *MockChatUseCase) EXPECT() *MockChatUseCaseMockRecorder {
 }
 
 // AddUsersToChat mocks base method.
-func (m *MockChatUseCase) AddUsersToChat(ctx context.Context, chat models0.Chat, users ...models0.User) models0.StatusCode {
+func (m *MockChatUseCase) AddUsersToChat(ctx context.Context, chat models0.Chat, issuer models0.User, users ...models0.User) models0.StatusCode {
 	m.ctrl.T.Helper()
-	varargs := []any{ctx, chat}
+	varargs := []any{ctx, chat, issuer}
 	for _, a := range users {
 		varargs = append(varargs, a)
 	}
@@ -253,9 +589,9 @@ func (m *MockChatUseCase) AddUsersToChat(ctx context.Context, chat models0.Chat,
 }
 
 // AddUsersToChat indicates an expected call of AddUsersToChat.
-func (mr *MockChatUseCaseMockRecorder) AddUsersToChat(ctx, chat any, users ...any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) AddUsersToChat(ctx, chat, issuer any, users ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{ctx, chat}, users...)
+	varargs := append([]any{ctx, chat, issuer}, users...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUsersToChat", reflect.TypeOf((*MockChatUseCase)(nil).AddUsersToChat), varargs...)
 }
 
@@ -275,46 +611,60 @@ func (mr *MockChatUseCaseMockRecorder) CreateChat(ctx, chat any) *gomock.Call {
 }
 
 // DeleteChat mocks base method.
-func (m *MockChatUseCase) DeleteChat(ctx context.Context, chat models0.Chat) models0.StatusCode {
+func (m *MockChatUseCase) DeleteChat(ctx context.Context, chat models0.Chat, issuer models0.User) models0.StatusCode {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteChat", ctx, chat)
+	ret := m.ctrl.Call(m, "DeleteChat", ctx, chat, issuer)
 	ret0, _ := ret[0].(models0.StatusCode)
 	return ret0
 }
 
 // DeleteChat indicates an expected call of DeleteChat.
-func (mr *MockChatUseCaseMockRecorder) DeleteChat(ctx, chat any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) DeleteChat(ctx, chat, issuer any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChat", reflect.TypeOf((*MockChatUseCase)(nil).DeleteChat), ctx, chat)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteChat", reflect.TypeOf((*MockChatUseCase)(nil).DeleteChat), ctx, chat, issuer)
 }
 
 // DeleteMessage mocks base method.
-func (m *MockChatUseCase) DeleteMessage(ctx context.Context, message models0.Message) models0.StatusCode {
+func (m *MockChatUseCase) DeleteMessage(ctx context.Context, chat models0.Chat, issuer models0.User, message models0.Message) models0.StatusCode {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMessage", ctx, message)
+	ret := m.ctrl.Call(m, "DeleteMessage", ctx, chat, issuer, message)
 	ret0, _ := ret[0].(models0.StatusCode)
 	return ret0
 }
 
 // DeleteMessage indicates an expected call of DeleteMessage.
-func (mr *MockChatUseCaseMockRecorder) DeleteMessage(ctx, message any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) DeleteMessage(ctx, chat, issuer, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockChatUseCase)(nil).DeleteMessage), ctx, chat, issuer, message)
+}
+
+// DemoteParticipant mocks base method.
+func (m *MockChatUseCase) DemoteParticipant(ctx context.Context, chat models0.Chat, issuer, user models0.User) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DemoteParticipant", ctx, chat, issuer, user)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// DemoteParticipant indicates an expected call of DemoteParticipant.
+func (mr *MockChatUseCaseMockRecorder) DemoteParticipant(ctx, chat, issuer, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockChatUseCase)(nil).DeleteMessage), ctx, message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DemoteParticipant", reflect.TypeOf((*MockChatUseCase)(nil).DemoteParticipant), ctx, chat, issuer, user)
 }
 
 // GetChat mocks base method.
-func (m *MockChatUseCase) GetChat(ctx context.Context, chat models0.Chat) (models0.Chat, models0.StatusCode) {
+func (m *MockChatUseCase) GetChat(ctx context.Context, chat models0.Chat, user models0.User) (models0.Chat, models0.StatusCode) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetChat", ctx, chat)
+	ret := m.ctrl.Call(m, "GetChat", ctx, chat, user)
 	ret0, _ := ret[0].(models0.Chat)
 	ret1, _ := ret[1].(models0.StatusCode)
 	return ret0, ret1
 }
 
 // GetChat indicates an expected call of GetChat.
-func (mr *MockChatUseCaseMockRecorder) GetChat(ctx, chat any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) GetChat(ctx, chat, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChat", reflect.TypeOf((*MockChatUseCase)(nil).GetChat), ctx, chat)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChat", reflect.TypeOf((*MockChatUseCase)(nil).GetChat), ctx, chat, user)
 }
 
 // GetChatList mocks base method.
@@ -333,24 +683,127 @@ func (mr *MockChatUseCaseMockRecorder) GetChatList(ctx, user any) *gomock.Call {
 }
 
 // GetChatMessages mocks base method.
-func (m *MockChatUseCase) GetChatMessages(ctx context.Context, chat models0.Chat, opts models0.Opts) (models0.Messages, models0.StatusCode) {
+func (m *MockChatUseCase) GetChatMessages(ctx context.Context, chat models0.Chat, user models0.User, opts models0.Opts) (models0.Messages, models0.StatusCode) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetChatMessages", ctx, chat, opts)
+	ret := m.ctrl.Call(m, "GetChatMessages", ctx, chat, user, opts)
 	ret0, _ := ret[0].(models0.Messages)
 	ret1, _ := ret[1].(models0.StatusCode)
 	return ret0, ret1
 }
 
 // GetChatMessages indicates an expected call of GetChatMessages.
-func (mr *MockChatUseCaseMockRecorder) GetChatMessages(ctx, chat, opts any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) GetChatMessages(ctx, chat, user, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChatMessages", reflect.TypeOf((*MockChatUseCase)(nil).GetChatMessages), ctx, chat, user, opts)
+}
+
+// GetLastSeq mocks base method.
+func (m *MockChatUseCase) GetLastSeq(ctx context.Context, chat models0.Chat) (int64, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastSeq", ctx, chat)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetLastSeq indicates an expected call of GetLastSeq.
+func (mr *MockChatUseCaseMockRecorder) GetLastSeq(ctx, chat any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastSeq", reflect.TypeOf((*MockChatUseCase)(nil).GetLastSeq), ctx, chat)
+}
+
+// GetMembership mocks base method.
+func (m *MockChatUseCase) GetMembership(ctx context.Context, chat models0.Chat, user models0.User) (models0.ChatKind, models0.ChatRole, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembership", ctx, chat, user)
+	ret0, _ := ret[0].(models0.ChatKind)
+	ret1, _ := ret[1].(models0.ChatRole)
+	ret2, _ := ret[2].(models0.StatusCode)
+	return ret0, ret1, ret2
+}
+
+// GetMembership indicates an expected call of GetMembership.
+func (mr *MockChatUseCaseMockRecorder) GetMembership(ctx, chat, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembership", reflect.TypeOf((*MockChatUseCase)(nil).GetMembership), ctx, chat, user)
+}
+
+// GetSeenBy mocks base method.
+func (m *MockChatUseCase) GetSeenBy(ctx context.Context, chat models0.Chat, user models0.User, message models0.Message) ([]models0.ReadReceipt, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSeenBy", ctx, chat, user, message)
+	ret0, _ := ret[0].([]models0.ReadReceipt)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetSeenBy indicates an expected call of GetSeenBy.
+func (mr *MockChatUseCaseMockRecorder) GetSeenBy(ctx, chat, user, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSeenBy", reflect.TypeOf((*MockChatUseCase)(nil).GetSeenBy), ctx, chat, user, message)
+}
+
+// GetUserChatIDs mocks base method.
+func (m *MockChatUseCase) GetUserChatIDs(ctx context.Context, user models0.User) ([]uuid.UUID, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserChatIDs", ctx, user)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetUserChatIDs indicates an expected call of GetUserChatIDs.
+func (mr *MockChatUseCaseMockRecorder) GetUserChatIDs(ctx, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChatMessages", reflect.TypeOf((*MockChatUseCase)(nil).GetChatMessages), ctx, chat, opts)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserChatIDs", reflect.TypeOf((*MockChatUseCase)(nil).GetUserChatIDs), ctx, user)
+}
+
+// LeaveChat mocks base method.
+func (m *MockChatUseCase) LeaveChat(ctx context.Context, chat models0.Chat, user models0.User) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaveChat", ctx, chat, user)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// LeaveChat indicates an expected call of LeaveChat.
+func (mr *MockChatUseCaseMockRecorder) LeaveChat(ctx, chat, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveChat", reflect.TypeOf((*MockChatUseCase)(nil).LeaveChat), ctx, chat, user)
+}
+
+// MarkRead mocks base method.
+func (m *MockChatUseCase) MarkRead(ctx context.Context, chat models0.Chat, user models0.User, message models0.Message) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRead", ctx, chat, user, message)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// MarkRead indicates an expected call of MarkRead.
+func (mr *MockChatUseCaseMockRecorder) MarkRead(ctx, chat, user, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRead", reflect.TypeOf((*MockChatUseCase)(nil).MarkRead), ctx, chat, user, message)
+}
+
+// PromoteParticipant mocks base method.
+func (m *MockChatUseCase) PromoteParticipant(ctx context.Context, chat models0.Chat, issuer, user models0.User) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteParticipant", ctx, chat, issuer, user)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// PromoteParticipant indicates an expected call of PromoteParticipant.
+func (mr *MockChatUseCaseMockRecorder) PromoteParticipant(ctx, chat, issuer, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteParticipant", reflect.TypeOf((*MockChatUseCase)(nil).PromoteParticipant), ctx, chat, issuer, user)
 }
 
 // RemoveUserFromChat mocks base method.
-func (m *MockChatUseCase) RemoveUserFromChat(ctx context.Context, chat models0.Chat, users ...models0.User) models0.StatusCode {
+func (m *MockChatUseCase) RemoveUserFromChat(ctx context.Context, chat models0.Chat, issuer models0.User, users ...models0.User) models0.StatusCode {
 	m.ctrl.T.Helper()
-	varargs := []any{ctx, chat}
+	varargs := []any{ctx, chat, issuer}
 	for _, a := range users {
 		varargs = append(varargs, a)
 	}
@@ -360,24 +813,52 @@ func (m *MockChatUseCase) RemoveUserFromChat(ctx context.Context, chat models0.C
 }
 
 // RemoveUserFromChat indicates an expected call of RemoveUserFromChat.
-func (mr *MockChatUseCaseMockRecorder) RemoveUserFromChat(ctx, chat any, users ...any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) RemoveUserFromChat(ctx, chat, issuer any, users ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{ctx, chat}, users...)
+	varargs := append([]any{ctx, chat, issuer}, users...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserFromChat", reflect.TypeOf((*MockChatUseCase)(nil).RemoveUserFromChat), varargs...)
 }
 
+// RenameChat mocks base method.
+func (m *MockChatUseCase) RenameChat(ctx context.Context, chat models0.Chat, issuer models0.User, name string) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameChat", ctx, chat, issuer, name)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// RenameChat indicates an expected call of RenameChat.
+func (mr *MockChatUseCaseMockRecorder) RenameChat(ctx, chat, issuer, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameChat", reflect.TypeOf((*MockChatUseCase)(nil).RenameChat), ctx, chat, issuer, name)
+}
+
+// TransferOwnership mocks base method.
+func (m *MockChatUseCase) TransferOwnership(ctx context.Context, chat models0.Chat, issuer, user models0.User) models0.StatusCode {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", ctx, chat, issuer, user)
+	ret0, _ := ret[0].(models0.StatusCode)
+	return ret0
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockChatUseCaseMockRecorder) TransferOwnership(ctx, chat, issuer, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockChatUseCase)(nil).TransferOwnership), ctx, chat, issuer, user)
+}
+
 // UpdateChatPhotoURL mocks base method.
-func (m *MockChatUseCase) UpdateChatPhotoURL(ctx context.Context, chat models0.Chat, photoURL string) models0.StatusCode {
+func (m *MockChatUseCase) UpdateChatPhotoURL(ctx context.Context, chat models0.Chat, issuer models0.User, photoURL string) models0.StatusCode {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateChatPhotoURL", ctx, chat, photoURL)
+	ret := m.ctrl.Call(m, "UpdateChatPhotoURL", ctx, chat, issuer, photoURL)
 	ret0, _ := ret[0].(models0.StatusCode)
 	return ret0
 }
 
 // UpdateChatPhotoURL indicates an expected call of UpdateChatPhotoURL.
-func (mr *MockChatUseCaseMockRecorder) UpdateChatPhotoURL(ctx, chat, photoURL any) *gomock.Call {
+func (mr *MockChatUseCaseMockRecorder) UpdateChatPhotoURL(ctx, chat, issuer, photoURL any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChatPhotoURL", reflect.TypeOf((*MockChatUseCase)(nil).UpdateChatPhotoURL), ctx, chat, photoURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateChatPhotoURL", reflect.TypeOf((*MockChatUseCase)(nil).UpdateChatPhotoURL), ctx, chat, issuer, photoURL)
 }
 
 // MockUserDataInteractor is a mock of UserDataInteractor interface.
@@ -404,16 +885,31 @@ func (m *MockUserDataInteractor) EXPECT() *MockUserDataInteractorMockRecorder {
 }
 
 // GetUser mocks base method.
-func (m *MockUserDataInteractor) GetUser(user models0.User) (models0.User, models0.StatusCode) {
+func (m *MockUserDataInteractor) GetUser(ctx context.Context, user models0.User) (models0.User, models0.StatusCode) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", user)
+	ret := m.ctrl.Call(m, "GetUser", ctx, user)
 	ret0, _ := ret[0].(models0.User)
 	ret1, _ := ret[1].(models0.StatusCode)
 	return ret0, ret1
 }
 
 // GetUser indicates an expected call of GetUser.
-func (mr *MockUserDataInteractorMockRecorder) GetUser(user any) *gomock.Call {
+func (mr *MockUserDataInteractorMockRecorder) GetUser(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserDataInteractor)(nil).GetUser), ctx, user)
+}
+
+// GetUsers mocks base method.
+func (m *MockUserDataInteractor) GetUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models0.User, models0.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, ids)
+	ret0, _ := ret[0].(map[uuid.UUID]models0.User)
+	ret1, _ := ret[1].(models0.StatusCode)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockUserDataInteractorMockRecorder) GetUsers(ctx, ids any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserDataInteractor)(nil).GetUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserDataInteractor)(nil).GetUsers), ctx, ids)
 }
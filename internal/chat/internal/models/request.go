@@ -2,14 +2,19 @@ package models
 
 import (
 	"github.com/google/uuid"
+	"our-little-chatik/internal/models"
 	"our-little-chatik/internal/pkg/validator"
+	"strings"
 )
 
 type CreateChatRequest struct {
 	Participants []uuid.UUID `json:"participants,omitempty"`
 	Name         *string     `json:"name,omitempty"`
 	PhotoURL     *string     `json:"photo_url,omitempty"`
-	IssuerID     uuid.UUID   `json:"-"`
+	// Kind is told by the number of the participants if it is not given:
+	// saved messages for the issuer alone, a direct chat for two users and a group otherwise.
+	Kind     *models.ChatKind `json:"kind,omitempty"`
+	IssuerID uuid.UUID        `json:"-"`
 }
 
 func ValidateCreateChatRequest(v *validator.Validator, request CreateChatRequest) {
@@ -23,6 +28,9 @@ func ValidateCreateChatRequest(v *validator.Validator, request CreateChatRequest
 	if request.Participants != nil {
 		v.Check(len(request.Participants) < 100, "participants", "must be less than 100 members")
 	}
+	if request.Kind != nil {
+		v.Check(request.Kind.IsValid(), "kind", "must be one of direct, group, saved_messages, channel")
+	}
 }
 
 type AddUsersToChatRequest struct {
@@ -53,6 +61,18 @@ func ValidateRemoveUsersFromChatRequest(v *validator.Validator, request RemoveUs
 	v.Check(len(request.Participants) < 10, "participants", "can't remove more than 10 users")
 }
 
+type RenameChatRequest struct {
+	Name *string `json:"name"`
+}
+
+func ValidateRenameChatRequest(v *validator.Validator, request RenameChatRequest) {
+	v.Check(request.Name != nil, "name", "must be provided")
+	if request.Name != nil {
+		v.Check(strings.TrimSpace(*request.Name) != "", "name", "must not be blank")
+		v.Check(len(*request.Name) < 50, "name", "must be less than 50 bytes")
+	}
+}
+
 type UpdateChatPhotoURLRequest struct {
 	ChatID   *uuid.UUID `json:"chat_id"`
 	PhotoURL *string    `json:"photo_url,omitempty"`
@@ -68,3 +88,26 @@ func ValidateUpdateChatPhotoURLRequest(v *validator.Validator, request UpdateCha
 		//TODO add check for extensions??
 	}
 }
+
+type MarkReadRequest struct {
+	MsgID *uuid.UUID `json:"msg_id"`
+}
+
+func ValidateMarkReadRequest(v *validator.Validator, request MarkReadRequest) {
+	v.Check(request.MsgID != nil, "msg_id", "must be provided")
+	if request.MsgID != nil {
+		v.Check(*request.MsgID != uuid.Nil, "msg_id", "must be a correct uuid value")
+	}
+}
+
+// ParticipantRequest names the participant whose role is changed.
+type ParticipantRequest struct {
+	UserID *uuid.UUID `json:"user_id"`
+}
+
+func ValidateParticipantRequest(v *validator.Validator, request ParticipantRequest) {
+	v.Check(request.UserID != nil, "user_id", "must be provided")
+	if request.UserID != nil {
+		v.Check(*request.UserID != uuid.Nil, "user_id", "must be a correct uuid value")
+	}
+}
@@ -3,29 +3,67 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/exp/slog"
-	"log"
 	"our-little-chatik/internal/models"
 	"sort"
 )
 
 const (
-	CreateChatParticipantsQuery = `INSERT INTO chat_participants VALUES ($1, $2, $3)`
-	CreateChatQuery             = `INSERT INTO chats VALUES($1, $2, $3)`
-	GetChatMessagesQuery        = `SELECT msg_id, sender_id, payload, created_at FROM messages WHERE chat_id=$1 ORDER BY created_at ASC OFFSET $2 LIMIT $3`
-	GetChatInfoQuery            = `SELECT c.chat_id, cp.chat_name, c.photo_url, c.created_at, m.msg_id, m.sender_id, m.payload, m.created_at FROM chats AS c
-    LEFT JOIN chat_participants AS cp ON c.chat_id = cp.chat_id 
+	CreateChatParticipantsQuery = `INSERT INTO chat_participants (chat_id, participant_id, chat_name, role) VALUES ($1, $2, $3, $4)`
+	CreateChatQuery             = `INSERT INTO chats (chat_id, photo_url, created_at, kind, personal_key) VALUES ($1, $2, $3, $4, $5)`
+	GetPersonalChatQuery        = `SELECT chat_id FROM chats WHERE personal_key=$1`
+	GetChatMessagesBeforeQuery  = `SELECT msg_id, sender_id, payload, created_at, seq, kind FROM messages WHERE chat_id=$1 AND ($2 = 0 OR seq < $2) ORDER BY seq DESC LIMIT $3`
+	GetChatMessagesAfterQuery   = `SELECT msg_id, sender_id, payload, created_at, seq, kind FROM messages WHERE chat_id=$1 AND seq > $2 ORDER BY seq ASC LIMIT $3`
+	GetChatInfoQuery            = `SELECT c.chat_id, c.kind, cp.chat_name, c.photo_url, c.created_at, m.msg_id, m.sender_id, m.payload, m.created_at, m.seq, m.kind,
+    COALESCE(cp.chat_name_custom, false) FROM chats AS c
+    LEFT JOIN chat_participants AS cp ON c.chat_id = cp.chat_id AND cp.participant_id = $2
     LEFT JOIN messages AS m ON c.last_msg_id = m.msg_id WHERE c.chat_id=$1`
-	GetChatParticipantsQuery = `SELECT participant_id FROM chat_participants WHERE chat_id=$1`
-	FetchChatListQuery       = `SELECT cp.chat_id, cp.chat_name, c.photo_url, m.msg_id, m.sender_id, m.payload, m.created_at FROM chat_participants AS cp 
+	GetChatParticipantsQuery = `SELECT participant_id, role FROM chat_participants WHERE chat_id=$1`
+	FetchChatListQuery       = `SELECT cp.chat_id, c.kind, cp.chat_name, c.photo_url, m.msg_id, m.sender_id, m.payload, m.created_at, m.seq, m.kind,
+    c.messages_count, cp.last_read_msg_id, cp.last_read_seq,
+    (SELECT COUNT(*) FROM messages AS um WHERE um.chat_id = cp.chat_id AND um.sender_id <> cp.participant_id
+        AND (cp.last_read_seq IS NULL OR um.seq > cp.last_read_seq)) AS unread_count,
+    (SELECT op.participant_id FROM chat_participants AS op WHERE c.kind = 'direct'
+        AND op.chat_id = cp.chat_id AND op.participant_id <> cp.participant_id LIMIT 1) AS peer_id,
+    cp.chat_name_custom
+    FROM chat_participants AS cp 
     LEFT JOIN chats AS c ON cp.chat_id = c.chat_id
     LEFT JOIN messages AS m on c.last_msg_id = m.msg_id                                         
                           WHERE cp.participant_id=$1`
 	UpdatePhotoURLQuery     = "UPDATE chats SET photo_url=$1 WHERE chat_id=$2"
 	RemoveUserFromChatQuery = "DELETE FROM chat_participants WHERE participant_id=$1 AND chat_id=$2"
 	DeleteChatQuery         = "DELETE FROM chats WHERE chat_id=$1"
-	DeleteMessageQuery      = "DELETE FROM messages WHERE msg_id=$1"
+	// The participants do not reference the chat, they are deleted along with it explicitly.
+	DeleteChatParticipantsQuery = "DELETE FROM chat_participants WHERE chat_id=$1"
+	DeleteMessageQuery          = "DELETE FROM messages WHERE msg_id=$1"
+	IsChatParticipantQuery      = "SELECT EXISTS(SELECT 1 FROM chat_participants WHERE chat_id=$1 AND participant_id=$2)"
+	GetMessageQuery             = "SELECT chat_id, sender_id, payload, created_at, seq, kind FROM messages WHERE msg_id=$1"
+	GetLastSeqQuery             = "SELECT COALESCE(MAX(seq), 0) FROM messages WHERE chat_id=$1"
+	// The last message is taken from the remaining ones, as the deleted message may have been the last.
+	UpdateChatCountersQuery = `UPDATE chats SET messages_count = GREATEST(messages_count - 1, 0),
+    last_msg_id = (SELECT msg_id FROM messages WHERE chat_id=$1 ORDER BY seq DESC LIMIT 1),
+    last_msg_seq = COALESCE((SELECT MAX(seq) FROM messages WHERE chat_id=$1), 0)
+    WHERE chat_id=$1`
+	// The read cursor only moves forward, so a late read of an older message does not mark newer ones unread.
+	UpdateLastReadQuery = `UPDATE chat_participants SET last_read_msg_id=$1, last_read_seq=$2
+    WHERE chat_id=$3 AND participant_id=$4 AND (last_read_seq IS NULL OR last_read_seq < $2)`
+	GetSeenByQuery = `SELECT participant_id, last_read_msg_id FROM chat_participants
+    WHERE chat_id=$1 AND participant_id <> $2 AND last_read_seq >= $3`
+	GetParticipantRoleQuery        = "SELECT role FROM chat_participants WHERE chat_id=$1 AND participant_id=$2"
+	UpdateParticipantRoleQuery     = "UPDATE chat_participants SET role=$1 WHERE chat_id=$2 AND participant_id=$3"
+	UpdateChatNameQuery            = "UPDATE chat_participants SET chat_name=$1 WHERE chat_id=$2"
+	UpdateParticipantChatNameQuery = "UPDATE chat_participants SET chat_name=$1, chat_name_custom=true WHERE chat_id=$2 AND participant_id=$3"
+	GetMembershipQuery             = `SELECT c.kind, cp.role FROM chat_participants AS cp
+    JOIN chats AS c ON c.chat_id = cp.chat_id WHERE cp.chat_id=$1 AND cp.participant_id=$2`
+	GetUserChatIDsQuery = "SELECT chat_id FROM chat_participants WHERE participant_id=$1"
+	// The flusher locks the chats of the messages it persists as well, so either it sees the tombstone
+	// or the message is already persisted when it is deleted.
+	LockChatQuery        = "SELECT chat_id FROM chats WHERE chat_id=$1 FOR UPDATE"
+	InsertTombstoneQuery = `INSERT INTO deleted_messages (msg_id, chat_id, deleted_at)
+    VALUES ($1, $2, extract(epoch FROM now())::bigint) ON CONFLICT (msg_id) DO NOTHING`
 )
 
 type PostgresRepo struct {
@@ -36,15 +74,17 @@ func NewPostgresRepo(pool *sql.DB) *PostgresRepo {
 	return &PostgresRepo{pool: pool}
 }
 
-// GetChat
-func (pr PostgresRepo) GetChat(ctx context.Context, chat models.Chat) (models.Chat, models.StatusCode) {
-	row := pr.pool.QueryRowContext(ctx, GetChatInfoQuery, chat.ChatID)
+// GetChat returns the chat as the user sees it, the names of the chats are personal.
+func (pr PostgresRepo) GetChat(ctx context.Context, chat models.Chat, user models.User) (models.Chat, models.StatusCode) {
+	row := pr.pool.QueryRowContext(ctx, GetChatInfoQuery, chat.ChatID, user.ID)
 	lastMsgID := uuid.NullUUID{}
 	senderID := uuid.NullUUID{}
 	payload := sql.NullString{}
 	createdAt := sql.NullInt64{}
-	err := row.Scan(&chat.ChatID, &chat.Name, &chat.PhotoURL, &chat.CreatedAt, &lastMsgID,
-		&senderID, &payload, &createdAt)
+	seq := sql.NullInt64{}
+	kind := sql.NullString{}
+	err := row.Scan(&chat.ChatID, &chat.Kind, &chat.Name, &chat.PhotoURL, &chat.CreatedAt, &lastMsgID,
+		&senderID, &payload, &createdAt, &seq, &kind, &chat.CustomName)
 	if err != nil {
 		return models.Chat{}, models.NotFound
 	}
@@ -60,32 +100,46 @@ func (pr PostgresRepo) GetChat(ctx context.Context, chat models.Chat) (models.Ch
 	if createdAt.Valid {
 		chat.LastMessage.CreatedAt = createdAt.Int64
 	}
+	if seq.Valid {
+		chat.LastMessage.Seq = seq.Int64
+	}
+	if kind.Valid {
+		chat.LastMessage.Kind = models.MessageKind(kind.String)
+	}
 	rows, err := pr.pool.QueryContext(ctx, GetChatParticipantsQuery, chat.ChatID)
 	if err != nil {
 		return models.Chat{}, models.InternalError
 	}
+	defer rows.Close()
 	for rows.Next() {
-		var participantID uuid.UUID
-		err = rows.Scan(&participantID)
+		member := models.ChatMember{}
+		err = rows.Scan(&member.UserID, &member.Role)
 		if err != nil {
 			slog.Error(err.Error())
 		}
-		chat.Participants = append(chat.Participants, participantID)
+		chat.Participants = append(chat.Participants, member.UserID)
+		chat.Members = append(chat.Members, member)
 	}
 	return chat, models.OK
 }
 
-// GetChatMessages
+// GetChatMessages returns the window of the persisted messages, opts cursors must be resolved to seq.
 func (pr PostgresRepo) GetChatMessages(ctx context.Context, chat models.Chat, opts models.Opts) (models.Messages, models.StatusCode) {
-	rows, err := pr.pool.QueryContext(ctx, GetChatMessagesQuery, chat.ChatID, opts.Page, opts.Limit)
+	query, cursor := GetChatMessagesBeforeQuery, opts.Before.Seq
+	if opts.After.IsSet() {
+		query, cursor = GetChatMessagesAfterQuery, opts.After.Seq
+	}
+	rows, err := pr.pool.QueryContext(ctx, query, chat.ChatID, cursor, opts.Limit)
 	if err != nil {
-		return nil, models.NotFound
+		slog.Error(err.Error())
+		return nil, models.InternalError
 	}
+	defer rows.Close()
 
 	msgs := make(models.Messages, 0)
 	for rows.Next() {
 		msg := models.Message{}
-		err := rows.Scan(&msg.MsgID, &msg.SenderID, &msg.Payload, &msg.CreatedAt)
+		err := rows.Scan(&msg.MsgID, &msg.SenderID, &msg.Payload, &msg.CreatedAt, &msg.Seq, &msg.Kind)
 		if err != nil {
 			return nil, models.InternalError
 		}
@@ -96,7 +150,7 @@ func (pr PostgresRepo) GetChatMessages(ctx context.Context, chat models.Chat, op
 	return msgs, models.OK
 }
 
-// FetchChatList
+// FetchChatList returns the chats of the user, the direct chats come with both their participants.
 func (pr PostgresRepo) FetchChatList(ctx context.Context, user models.User) ([]models.Chat, models.StatusCode) {
 	rows, err := pr.pool.QueryContext(ctx, FetchChatListQuery, user.ID)
 	if err != nil {
@@ -107,15 +161,29 @@ func (pr PostgresRepo) FetchChatList(ctx context.Context, user models.User) ([]m
 	senderID := uuid.NullUUID{}
 	payload := sql.NullString{}
 	createdAt := sql.NullInt64{}
+	seq := sql.NullInt64{}
+	kind := sql.NullString{}
+	messagesCount := sql.NullInt64{}
+	lastReadMsgID := uuid.NullUUID{}
+	lastReadSeq := sql.NullInt64{}
+	peerID := uuid.NullUUID{}
 
 	chatList := make([]models.Chat, 0)
 	for rows.Next() {
 		chat := models.Chat{}
-		err := rows.Scan(&chat.ChatID, &chat.Name, &chat.PhotoURL, &lastMsgID,
-			&senderID, &payload, &createdAt)
+		err := rows.Scan(&chat.ChatID, &chat.Kind, &chat.Name, &chat.PhotoURL, &lastMsgID,
+			&senderID, &payload, &createdAt, &seq, &kind, &messagesCount, &lastReadMsgID, &lastReadSeq, &chat.UnreadCount, &peerID,
+			&chat.CustomName)
 		if err != nil {
 			return nil, models.InternalError
 		}
+		if lastReadMsgID.Valid {
+			readMsgID := lastReadMsgID.UUID
+			chat.LastReadMsgID = &readMsgID
+		}
+		if lastReadSeq.Valid {
+			chat.LastReadSeq = lastReadSeq.Int64
+		}
 		if lastMsgID.Valid {
 			chat.LastMessage.MsgID = lastMsgID.UUID
 		}
@@ -128,6 +196,19 @@ func (pr PostgresRepo) FetchChatList(ctx context.Context, user models.User) ([]m
 		if createdAt.Valid {
 			chat.LastMessage.CreatedAt = createdAt.Int64
 		}
+		if seq.Valid {
+			chat.LastMessage.Seq = seq.Int64
+		}
+		if kind.Valid {
+			chat.LastMessage.Kind = models.MessageKind(kind.String)
+		}
+		if messagesCount.Valid {
+			chat.MessagesCount = messagesCount.Int64
+		}
+		// the participants of a direct chat are listed, so that its title can be taken from the other one
+		if peerID.Valid {
+			chat.Participants = []uuid.UUID{user.ID, peerID.UUID}
+		}
 
 		chatList = append(chatList, chat)
 	}
@@ -135,41 +216,44 @@ func (pr PostgresRepo) FetchChatList(ctx context.Context, user models.User) ([]m
 	return chatList, models.OK
 }
 
-// CreateChat
+// CreateChat creates the chat along with its participants, their roles are taken from the members of the chat.
+// A personal chat is keyed by its participants, Conflict is returned if they already have one.
 func (pr PostgresRepo) CreateChat(ctx context.Context, chat models.Chat,
 	chatNames map[string]string) models.StatusCode {
 	tx, err := pr.pool.Begin()
 	if err != nil {
 		return models.InternalError
 	}
+	rollback := func() {
+		txErr := tx.Rollback()
+		if txErr != nil {
+			slog.Error(txErr.Error())
+		}
+	}
 
 	for _, participant := range chat.Participants {
-		_, err := pr.pool.ExecContext(ctx, CreateChatParticipantsQuery, chat.ChatID, participant,
-			chatNames[participant.String()])
+		_, err := tx.ExecContext(ctx, CreateChatParticipantsQuery, chat.ChatID, participant,
+			chatNames[participant.String()], chat.RoleOf(participant))
 		if err != nil {
-			slog.Error("Failed to add a chat user-1", "user", participant.String())
-			log.Println("err here pg", err.Error())
-			txErr := tx.Rollback()
-			if txErr != nil {
-				slog.Error(txErr.Error())
-			}
+			slog.Error("failed to add a chat participant", "user", participant.String(), "err", err.Error())
+			rollback()
 			return models.InternalError
 		}
 	}
 
-	res, err := pr.pool.ExecContext(ctx, CreateChatQuery, chat.ChatID, chat.PhotoURL, chat.CreatedAt)
+	personalKey := sql.NullString{String: chat.PersonalKey(), Valid: chat.Kind.IsPersonal()}
+	res, err := tx.ExecContext(ctx, CreateChatQuery, chat.ChatID, chat.PhotoURL, chat.CreatedAt,
+		chat.Kind, personalKey)
 	if err != nil {
-		txErr := tx.Rollback()
-		if txErr != nil {
-			slog.Error(txErr.Error())
+		rollback()
+		if isUniqueViolation(err) {
+			return models.Conflict
 		}
+		slog.Error(err.Error())
 		return models.InternalError
 	}
 	if val, err := res.RowsAffected(); err != nil || val == 0 {
-		txErr := tx.Rollback()
-		if txErr != nil {
-			slog.Error(txErr.Error())
-		}
+		rollback()
 		return models.InternalError
 	}
 
@@ -180,6 +264,28 @@ func (pr PostgresRepo) CreateChat(ctx context.Context, chat models.Chat,
 	return models.OK
 }
 
+// isUniqueViolation tells whether the insert has been rejected by a unique index.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	// unique_violation
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// GetPersonalChat returns the personal chat the participants of the chat already have as the user sees it,
+// NotFound if they have none.
+func (pr PostgresRepo) GetPersonalChat(ctx context.Context, chat models.Chat, user models.User) (models.Chat, models.StatusCode) {
+	chatID := uuid.UUID{}
+	err := pr.pool.QueryRowContext(ctx, GetPersonalChatQuery, chat.PersonalKey()).Scan(&chatID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Chat{}, models.NotFound
+		}
+		slog.Error(err.Error())
+		return models.Chat{}, models.InternalError
+	}
+	return pr.GetChat(ctx, models.Chat{ChatID: chatID}, user)
+}
+
 func (pr PostgresRepo) UpdateChatPhotoURL(ctx context.Context, chat models.Chat,
 	photoURL string) models.StatusCode {
 	res, err := pr.pool.ExecContext(ctx, UpdatePhotoURLQuery, photoURL, chat.ChatID)
@@ -201,7 +307,7 @@ func (pr PostgresRepo) AddUsersToChat(ctx context.Context,
 	if len(chat.Participants) > 0 {
 		for _, user := range users {
 			res, err := tx.ExecContext(ctx, CreateChatParticipantsQuery,
-				chat.ChatID, user.ID, chatNames[user.ID.String()])
+				chat.ChatID, user.ID, chatNames[user.ID.String()], models.MemberRole)
 			if err != nil {
 				slog.Error("Failed to add a chat user", "user", user.ID.String())
 				txErr := tx.Rollback()
@@ -259,18 +365,291 @@ func (pr PostgresRepo) RemoveUserFromChat(ctx context.Context,
 	return models.OK
 }
 
+// DeleteChat deletes the chat with its participants and messages.
 func (pr PostgresRepo) DeleteChat(ctx context.Context, chat models.Chat) models.StatusCode {
-	_, err := pr.pool.ExecContext(ctx, DeleteChatQuery, chat.ChatID)
+	tx, err := pr.pool.BeginTx(ctx, nil)
 	if err != nil {
 		return models.InternalError
 	}
+	for _, query := range []string{DeleteChatParticipantsQuery, DeleteChatQuery} {
+		_, err = tx.ExecContext(ctx, query, chat.ChatID)
+		if err != nil {
+			slog.Error(err.Error())
+			if txErr := tx.Rollback(); txErr != nil {
+				slog.Error(txErr.Error())
+			}
+			return models.InternalError
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.InternalError
+	}
 	return models.Deleted
 }
 
+// DeleteMessage deletes the persisted message of the chat and updates the counters of the chat.
+// The message may still be only queued, then there is nothing to delete and Deleted is returned as well.
+// Either way a tombstone is left, so the flusher skips the message if it has already fetched it.
 func (pr PostgresRepo) DeleteMessage(ctx context.Context, message models.Message) models.StatusCode {
-	_, err := pr.pool.ExecContext(ctx, DeleteMessageQuery, message.MsgID)
+	tx, err := pr.pool.BeginTx(ctx, nil)
 	if err != nil {
 		return models.InternalError
 	}
+	var res sql.Result
+	_, err = tx.ExecContext(ctx, LockChatQuery, message.ChatID)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, InsertTombstoneQuery, message.MsgID, message.ChatID)
+	}
+	if err == nil {
+		res, err = tx.ExecContext(ctx, DeleteMessageQuery, message.MsgID)
+	}
+	if err == nil {
+		var deleted int64
+		deleted, err = res.RowsAffected()
+		if err == nil && deleted > 0 {
+			_, err = tx.ExecContext(ctx, UpdateChatCountersQuery, message.ChatID)
+		}
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		if txErr := tx.Rollback(); txErr != nil {
+			slog.Error(txErr.Error())
+		}
+		return models.InternalError
+	}
+	if err := tx.Commit(); err != nil {
+		return models.InternalError
+	}
 	return models.Deleted
 }
+
+// UpdateChatName renames the chat for the users, for every participant if no user is given.
+// NotFound is returned if there is no one to rename the chat for.
+func (pr PostgresRepo) UpdateChatName(ctx context.Context, chat models.Chat,
+	name string, users ...models.User) models.StatusCode {
+	tx, err := pr.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return models.InternalError
+	}
+	var res sql.Result
+	if len(users) == 0 {
+		res, err = tx.ExecContext(ctx, UpdateChatNameQuery, name, chat.ChatID)
+		err = checkRenamed(res, err)
+	}
+	for _, user := range users {
+		res, err = tx.ExecContext(ctx, UpdateParticipantChatNameQuery, name, chat.ChatID, user.ID)
+		if err = checkRenamed(res, err); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		if txErr := tx.Rollback(); txErr != nil {
+			slog.Error(txErr.Error())
+		}
+		if err == sql.ErrNoRows {
+			return models.NotFound
+		}
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	if err := tx.Commit(); err != nil {
+		return models.InternalError
+	}
+	return models.OK
+}
+
+// checkRenamed reports sql.ErrNoRows if the rename has not touched any participant.
+func checkRenamed(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetParticipantRole returns the role of the participant, NotFound if the user does not participate in the chat.
+func (pr PostgresRepo) GetParticipantRole(ctx context.Context, chat models.Chat,
+	user models.User) (models.ChatRole, models.StatusCode) {
+	var role models.ChatRole
+	err := pr.pool.QueryRowContext(ctx, GetParticipantRoleQuery, chat.ChatID, user.ID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", models.NotFound
+		}
+		slog.Error(err.Error())
+		return "", models.InternalError
+	}
+	return role, models.OK
+}
+
+// GetMembership returns the kind of the chat along with the role of the participant,
+// NotFound if the user does not participate in the chat.
+func (pr PostgresRepo) GetMembership(ctx context.Context, chat models.Chat,
+	user models.User) (models.ChatKind, models.ChatRole, models.StatusCode) {
+	var (
+		kind models.ChatKind
+		role models.ChatRole
+	)
+	err := pr.pool.QueryRowContext(ctx, GetMembershipQuery, chat.ChatID, user.ID).Scan(&kind, &role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", models.NotFound
+		}
+		slog.Error(err.Error())
+		return "", "", models.InternalError
+	}
+	return kind, role, models.OK
+}
+
+// GetUserChatIDs returns the ids of the chats the user participates in.
+func (pr PostgresRepo) GetUserChatIDs(ctx context.Context, user models.User) ([]uuid.UUID, models.StatusCode) {
+	rows, err := pr.pool.QueryContext(ctx, GetUserChatIDsQuery, user.ID)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, models.InternalError
+	}
+	defer rows.Close()
+	chatIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var chatID uuid.UUID
+		if err = rows.Scan(&chatID); err != nil {
+			slog.Error(err.Error())
+			return nil, models.InternalError
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, models.InternalError
+	}
+	return chatIDs, models.OK
+}
+
+// UpdateParticipantRole changes the role of the participant, NotFound if the user does not participate in the chat.
+func (pr PostgresRepo) UpdateParticipantRole(ctx context.Context, chat models.Chat,
+	user models.User, role models.ChatRole) models.StatusCode {
+	res, err := pr.pool.ExecContext(ctx, UpdateParticipantRoleQuery, role, chat.ChatID, user.ID)
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return models.NotFound
+	}
+	return models.OK
+}
+
+// TransferOwnership makes the participant the owner of the chat and the former owner an admin
+// in one transaction, as the chat must have a single owner.
+func (pr PostgresRepo) TransferOwnership(ctx context.Context, chat models.Chat,
+	owner models.User, user models.User) models.StatusCode {
+	tx, err := pr.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return models.InternalError
+	}
+	for _, change := range []struct {
+		user models.User
+		role models.ChatRole
+	}{
+		{user: owner, role: models.AdminRole},
+		{user: user, role: models.OwnerRole},
+	} {
+		res, err := tx.ExecContext(ctx, UpdateParticipantRoleQuery, change.role, chat.ChatID, change.user.ID)
+		status := models.OK
+		if err != nil {
+			slog.Error(err.Error())
+			status = models.InternalError
+		} else if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			status = models.NotFound
+		}
+		if status != models.OK {
+			if txErr := tx.Rollback(); txErr != nil {
+				slog.Error(txErr.Error())
+			}
+			return status
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.InternalError
+	}
+	return models.OK
+}
+
+func (pr PostgresRepo) IsChatParticipant(ctx context.Context, chat models.Chat,
+	user models.User) (bool, models.StatusCode) {
+	var isParticipant bool
+	err := pr.pool.QueryRowContext(ctx, IsChatParticipantQuery, chat.ChatID, user.ID).Scan(&isParticipant)
+	if err != nil {
+		slog.Error(err.Error())
+		return false, models.InternalError
+	}
+	return isParticipant, models.OK
+}
+
+// GetMessage looks up the persisted message by its id.
+func (pr PostgresRepo) GetMessage(ctx context.Context, message models.Message) (models.Message, models.StatusCode) {
+	err := pr.pool.QueryRowContext(ctx, GetMessageQuery, message.MsgID).Scan(&message.ChatID,
+		&message.SenderID, &message.Payload, &message.CreatedAt, &message.Seq, &message.Kind)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Message{}, models.NotFound
+		}
+		slog.Error(err.Error())
+		return models.Message{}, models.InternalError
+	}
+	return message, models.OK
+}
+
+// UpdateLastRead moves the read cursor of the participant to the message. It reports
+// false if the cursor already points to the same or a newer message.
+func (pr PostgresRepo) UpdateLastRead(ctx context.Context, chat models.Chat,
+	user models.User, message models.Message) (bool, models.StatusCode) {
+	res, err := pr.pool.ExecContext(ctx, UpdateLastReadQuery, message.MsgID, message.Seq,
+		chat.ChatID, user.ID)
+	if err != nil {
+		slog.Error(err.Error())
+		return false, models.InternalError
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		slog.Error(err.Error())
+		return false, models.InternalError
+	}
+	return affected > 0, models.OK
+}
+
+// GetSeenBy returns the read cursors of the participants, except the sender, who have read the message.
+func (pr PostgresRepo) GetSeenBy(ctx context.Context, chat models.Chat,
+	message models.Message) ([]models.ReadReceipt, models.StatusCode) {
+	rows, err := pr.pool.QueryContext(ctx, GetSeenByQuery, chat.ChatID, message.SenderID, message.Seq)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, models.InternalError
+	}
+	defer rows.Close()
+
+	receipts := make([]models.ReadReceipt, 0)
+	for rows.Next() {
+		receipt := models.ReadReceipt{}
+		err := rows.Scan(&receipt.UserID, &receipt.LastReadMsgID)
+		if err != nil {
+			slog.Error(err.Error())
+			return nil, models.InternalError
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, models.OK
+}
+
+// GetLastSeq returns the sequence number of the newest persisted message of the chat, 0 for an empty chat.
+func (pr PostgresRepo) GetLastSeq(ctx context.Context, chat models.Chat) (int64, models.StatusCode) {
+	var seq int64
+	err := pr.pool.QueryRowContext(ctx, GetLastSeqQuery, chat.ChatID).Scan(&seq)
+	if err != nil {
+		slog.Error(err.Error())
+		return 0, models.InternalError
+	}
+	return seq, models.OK
+}
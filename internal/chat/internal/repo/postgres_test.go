@@ -3,9 +3,11 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"our-little-chatik/internal/models"
 	"reflect"
 	"regexp"
@@ -38,23 +40,59 @@ func TestPostgresRepo_FetchChatList(t *testing.T) {
 		SenderID:  uuid.New(),
 		Payload:   "test",
 		CreatedAt: int64(1),
+		Seq:       int64(7),
+		Kind:      models.TextMessage,
 	}
 
 	testChat := models.Chat{
-		ChatID:      testChatID,
-		Name:        testName,
-		PhotoURL:    testURL,
-		LastMessage: testMsg,
+		ChatID:        testChatID,
+		Kind:          models.GroupChat,
+		Name:          testName,
+		PhotoURL:      testURL,
+		LastMessage:   testMsg,
+		LastReadMsgID: &testMsg.MsgID,
+		LastReadSeq:   testMsg.Seq,
+		UnreadCount:   2,
+		MessagesCount: 7,
+	}
+	unreadChat := models.Chat{
+		ChatID:        testChatID,
+		Kind:          models.GroupChat,
+		Name:          testName,
+		PhotoURL:      testURL,
+		LastMessage:   testMsg,
+		UnreadCount:   3,
+		MessagesCount: 7,
+	}
+	testPeerID := uuid.New()
+	directChat := models.Chat{
+		ChatID:        testChatID,
+		Kind:          models.DirectChat,
+		Participants:  []uuid.UUID{testUserID, testPeerID},
+		Name:          testName,
+		PhotoURL:      testURL,
+		LastMessage:   testMsg,
+		MessagesCount: 7,
+		CustomName:    true,
 	}
 
 	columns := []string{
 		"cp.chat_id",
+		"c.kind",
 		"cp.chat_name",
 		"c.photo_url",
 		"m.msg_id",
 		"m.sender_id",
 		"m.payload",
 		"m.created_at",
+		"m.seq",
+		"m.kind",
+		"c.messages_count",
+		"cp.last_read_msg_id",
+		"cp.last_read_seq",
+		"unread_count",
+		"peer_id",
+		"cp.chat_name_custom",
 	}
 
 	tests := []struct {
@@ -73,9 +111,9 @@ func TestPostgresRepo_FetchChatList(t *testing.T) {
 			pre: func() {
 				mock.ExpectQuery(regexp.QuoteMeta(FetchChatListQuery)).
 					WithArgs(testUserID).
-					WillReturnRows(sqlmock.NewRows(columns).AddRow(testChatID,
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(testChatID, models.GroupChat,
 						testName, testURL, testMsg.MsgID, testMsg.SenderID,
-						testMsg.Payload, testMsg.CreatedAt))
+						testMsg.Payload, testMsg.CreatedAt, testMsg.Seq, testMsg.Kind, 7, testMsg.MsgID, testMsg.Seq, 2, nil, false))
 			},
 			args: args{
 				user: models.User{
@@ -85,6 +123,46 @@ func TestPostgresRepo_FetchChatList(t *testing.T) {
 			want:   []models.Chat{testChat},
 			status: models.OK,
 		},
+		{
+			name: "nothing read yet",
+			fields: fields{
+				pool: db,
+			},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(FetchChatListQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(testChatID, models.GroupChat,
+						testName, testURL, testMsg.MsgID, testMsg.SenderID,
+						testMsg.Payload, testMsg.CreatedAt, testMsg.Seq, testMsg.Kind, 7, nil, nil, 3, nil, false))
+			},
+			args: args{
+				user: models.User{
+					ID: testUserID,
+				},
+			},
+			want:   []models.Chat{unreadChat},
+			status: models.OK,
+		},
+		{
+			name: "direct chat comes with the other participant",
+			fields: fields{
+				pool: db,
+			},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(FetchChatListQuery)).
+					WithArgs(testUserID).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(testChatID, models.DirectChat,
+						testName, testURL, testMsg.MsgID, testMsg.SenderID,
+						testMsg.Payload, testMsg.CreatedAt, testMsg.Seq, testMsg.Kind, 7, nil, nil, 0, testPeerID, true))
+			},
+			args: args{
+				user: models.User{
+					ID: testUserID,
+				},
+			},
+			want:   []models.Chat{directChat},
+			status: models.OK,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -110,6 +188,7 @@ func TestPostgresRepo_GetChat(t *testing.T) {
 	}
 	type args struct {
 		chat models.Chat
+		user models.User
 	}
 
 	db, mock, err := sqlmock.New()
@@ -124,12 +203,14 @@ func TestPostgresRepo_GetChat(t *testing.T) {
 	testTimestamp := time.Now().Unix()
 	participant1 := uuid.New()
 	participant2 := uuid.New()
+	testUser := models.User{ID: participant1}
 
 	testMsg := models.Message{
 		MsgID:     uuid.New(),
 		SenderID:  uuid.New(),
 		Payload:   "test",
 		CreatedAt: int64(1),
+		Kind:      models.ChatCreatedMessage,
 	}
 
 	passingTestChat := models.Chat{
@@ -137,20 +218,28 @@ func TestPostgresRepo_GetChat(t *testing.T) {
 	}
 	expectedTestChat := models.Chat{
 		ChatID:       testChatID,
+		Kind:         models.DirectChat,
 		Name:         testName,
 		PhotoURL:     testURL,
 		CreatedAt:    testTimestamp,
 		Participants: []uuid.UUID{participant1, participant2},
+		Members: []models.ChatMember{
+			{UserID: participant1, Role: models.OwnerRole},
+			{UserID: participant2, Role: models.MemberRole},
+		},
 		LastMessage: models.Message{
 			MsgID:     testMsg.MsgID,
 			SenderID:  testMsg.SenderID,
 			Payload:   testMsg.Payload,
 			CreatedAt: testMsg.CreatedAt,
+			Seq:       testMsg.Seq,
+			Kind:      testMsg.Kind,
 		},
 	}
 
 	columns := []string{
 		"c.chat_id",
+		"c.kind",
 		"cp.chat_name",
 		"c.photo_url",
 		"c.created_at",
@@ -158,10 +247,14 @@ func TestPostgresRepo_GetChat(t *testing.T) {
 		"m.sender_id",
 		"m.payload",
 		"m.created_at",
+		"m.seq",
+		"m.kind",
+		"cp.chat_name_custom",
 	}
 
 	pColumns := []string{
 		"participant_id",
+		"role",
 	}
 
 	tests := []struct {
@@ -179,16 +272,19 @@ func TestPostgresRepo_GetChat(t *testing.T) {
 			},
 			pre: func() {
 				mock.ExpectQuery(regexp.QuoteMeta(GetChatInfoQuery)).
-					WithArgs(expectedTestChat.ChatID).
-					WillReturnRows(sqlmock.NewRows(columns).AddRow(testChatID,
+					WithArgs(expectedTestChat.ChatID, testUser.ID).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(testChatID, models.DirectChat,
 						testName, testURL, testTimestamp, testMsg.MsgID, testMsg.SenderID,
-						testMsg.Payload, testMsg.CreatedAt))
+						testMsg.Payload, testMsg.CreatedAt, testMsg.Seq, testMsg.Kind, false))
 				mock.ExpectQuery(regexp.QuoteMeta(GetChatParticipantsQuery)).
 					WithArgs(expectedTestChat.ChatID).
-					WillReturnRows(sqlmock.NewRows(pColumns).AddRow(participant1).AddRow(participant2))
+					WillReturnRows(sqlmock.NewRows(pColumns).
+						AddRow(participant1, models.OwnerRole).
+						AddRow(participant2, models.MemberRole))
 			},
 			args: args{
 				chat: passingTestChat,
+				user: testUser,
 			},
 			want:   expectedTestChat,
 			status: models.OK,
@@ -200,7 +296,7 @@ func TestPostgresRepo_GetChat(t *testing.T) {
 				pool: tt.fields.pool,
 			}
 			tt.pre()
-			got, status := pr.GetChat(context.Background(), tt.args.chat)
+			got, status := pr.GetChat(context.Background(), tt.args.chat, tt.args.user)
 			if status != tt.status {
 				t.Errorf("GetChat() error = %v, wantErr %v", status, tt.status)
 				return
@@ -240,6 +336,8 @@ func TestPostgresRepo_GetChatMessages(t *testing.T) {
 		SenderID:  testUserID,
 		MsgID:     testMsgID,
 		CreatedAt: testTimestamp,
+		Seq:       1,
+		Kind:      models.TextMessage,
 	}
 
 	testChat := models.Chat{
@@ -251,6 +349,8 @@ func TestPostgresRepo_GetChatMessages(t *testing.T) {
 		"sender_id",
 		"payload",
 		"created_at",
+		"seq",
+		"kind",
 	}
 
 	tests := []struct {
@@ -264,10 +364,10 @@ func TestPostgresRepo_GetChatMessages(t *testing.T) {
 		{
 			name: "Successful",
 			pre: func() {
-				mock.ExpectQuery(regexp.QuoteMeta(GetChatMessagesQuery)).
+				mock.ExpectQuery(regexp.QuoteMeta(GetChatMessagesBeforeQuery)).
 					WithArgs(testChatID, int64(0), int64(1)).
 					WillReturnRows(sqlmock.NewRows(columns).AddRow(testMsgID,
-						testUserID, testPayload, testTimestamp))
+						testUserID, testPayload, testTimestamp, 1, models.TextMessage))
 			},
 			fields: fields{
 				pool: db,
@@ -275,13 +375,37 @@ func TestPostgresRepo_GetChatMessages(t *testing.T) {
 			args: args{
 				chat: testChat,
 				opts: models.Opts{
-					Page:  0,
 					Limit: 1,
 				},
 			},
 			want:   models.Messages{testMsg},
 			status: models.OK,
 		},
+		{
+			name: "newer messages are sorted from the newest",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetChatMessagesAfterQuery)).
+					WithArgs(testChatID, int64(1), int64(2)).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(testMsgID, testUserID, testPayload, testTimestamp, 2, models.TextMessage).
+						AddRow(testMsgID, testUserID, testPayload, testTimestamp, 3, models.TextMessage))
+			},
+			fields: fields{
+				pool: db,
+			},
+			args: args{
+				chat: testChat,
+				opts: models.Opts{
+					Limit: 2,
+					After: models.Cursor{Seq: 1},
+				},
+			},
+			want: models.Messages{
+				{ChatID: testChatID, Payload: testPayload, SenderID: testUserID, MsgID: testMsgID, CreatedAt: testTimestamp, Seq: 3, Kind: models.TextMessage},
+				{ChatID: testChatID, Payload: testPayload, SenderID: testUserID, MsgID: testMsgID, CreatedAt: testTimestamp, Seq: 2, Kind: models.TextMessage},
+			},
+			status: models.OK,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -323,11 +447,16 @@ func TestPostgresRepo_CreateChat(t *testing.T) {
 
 	testChat := models.Chat{
 		ChatID:   uuid.New(),
+		Kind:     models.DirectChat,
 		PhotoURL: "test.png",
 		Participants: []uuid.UUID{
 			testUser1.ID,
 			testUser2.ID,
 		},
+		Members: []models.ChatMember{
+			{UserID: testUser1.ID, Role: models.OwnerRole},
+			{UserID: testUser2.ID, Role: models.MemberRole},
+		},
 	}
 
 	testCtx := context.Background()
@@ -352,13 +481,13 @@ func TestPostgresRepo_CreateChat(t *testing.T) {
 			pre: func() {
 				mock.ExpectBegin().WillReturnError(nil)
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name).
+					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name, models.OwnerRole).
 					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser2.ID, testUser1.Name).
+					WithArgs(testChat.ChatID, testUser2.ID, testUser1.Name, models.MemberRole).
 					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatQuery)).WithArgs(testChat.ChatID,
-					testChat.PhotoURL, testChat.CreatedAt).
+					testChat.PhotoURL, testChat.CreatedAt, testChat.Kind, testChat.PersonalKey()).
 					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit().WillReturnError(nil)
 			},
@@ -380,13 +509,13 @@ func TestPostgresRepo_CreateChat(t *testing.T) {
 			pre: func() {
 				mock.ExpectBegin().WillReturnError(nil)
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name).
+					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name, models.OwnerRole).
 					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser2.ID, testUser1.Name).
+					WithArgs(testChat.ChatID, testUser2.ID, testUser1.Name, models.MemberRole).
 					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatQuery)).WithArgs(testChat.ChatID,
-					testChat.PhotoURL, testChat.CreatedAt).
+					testChat.PhotoURL, testChat.CreatedAt, testChat.Kind, testChat.PersonalKey()).
 					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 0))
 				mock.ExpectRollback().WillReturnError(nil)
 			},
@@ -400,6 +529,34 @@ func TestPostgresRepo_CreateChat(t *testing.T) {
 			},
 			status: models.InternalError,
 		},
+		{
+			name: "users already have a direct chat",
+			fields: fields{
+				pool: db,
+			},
+			pre: func() {
+				mock.ExpectBegin().WillReturnError(nil)
+				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
+					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name, models.OwnerRole).
+					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
+					WithArgs(testChat.ChatID, testUser2.ID, testUser1.Name, models.MemberRole).
+					WillReturnError(nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(CreateChatQuery)).WithArgs(testChat.ChatID,
+					testChat.PhotoURL, testChat.CreatedAt, testChat.Kind, testChat.PersonalKey()).
+					WillReturnError(&pgconn.PgError{Code: "23505"})
+				mock.ExpectRollback().WillReturnError(nil)
+			},
+			args: args{
+				ctx:  testCtx,
+				chat: testChat,
+				chatNames: map[string]string{
+					testUser1.ID.String(): testUser2.Name,
+					testUser2.ID.String(): testUser1.Name,
+				},
+			},
+			status: models.Conflict,
+		},
 		{
 			name: "fail to add a participant",
 			fields: fields{
@@ -408,7 +565,7 @@ func TestPostgresRepo_CreateChat(t *testing.T) {
 			pre: func() {
 				mock.ExpectBegin().WillReturnError(nil)
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name).
+					WithArgs(testChat.ChatID, testUser1.ID, testUser2.Name, models.OwnerRole).
 					WillReturnError(fmt.Errorf("")).
 					WillReturnResult(sqlmock.NewResult(0, 0))
 				mock.ExpectRollback().WillReturnError(nil)
@@ -596,11 +753,11 @@ func TestPostgresRepo_AddUsersToChat(t *testing.T) {
 			pre: func() {
 				mock.ExpectBegin().WillReturnError(nil)
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser1.ID, testChatNames[testUser1.ID.String()]).
+					WithArgs(testChat.ChatID, testUser1.ID, testChatNames[testUser1.ID.String()], models.MemberRole).
 					WillReturnError(nil).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(CreateChatParticipantsQuery)).
-					WithArgs(testChat.ChatID, testUser2.ID, testChatNames[testUser2.ID.String()]).
+					WithArgs(testChat.ChatID, testUser2.ID, testChatNames[testUser2.ID.String()], models.MemberRole).
 					WillReturnError(nil).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit().WillReturnError(nil)
@@ -739,10 +896,33 @@ func TestPostgresRepo_DeleteChat(t *testing.T) {
 			},
 			status: models.Deleted,
 			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(DeleteChatParticipantsQuery)).
+					WithArgs(testChat.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 2))
 				mock.ExpectExec(regexp.QuoteMeta(DeleteChatQuery)).
 					WithArgs(testChat.ChatID).
 					WillReturnError(nil).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "failed to delete participants",
+			fields: fields{
+				pool: db,
+			},
+			args: args{
+				ctx:  testCtx,
+				chat: testChat,
+			},
+			status: models.InternalError,
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(DeleteChatParticipantsQuery)).
+					WithArgs(testChat.ChatID).
+					WillReturnError(fmt.Errorf(""))
+				mock.ExpectRollback()
 			},
 		},
 	}
@@ -755,6 +935,9 @@ func TestPostgresRepo_DeleteChat(t *testing.T) {
 			if status := pr.DeleteChat(tt.args.ctx, tt.args.chat); status != tt.status {
 				t.Errorf("DeleteChat() error = %v, wantErr %v", status, tt.status)
 			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
 		})
 	}
 }
@@ -771,7 +954,8 @@ func TestPostgresRepo_DeleteMessage(t *testing.T) {
 	defer db.Close()
 
 	testMsg := models.Message{
-		MsgID: uuid.New(),
+		MsgID:  uuid.New(),
+		ChatID: uuid.New(),
 	}
 	testCtx := context.Background()
 
@@ -796,13 +980,96 @@ func TestPostgresRepo_DeleteMessage(t *testing.T) {
 				message: testMsg,
 			},
 			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(LockChatQuery)).
+					WithArgs(testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(InsertTombstoneQuery)).
+					WithArgs(testMsg.MsgID, testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(DeleteMessageQuery)).
 					WithArgs(testMsg.MsgID).
 					WillReturnError(nil).
 					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(UpdateChatCountersQuery)).
+					WithArgs(testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			status: models.Deleted,
+		},
+		{
+			name: "message is only queued",
+			fields: fields{
+				pool: db,
+			},
+			args: args{
+				ctx:     testCtx,
+				message: testMsg,
+			},
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(LockChatQuery)).
+					WithArgs(testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(InsertTombstoneQuery)).
+					WithArgs(testMsg.MsgID, testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(DeleteMessageQuery)).
+					WithArgs(testMsg.MsgID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
 			},
 			status: models.Deleted,
 		},
+		{
+			name: "counters update failure",
+			fields: fields{
+				pool: db,
+			},
+			args: args{
+				ctx:     testCtx,
+				message: testMsg,
+			},
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(LockChatQuery)).
+					WithArgs(testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(InsertTombstoneQuery)).
+					WithArgs(testMsg.MsgID, testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(DeleteMessageQuery)).
+					WithArgs(testMsg.MsgID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(UpdateChatCountersQuery)).
+					WithArgs(testMsg.ChatID).
+					WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+			},
+			status: models.InternalError,
+		},
+		{
+			name: "tombstone failure",
+			fields: fields{
+				pool: db,
+			},
+			args: args{
+				ctx:     testCtx,
+				message: testMsg,
+			},
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(LockChatQuery)).
+					WithArgs(testMsg.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(InsertTombstoneQuery)).
+					WithArgs(testMsg.MsgID, testMsg.ChatID).
+					WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+			},
+			status: models.InternalError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -813,6 +1080,762 @@ func TestPostgresRepo_DeleteMessage(t *testing.T) {
 			if status := pr.DeleteMessage(tt.args.ctx, tt.args.message); status != tt.status {
 				t.Errorf("DeleteMessage() error = %v, wantErr %v", err, tt.status)
 			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_IsChatParticipant(t *testing.T) {
+	type fields struct {
+		pool *sql.DB
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func()
+		want   bool
+		status models.StatusCode
+	}{
+		{
+			name:   "participant",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(IsChatParticipantQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			},
+			want:   true,
+			status: models.OK,
+		},
+		{
+			name:   "not a participant",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(IsChatParticipantQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			want:   false,
+			status: models.OK,
+		},
+		{
+			name:   "db failure",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(IsChatParticipantQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnError(fmt.Errorf(""))
+			},
+			want:   false,
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: tt.fields.pool,
+			}
+			tt.pre()
+			got, status := pr.IsChatParticipant(testCtx, testChat, testUser)
+			if status != tt.status {
+				t.Errorf("IsChatParticipant() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IsChatParticipant() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_UpdateLastRead(t *testing.T) {
+	type fields struct {
+		pool *sql.DB
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testMsg := models.Message{MsgID: uuid.New(), CreatedAt: time.Now().Unix(), Seq: 5}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func()
+		want   bool
+		status models.StatusCode
+	}{
+		{
+			name:   "cursor moved",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectExec(regexp.QuoteMeta(UpdateLastReadQuery)).
+					WithArgs(testMsg.MsgID, testMsg.Seq, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want:   true,
+			status: models.OK,
+		},
+		{
+			name:   "newer message already read",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectExec(regexp.QuoteMeta(UpdateLastReadQuery)).
+					WithArgs(testMsg.MsgID, testMsg.Seq, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			want:   false,
+			status: models.OK,
+		},
+		{
+			name:   "db failure",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectExec(regexp.QuoteMeta(UpdateLastReadQuery)).
+					WithArgs(testMsg.MsgID, testMsg.Seq, testChat.ChatID, testUser.ID).
+					WillReturnError(fmt.Errorf(""))
+			},
+			want:   false,
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: tt.fields.pool,
+			}
+			tt.pre()
+			got, status := pr.UpdateLastRead(testCtx, testChat, testUser, testMsg)
+			if status != tt.status {
+				t.Errorf("UpdateLastRead() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("UpdateLastRead() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_GetSeenBy(t *testing.T) {
+	type fields struct {
+		pool *sql.DB
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testMsg := models.Message{MsgID: uuid.New(), SenderID: uuid.New(), CreatedAt: time.Now().Unix(), Seq: 5}
+	testReceipt := models.ReadReceipt{UserID: uuid.New(), LastReadMsgID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func()
+		want   []models.ReadReceipt
+		status models.StatusCode
+	}{
+		{
+			name:   "seen by a participant",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetSeenByQuery)).
+					WithArgs(testChat.ChatID, testMsg.SenderID, testMsg.Seq).
+					WillReturnRows(sqlmock.NewRows([]string{"participant_id", "last_read_msg_id"}).
+						AddRow(testReceipt.UserID, testReceipt.LastReadMsgID))
+			},
+			want:   []models.ReadReceipt{testReceipt},
+			status: models.OK,
+		},
+		{
+			name:   "not seen",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetSeenByQuery)).
+					WithArgs(testChat.ChatID, testMsg.SenderID, testMsg.Seq).
+					WillReturnRows(sqlmock.NewRows([]string{"participant_id", "last_read_msg_id"}))
+			},
+			want:   []models.ReadReceipt{},
+			status: models.OK,
+		},
+		{
+			name:   "db failure",
+			fields: fields{pool: db},
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetSeenByQuery)).
+					WithArgs(testChat.ChatID, testMsg.SenderID, testMsg.Seq).
+					WillReturnError(fmt.Errorf(""))
+			},
+			want:   nil,
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: tt.fields.pool,
+			}
+			tt.pre()
+			got, status := pr.GetSeenBy(testCtx, testChat, testMsg)
+			if status != tt.status {
+				t.Errorf("GetSeenBy() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSeenBy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_GetLastSeq(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		want   int64
+		status models.StatusCode
+	}{
+		{
+			name: "chat with messages",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetLastSeqQuery)).
+					WithArgs(testChat.ChatID).
+					WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(42))
+			},
+			want:   42,
+			status: models.OK,
+		},
+		{
+			name: "db failure",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetLastSeqQuery)).
+					WithArgs(testChat.ChatID).
+					WillReturnError(fmt.Errorf(""))
+			},
+			want:   0,
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			got, status := pr.GetLastSeq(testCtx, testChat)
+			if status != tt.status {
+				t.Errorf("GetLastSeq() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetLastSeq() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_GetParticipantRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		want   models.ChatRole
+		status models.StatusCode
+	}{
+		{
+			name: "participant",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetParticipantRoleQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(models.AdminRole))
+			},
+			want:   models.AdminRole,
+			status: models.OK,
+		},
+		{
+			name: "not a participant",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetParticipantRoleQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnError(sql.ErrNoRows)
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "db failure",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetParticipantRoleQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnError(fmt.Errorf(""))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			got, status := pr.GetParticipantRole(testCtx, testChat, testUser)
+			if status != tt.status {
+				t.Errorf("GetParticipantRole() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetParticipantRole() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_GetMembership(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name     string
+		pre      func()
+		wantKind models.ChatKind
+		wantRole models.ChatRole
+		status   models.StatusCode
+	}{
+		{
+			name: "member of a channel",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetMembershipQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"kind", "role"}).AddRow(models.ChannelChat, models.MemberRole))
+			},
+			wantKind: models.ChannelChat,
+			wantRole: models.MemberRole,
+			status:   models.OK,
+		},
+		{
+			name: "not a participant",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetMembershipQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnError(sql.ErrNoRows)
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "db failure",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetMembershipQuery)).
+					WithArgs(testChat.ChatID, testUser.ID).
+					WillReturnError(fmt.Errorf(""))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			kind, role, status := pr.GetMembership(testCtx, testChat, testUser)
+			if status != tt.status {
+				t.Errorf("GetMembership() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if kind != tt.wantKind || role != tt.wantRole {
+				t.Errorf("GetMembership() got = %v, %v, want %v, %v", kind, role, tt.wantKind, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_GetUserChatIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testUser := models.User{ID: uuid.New()}
+	firstChat := uuid.New()
+	secondChat := uuid.New()
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		want   []uuid.UUID
+		status models.StatusCode
+	}{
+		{
+			name: "participant of two chats",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetUserChatIDsQuery)).
+					WithArgs(testUser.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"chat_id"}).AddRow(firstChat).AddRow(secondChat))
+			},
+			want:   []uuid.UUID{firstChat, secondChat},
+			status: models.OK,
+		},
+		{
+			name: "no chats",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetUserChatIDsQuery)).
+					WithArgs(testUser.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"chat_id"}))
+			},
+			want:   []uuid.UUID{},
+			status: models.OK,
+		},
+		{
+			name: "db failure",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetUserChatIDsQuery)).
+					WithArgs(testUser.ID).
+					WillReturnError(fmt.Errorf(""))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			got, status := pr.GetUserChatIDs(testCtx, testUser)
+			if status != tt.status {
+				t.Errorf("GetUserChatIDs() error = %v, wantErr %v", status, tt.status)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserChatIDs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_UpdateParticipantRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		status models.StatusCode
+	}{
+		{
+			name: "success",
+			pre: func() {
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantRoleQuery)).
+					WithArgs(models.AdminRole, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			status: models.OK,
+		},
+		{
+			name: "not a participant",
+			pre: func() {
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantRoleQuery)).
+					WithArgs(models.AdminRole, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			if status := pr.UpdateParticipantRole(testCtx, testChat, testUser, models.AdminRole); status != tt.status {
+				t.Errorf("UpdateParticipantRole() error = %v, wantErr %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_TransferOwnership(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testOwner := models.User{ID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		status models.StatusCode
+	}{
+		{
+			name: "success",
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantRoleQuery)).
+					WithArgs(models.AdminRole, testChat.ChatID, testOwner.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantRoleQuery)).
+					WithArgs(models.OwnerRole, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			status: models.OK,
+		},
+		{
+			name: "user is not a participant, owner is kept",
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantRoleQuery)).
+					WithArgs(models.AdminRole, testChat.ChatID, testOwner.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantRoleQuery)).
+					WithArgs(models.OwnerRole, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "fail to start transaction",
+			pre: func() {
+				mock.ExpectBegin().WillReturnError(fmt.Errorf(""))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			if status := pr.TransferOwnership(testCtx, testChat, testOwner, testUser); status != tt.status {
+				t.Errorf("TransferOwnership() error = %v, wantErr %v", status, tt.status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_UpdateChatName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testChat := models.Chat{ChatID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testName := "renamed"
+	testCtx := context.Background()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		users  []models.User
+		status models.StatusCode
+	}{
+		{
+			name: "for every participant",
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(UpdateChatNameQuery)).
+					WithArgs(testName, testChat.ChatID).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			status: models.OK,
+		},
+		{
+			name: "for the user",
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantChatNameQuery)).
+					WithArgs(testName, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			users:  []models.User{testUser},
+			status: models.OK,
+		},
+		{
+			name: "user is not a participant",
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(UpdateParticipantChatNameQuery)).
+					WithArgs(testName, testChat.ChatID, testUser.ID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			users:  []models.User{testUser},
+			status: models.NotFound,
+		},
+		{
+			name: "db failure",
+			pre: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(UpdateChatNameQuery)).
+					WithArgs(testName, testChat.ChatID).
+					WillReturnError(fmt.Errorf(""))
+				mock.ExpectRollback()
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			if status := pr.UpdateChatName(testCtx, testChat, testName, tt.users...); status != tt.status {
+				t.Errorf("UpdateChatName() error = %v, wantErr %v", status, tt.status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPostgresRepo_GetPersonalChat(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testUserID1 := uuid.New()
+	testUserID2 := uuid.New()
+	testChatID := uuid.New()
+	// the key does not depend on the order of the participants
+	testChat := models.Chat{Kind: models.DirectChat, Participants: []uuid.UUID{testUserID2, testUserID1}}
+	testKey := models.Chat{Participants: []uuid.UUID{testUserID1, testUserID2}}.PersonalKey()
+
+	tests := []struct {
+		name   string
+		pre    func()
+		want   models.Chat
+		status models.StatusCode
+	}{
+		{
+			name: "users have a direct chat",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetPersonalChatQuery)).WithArgs(testKey).
+					WillReturnRows(sqlmock.NewRows([]string{"chat_id"}).AddRow(testChatID))
+				mock.ExpectQuery(regexp.QuoteMeta(GetChatInfoQuery)).WithArgs(testChatID, testUserID1).
+					WillReturnRows(sqlmock.NewRows([]string{"c.chat_id", "c.kind", "cp.chat_name", "c.photo_url",
+						"c.created_at", "m.msg_id", "m.sender_id", "m.payload", "m.created_at", "m.seq", "m.kind",
+						"cp.chat_name_custom"}).
+						AddRow(testChatID, models.DirectChat, "test", "test.png", 1, nil, nil, nil, nil, nil, nil, false))
+				mock.ExpectQuery(regexp.QuoteMeta(GetChatParticipantsQuery)).WithArgs(testChatID).
+					WillReturnRows(sqlmock.NewRows([]string{"participant_id", "role"}).
+						AddRow(testUserID1, models.OwnerRole).
+						AddRow(testUserID2, models.MemberRole))
+			},
+			want: models.Chat{
+				ChatID:       testChatID,
+				Kind:         models.DirectChat,
+				Name:         "test",
+				PhotoURL:     "test.png",
+				CreatedAt:    1,
+				Participants: []uuid.UUID{testUserID1, testUserID2},
+				Members: []models.ChatMember{
+					{UserID: testUserID1, Role: models.OwnerRole},
+					{UserID: testUserID2, Role: models.MemberRole},
+				},
+			},
+			status: models.OK,
+		},
+		{
+			name: "users have no direct chat",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetPersonalChatQuery)).WithArgs(testKey).
+					WillReturnError(sql.ErrNoRows)
+			},
+			want:   models.Chat{},
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := PostgresRepo{
+				pool: db,
+			}
+			tt.pre()
+			got, status := pr.GetPersonalChat(context.Background(), testChat, models.User{ID: testUserID1})
+			if status != tt.status {
+				t.Errorf("GetPersonalChat() error = %v, wantErr %v", status, tt.status)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetPersonalChat() got = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
 		})
 	}
 }
@@ -3,55 +3,234 @@ package repo
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slog"
 	"our-little-chatik/internal/models"
-	"sort"
+	"our-little-chatik/internal/pkg/seq"
+	"our-little-chatik/internal/pkg/tracing"
 )
 
+var tracer = otel.Tracer("our-little-chatik/internal/chat/internal/repo")
+
 type RedisRepo struct {
+	*seq.RedisCounter
 	cl *redis.Client
 }
 
 func NewRedisRepo(cl *redis.Client) *RedisRepo {
 	return &RedisRepo{
-		cl: cl,
+		RedisCounter: seq.NewRedisCounter(cl),
+		cl:           cl,
 	}
 }
 
-func (r RedisRepo) GetChatMessages(chat models.Chat,
-	opts models.Opts) (models.Messages, models.StatusCode) {
-	keys, err := r.cl.Keys(context.Background(), chat.ChatID.String()+"*").Result()
-	if err != nil {
-		return nil, models.NotFound
-	}
-	msgList := make(models.Messages, 0)
-	values, err := r.cl.MGet(context.Background(), keys...).Result()
-	if err != nil {
-		return nil, models.NotFound
-	}
+func messagesKey(chat models.Chat) string {
+	return fmt.Sprintf(models.MessagesKeyFormat, chat.ChatID.String())
+}
 
+func messageSeqsKey(chat models.Chat) string {
+	return fmt.Sprintf(models.MessageSeqsKeyFormat, chat.ChatID.String())
+}
+
+func decodeMessages(values []string) models.Messages {
+	msgList := make(models.Messages, 0, len(values))
 	for _, val := range values {
 		msg := models.Message{}
-		err := json.Unmarshal([]byte(val.(string)), &msg)
+		err := json.Unmarshal([]byte(val), &msg)
 		if err != nil {
 			slog.Error(err.Error())
 			continue
 		}
+		// the trace context is meant for the flusher only
+		msg.Trace = nil
 		msgList = append(msgList, msg)
 	}
-	sort.Sort(msgList)
+	return msgList
+}
 
-	limit := int(opts.Limit)
-	page := int(opts.Page)
-	firstElemIdx := limit * page
-	lastElemIdx := limit*page + limit
-	if lastElemIdx > len(msgList) {
-		lastElemIdx = len(msgList)
-	}
-	if len(msgList) <= firstElemIdx {
-		return models.Messages{}, models.NotFound
+// GetChatMessages returns the window of the messages that have not been flushed to the database yet.
+func (r RedisRepo) GetChatMessages(chat models.Chat,
+	opts models.Opts) (models.Messages, models.StatusCode) {
+	var (
+		values []string
+		err    error
+	)
+	if opts.After.Seq != 0 {
+		values, err = r.cl.ZRangeByScore(context.Background(), messagesKey(chat), &redis.ZRangeBy{
+			Min:   fmt.Sprintf("(%d", opts.After.Seq),
+			Max:   "+inf",
+			Count: opts.Limit,
+		}).Result()
 	} else {
-		return msgList[firstElemIdx:lastElemIdx], models.OK
+		max := "+inf"
+		if opts.Before.Seq != 0 {
+			max = fmt.Sprintf("(%d", opts.Before.Seq)
+		}
+		values, err = r.cl.ZRevRangeByScore(context.Background(), messagesKey(chat), &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   max,
+			Count: opts.Limit,
+		}).Result()
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, models.InternalError
+	}
+	return decodeMessages(values).Window(opts), models.OK
+}
+
+// GetMessage looks up the message that has not been flushed to the database yet by its seq.
+// The seqs of the flushed messages may still be indexed, so the message found is checked.
+func (r RedisRepo) GetMessage(ctx context.Context, message models.Message) (models.Message, models.StatusCode) {
+	chat := models.Chat{ChatID: message.ChatID}
+	seq, err := r.cl.HGet(ctx, messageSeqsKey(chat), message.MsgID.String()).Result()
+	if err == redis.Nil {
+		return models.Message{}, models.NotFound
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		return models.Message{}, models.InternalError
+	}
+	values, err := r.cl.ZRangeByScore(ctx, messagesKey(chat), &redis.ZRangeBy{
+		Min: seq,
+		Max: seq,
+	}).Result()
+	if err != nil {
+		slog.Error(err.Error())
+		return models.Message{}, models.InternalError
+	}
+	for _, msg := range decodeMessages(values) {
+		if msg.MsgID == message.MsgID {
+			return msg, models.OK
+		}
+	}
+	return models.Message{}, models.NotFound
+}
+
+// SaveMessage queues the message for the flusher, the same way the peer service queues
+// the messages of the users.
+func (r RedisRepo) SaveMessage(ctx context.Context, message models.Message) models.StatusCode {
+	message.Trace = tracing.Inject(ctx)
+	bMsg, err := json.Marshal(&message)
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	chat := models.Chat{ChatID: message.ChatID}
+	pipe := r.cl.TxPipeline()
+	pipe.ZAdd(ctx, messagesKey(chat), redis.Z{
+		Score:  float64(message.Seq),
+		Member: string(bMsg),
+	})
+	pipe.HSet(ctx, messageSeqsKey(chat), message.MsgID.String(), message.Seq)
+	pipe.SAdd(ctx, models.PendingChatsKey, message.ChatID.String())
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	return models.OK
+}
+
+// DeleteMessage drops the message from the queue, so that it is not flushed after it is deleted.
+func (r RedisRepo) DeleteMessage(ctx context.Context, message models.Message) models.StatusCode {
+	seq := fmt.Sprintf("%d", message.Seq)
+	chat := models.Chat{ChatID: message.ChatID}
+	pipe := r.cl.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, messagesKey(chat), seq, seq)
+	pipe.HDel(ctx, messageSeqsKey(chat), message.MsgID.String())
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	return models.OK
+}
+
+// DeleteChatMessages drops the messages of the chat that have not been flushed yet.
+func (r RedisRepo) DeleteChatMessages(ctx context.Context, chat models.Chat) models.StatusCode {
+	err := r.cl.Del(ctx, messagesKey(chat), messageSeqsKey(chat)).Err()
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	return models.OK
+}
+
+// GetQueuedMessages returns the messages of every chat that have not been flushed to the database yet
+// and are newer than the last flushed message of the chat, in one round trip.
+func (r RedisRepo) GetQueuedMessages(ctx context.Context, chats []models.Chat) ([]models.Messages, models.StatusCode) {
+	pipe := r.cl.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, 0, len(chats))
+	for _, chat := range chats {
+		cmds = append(cmds, pipe.ZRangeByScore(ctx, messagesKey(chat), &redis.ZRangeBy{
+			Min: fmt.Sprintf("(%d", chat.LastMessage.Seq),
+			Max: "+inf",
+		}))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, models.InternalError
+	}
+	queued := make([]models.Messages, 0, len(chats))
+	for _, cmd := range cmds {
+		queued = append(queued, decodeMessages(cmd.Val()))
+	}
+	return queued, models.OK
+}
+
+// startPublishSpan starts the span of a document published on the channel,
+// the document carries the trace context of the returned ctx.
+func startPublishSpan(ctx context.Context, channel string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "publish "+channel,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystem("redis"), semconv.MessagingDestinationName(channel)))
+}
+
+// PublishChatNotification sends the notification to the peers connected to the chat.
+func (r RedisRepo) PublishChatNotification(ctx context.Context, chat models.Chat,
+	notification models.Notification) models.StatusCode {
+	channel := fmt.Sprintf("chat_%s", chat.ChatID.String())
+	ctx, span := startPublishSpan(ctx, channel)
+	defer span.End()
+
+	notification.Trace = tracing.Inject(ctx)
+	bNotification, err := json.Marshal(&notification)
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	err = r.cl.Publish(ctx, channel, string(bNotification)).Err()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	return models.OK
+}
+
+// PublishChatEvent notifies peer service about a change of the chat.
+func (r RedisRepo) PublishChatEvent(ctx context.Context, event models.ChatEvent) models.StatusCode {
+	ctx, span := startPublishSpan(ctx, models.ChatEventsChannel)
+	defer span.End()
+
+	event.Trace = tracing.Inject(ctx)
+	bEvent, err := json.Marshal(&event)
+	if err != nil {
+		slog.Error(err.Error())
+		return models.InternalError
+	}
+	err = r.cl.Publish(ctx, models.ChatEventsChannel, string(bEvent)).Err()
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		slog.Error(err.Error())
+		return models.InternalError
 	}
+	return models.OK
 }
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-redis/redismock/v9"
@@ -40,11 +41,14 @@ func TestRedisRepo_GetChatMessages(t *testing.T) {
 	}
 
 	db, mock := redismock.NewClientMock()
-	key := fmt.Sprintf("%s_%s", testChat.ChatID.String(), testMsgID.String())
+	key := fmt.Sprintf(models.MessagesKeyFormat, testChat.ChatID.String())
 
 	bData, _ := json.Marshal(testMsg)
-	mock.ExpectKeys(testChat.ChatID.String() + "*").SetVal([]string{key})
-	mock.ExpectMGet(key).SetVal([]interface{}{string(bData)})
+	mock.ExpectZRevRangeByScore(key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "+inf",
+		Count: 10,
+	}).SetVal([]string{string(bData)})
 
 	tests := []struct {
 		name   string
@@ -70,7 +74,7 @@ func TestRedisRepo_GetChatMessages(t *testing.T) {
 			r := RedisRepo{
 				cl: tt.fields.cl,
 			}
-			got, status := r.GetChatMessages(tt.args.chat, models.Opts{Limit: 10, Page: 0})
+			got, status := r.GetChatMessages(tt.args.chat, models.Opts{Limit: 10})
 			if status != tt.status {
 				t.Errorf("GetChatMessages() error = %v, wantErr %v", status, tt.status)
 				return
@@ -81,3 +85,295 @@ func TestRedisRepo_GetChatMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisRepo_PublishChatEvent(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testEvent := models.ChatEvent{
+		Type:   models.UsersRemovedFromChat,
+		ChatID: uuid.New(),
+		Users:  []uuid.UUID{uuid.New()},
+	}
+	bEvent, _ := json.Marshal(&testEvent)
+
+	tests := []struct {
+		name   string
+		pre    func()
+		status models.StatusCode
+	}{
+		{
+			name: "published",
+			pre: func() {
+				mock.ExpectPublish(models.ChatEventsChannel, string(bEvent)).SetVal(1)
+			},
+			status: models.OK,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectPublish(models.ChatEventsChannel, string(bEvent)).SetErr(fmt.Errorf("down"))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl: db,
+			}
+			tt.pre()
+			if status := r.PublishChatEvent(context.Background(), testEvent); status != tt.status {
+				t.Errorf("PublishChatEvent() error = %v, wantErr %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_DeleteMessage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testMsg := models.Message{
+		MsgID:  uuid.New(),
+		ChatID: uuid.New(),
+		Seq:    7,
+	}
+	key := fmt.Sprintf(models.MessagesKeyFormat, testMsg.ChatID.String())
+	seqsKey := fmt.Sprintf(models.MessageSeqsKeyFormat, testMsg.ChatID.String())
+
+	tests := []struct {
+		name   string
+		pre    func()
+		status models.StatusCode
+	}{
+		{
+			name: "deleted",
+			pre: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectZRemRangeByScore(key, "7", "7").SetVal(1)
+				mock.ExpectHDel(seqsKey, testMsg.MsgID.String()).SetVal(1)
+				mock.ExpectTxPipelineExec()
+			},
+			status: models.OK,
+		},
+		{
+			name: "already flushed",
+			pre: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectZRemRangeByScore(key, "7", "7").SetVal(0)
+				mock.ExpectHDel(seqsKey, testMsg.MsgID.String()).SetVal(0)
+				mock.ExpectTxPipelineExec()
+			},
+			status: models.OK,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectZRemRangeByScore(key, "7", "7").SetErr(fmt.Errorf("down"))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl: db,
+			}
+			tt.pre()
+			if status := r.DeleteMessage(context.Background(), testMsg); status != tt.status {
+				t.Errorf("DeleteMessage() error = %v, wantErr %v", status, tt.status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_SaveMessage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testMsg := models.Message{
+		MsgID:    uuid.New(),
+		ChatID:   uuid.New(),
+		SenderID: uuid.New(),
+		Kind:     models.UsersAddedMessage,
+		Payload:  `{"users":[]}`,
+		Seq:      9,
+	}
+	bMsg, _ := json.Marshal(&testMsg)
+	key := fmt.Sprintf(models.MessagesKeyFormat, testMsg.ChatID.String())
+
+	tests := []struct {
+		name   string
+		pre    func()
+		status models.StatusCode
+	}{
+		{
+			name: "queued for the flusher",
+			pre: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectZAdd(key, redis.Z{Score: 9, Member: string(bMsg)}).SetVal(1)
+				mock.ExpectHSet(fmt.Sprintf(models.MessageSeqsKeyFormat, testMsg.ChatID.String()),
+					testMsg.MsgID.String(), int64(9)).SetVal(1)
+				mock.ExpectSAdd(models.PendingChatsKey, testMsg.ChatID.String()).SetVal(1)
+				mock.ExpectTxPipelineExec()
+			},
+			status: models.OK,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectZAdd(key, redis.Z{Score: 9, Member: string(bMsg)}).SetErr(fmt.Errorf("down"))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl: db,
+			}
+			tt.pre()
+			if status := r.SaveMessage(context.Background(), testMsg); status != tt.status {
+				t.Errorf("SaveMessage() error = %v, wantErr %v", status, tt.status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_GetMessage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testMsg := models.Message{
+		MsgID:  uuid.New(),
+		ChatID: uuid.New(),
+		Seq:    7,
+	}
+	// a message queued again under the seq of a flushed one
+	otherMsg := models.Message{
+		MsgID:  uuid.New(),
+		ChatID: testMsg.ChatID,
+		Seq:    7,
+	}
+	bMsg, _ := json.Marshal(&testMsg)
+	bOther, _ := json.Marshal(&otherMsg)
+	key := fmt.Sprintf(models.MessagesKeyFormat, testMsg.ChatID.String())
+	seqsKey := fmt.Sprintf(models.MessageSeqsKeyFormat, testMsg.ChatID.String())
+	bySeq := &redis.ZRangeBy{Min: "7", Max: "7"}
+
+	tests := []struct {
+		name   string
+		pre    func()
+		want   models.Message
+		status models.StatusCode
+	}{
+		{
+			name: "queued",
+			pre: func() {
+				mock.ExpectHGet(seqsKey, testMsg.MsgID.String()).SetVal("7")
+				mock.ExpectZRangeByScore(key, bySeq).SetVal([]string{string(bOther), string(bMsg)})
+			},
+			want:   testMsg,
+			status: models.OK,
+		},
+		{
+			name: "not indexed",
+			pre: func() {
+				mock.ExpectHGet(seqsKey, testMsg.MsgID.String()).RedisNil()
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "indexed but flushed",
+			pre: func() {
+				mock.ExpectHGet(seqsKey, testMsg.MsgID.String()).SetVal("7")
+				mock.ExpectZRangeByScore(key, bySeq).SetVal([]string{string(bOther)})
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectHGet(seqsKey, testMsg.MsgID.String()).SetErr(fmt.Errorf("down"))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl: db,
+			}
+			tt.pre()
+			got, status := r.GetMessage(context.Background(), models.Message{MsgID: testMsg.MsgID, ChatID: testMsg.ChatID})
+			if status != tt.status {
+				t.Errorf("GetMessage() error = %v, wantErr %v", status, tt.status)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMessage() got = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_GetQueuedMessages(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	firstChat := models.Chat{ChatID: uuid.New(), LastMessage: models.Message{Seq: 4}}
+	secondChat := models.Chat{ChatID: uuid.New()}
+	queuedMsg := models.Message{MsgID: uuid.New(), ChatID: firstChat.ChatID, Seq: 5}
+	bMsg, _ := json.Marshal(&queuedMsg)
+
+	tests := []struct {
+		name   string
+		pre    func()
+		want   []models.Messages
+		status models.StatusCode
+	}{
+		{
+			name: "queued after the last flushed message",
+			pre: func() {
+				mock.ExpectZRangeByScore(fmt.Sprintf(models.MessagesKeyFormat, firstChat.ChatID.String()),
+					&redis.ZRangeBy{Min: "(4", Max: "+inf"}).SetVal([]string{string(bMsg)})
+				mock.ExpectZRangeByScore(fmt.Sprintf(models.MessagesKeyFormat, secondChat.ChatID.String()),
+					&redis.ZRangeBy{Min: "(0", Max: "+inf"}).SetVal([]string{})
+			},
+			want:   []models.Messages{{queuedMsg}, {}},
+			status: models.OK,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectZRangeByScore(fmt.Sprintf(models.MessagesKeyFormat, firstChat.ChatID.String()),
+					&redis.ZRangeBy{Min: "(4", Max: "+inf"}).SetErr(fmt.Errorf("down"))
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl: db,
+			}
+			tt.pre()
+			got, status := r.GetQueuedMessages(context.Background(), []models.Chat{firstChat, secondChat})
+			if status != tt.status {
+				t.Errorf("GetQueuedMessages() error = %v, wantErr %v", status, tt.status)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetQueuedMessages() got = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -3,22 +3,46 @@ package repo
 import (
 	"context"
 	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
 	"our-little-chatik/internal/models"
 	"our-little-chatik/internal/pkg/proto/users"
+	"sync"
+	"time"
 )
 
+// profileTTL bounds how long a profile is served from the cache,
+// so that a changed nickname or avatar shows up in the chats shortly.
+const profileTTL = time.Minute
+
+// maxCachedProfiles bounds the number of the cached profiles.
+const maxCachedProfiles = 10000
+
+type cachedProfile struct {
+	user      models.User
+	expiresAt time.Time
+}
+
 type UserDataClient struct {
 	cl users.UsersClient
+
+	mu       sync.Mutex
+	cache    map[uuid.UUID]cachedProfile
+	maxCache int
+	now      func() time.Time
 }
 
 func NewUserDataClient(cl users.UsersClient) *UserDataClient {
 	return &UserDataClient{
-		cl: cl,
+		cl:       cl,
+		cache:    make(map[uuid.UUID]cachedProfile),
+		maxCache: maxCachedProfiles,
+		now:      time.Now,
 	}
 }
 
-func (c UserDataClient) GetUser(user models.User) (models.User, models.StatusCode) {
-	resp, err := c.cl.GetUser(context.Background(),
+func (c *UserDataClient) GetUser(ctx context.Context, user models.User) (models.User, models.StatusCode) {
+	resp, err := c.cl.GetUser(ctx,
 		&users.GetUserRequest{UserID: user.ID.String()})
 	if err != nil {
 		return models.User{}, models.NotFound
@@ -36,3 +60,81 @@ func (c UserDataClient) GetUser(user models.User) (models.User, models.StatusCod
 	}
 	return user, models.OK
 }
+
+// GetUsers returns the current profiles of the users found, the ones which could not be looked up
+// are missing from the result. The profiles are cached for profileTTL, and the rest are asked for
+// in a single call to the users service.
+func (c *UserDataClient) GetUsers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.User, models.StatusCode) {
+	found := make(map[uuid.UUID]models.User, len(ids))
+	missing := make([]string, 0, len(ids))
+	now := c.now()
+	c.mu.Lock()
+	for _, id := range ids {
+		if _, ok := found[id]; ok {
+			continue
+		}
+		cached, ok := c.cache[id]
+		if ok && now.Before(cached.expiresAt) {
+			found[id] = cached.user
+			continue
+		}
+		if ok {
+			delete(c.cache, id)
+		}
+		if !slices.Contains(missing, id.String()) {
+			missing = append(missing, id.String())
+		}
+	}
+	c.mu.Unlock()
+	if len(missing) == 0 {
+		return found, models.OK
+	}
+
+	resp, err := c.cl.GetUsers(ctx, &users.GetUsersRequest{UserIDs: missing})
+	if err != nil {
+		slog.Warn("failed to get users", "users", missing, "err", err.Error())
+		return found, models.OK
+	}
+
+	now = c.now()
+	expiresAt := now.Add(profileTTL)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.makeRoom(now, len(resp.Users))
+	for _, profile := range resp.Users {
+		user := models.User{
+			Name:      profile.Name,
+			Nickname:  profile.Nickname,
+			Surname:   profile.Surname,
+			Avatar:    profile.Avatar,
+			Activated: profile.Activated,
+		}
+		user.ID, err = uuid.Parse(profile.UserID)
+		if err != nil {
+			slog.Warn("failed to parse user id", "user", profile.UserID)
+			continue
+		}
+		found[user.ID] = user
+		c.cache[user.ID] = cachedProfile{user: user, expiresAt: expiresAt}
+	}
+	return found, models.OK
+}
+
+// makeRoom drops the expired profiles once the cache cannot take n more,
+// and then arbitrary ones if it is still full.
+func (c *UserDataClient) makeRoom(now time.Time, n int) {
+	if len(c.cache)+n <= c.maxCache {
+		return
+	}
+	for id, cached := range c.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(c.cache, id)
+		}
+	}
+	for id := range c.cache {
+		if len(c.cache)+n <= c.maxCache {
+			return
+		}
+		delete(c.cache, id)
+	}
+}
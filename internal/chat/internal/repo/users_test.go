@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/proto/users"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUsersClient answers with the profiles it knows and counts the batches and the lookups of every user.
+type fakeUsersClient struct {
+	mu       sync.Mutex
+	profiles map[string]*users.UserResponse
+	calls    map[string]int
+	batches  int
+}
+
+func (f *fakeUsersClient) GetUser(ctx context.Context, in *users.GetUserRequest,
+	opts ...grpc.CallOption) (*users.UserResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[in.UserID]++
+	profile, ok := f.profiles[in.UserID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return profile, nil
+}
+
+func (f *fakeUsersClient) GetUsers(ctx context.Context, in *users.GetUsersRequest,
+	opts ...grpc.CallOption) (*users.UsersResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches++
+	resp := &users.UsersResponse{}
+	for _, id := range in.UserIDs {
+		f.calls[id]++
+		if profile, ok := f.profiles[id]; ok {
+			resp.Users = append(resp.Users, profile)
+		}
+	}
+	return resp, nil
+}
+
+func TestUserDataClient_GetUsers(t *testing.T) {
+	testCtx := context.Background()
+	knownID := uuid.New()
+	unknownID := uuid.New()
+	known := models.User{ID: knownID, Nickname: "nick", Avatar: "avatar.png"}
+
+	type step struct {
+		after time.Duration
+		ids   []uuid.UUID
+	}
+	tests := []struct {
+		name        string
+		steps       []step
+		want        map[uuid.UUID]models.User
+		wantCalls   map[string]int
+		wantBatches int
+	}{
+		{
+			name:        "users are looked up in a single batch",
+			steps:       []step{{ids: []uuid.UUID{knownID, knownID, unknownID}}},
+			want:        map[uuid.UUID]models.User{knownID: known},
+			wantCalls:   map[string]int{knownID.String(): 1, unknownID.String(): 1},
+			wantBatches: 1,
+		},
+		{
+			name: "cached profiles are not looked up again",
+			steps: []step{
+				{ids: []uuid.UUID{knownID}},
+				{after: profileTTL / 2, ids: []uuid.UUID{knownID}},
+			},
+			want:        map[uuid.UUID]models.User{knownID: known},
+			wantCalls:   map[string]int{knownID.String(): 1},
+			wantBatches: 1,
+		},
+		{
+			name: "expired profiles are looked up again",
+			steps: []step{
+				{ids: []uuid.UUID{knownID}},
+				{after: profileTTL, ids: []uuid.UUID{knownID}},
+			},
+			want:        map[uuid.UUID]models.User{knownID: known},
+			wantCalls:   map[string]int{knownID.String(): 2},
+			wantBatches: 2,
+		},
+		{
+			name: "users not found are not cached",
+			steps: []step{
+				{ids: []uuid.UUID{unknownID}},
+				{ids: []uuid.UUID{unknownID}},
+			},
+			want:        map[uuid.UUID]models.User{},
+			wantCalls:   map[string]int{unknownID.String(): 2},
+			wantBatches: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl := &fakeUsersClient{
+				profiles: map[string]*users.UserResponse{
+					knownID.String(): {UserID: knownID.String(), Nickname: known.Nickname, Avatar: known.Avatar},
+				},
+				calls: make(map[string]int),
+			}
+			c := NewUserDataClient(cl)
+			now := time.Now()
+			c.now = func() time.Time { return now }
+
+			var got map[uuid.UUID]models.User
+			for _, s := range tt.steps {
+				now = now.Add(s.after)
+				var status models.StatusCode
+				got, status = c.GetUsers(testCtx, s.ids)
+				if status != models.OK {
+					t.Fatalf("GetUsers() status = %v", status)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUsers() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(cl.calls, tt.wantCalls) {
+				t.Errorf("GetUsers() calls = %v, want %v", cl.calls, tt.wantCalls)
+			}
+			if cl.batches != tt.wantBatches {
+				t.Errorf("GetUsers() batches = %v, want %v", cl.batches, tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestUserDataClient_GetUsers_CacheBound(t *testing.T) {
+	testCtx := context.Background()
+	cl := &fakeUsersClient{
+		profiles: make(map[string]*users.UserResponse),
+		calls:    make(map[string]int),
+	}
+	ids := make([]uuid.UUID, 0, 5)
+	for i := 0; i < 5; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		cl.profiles[id.String()] = &users.UserResponse{UserID: id.String()}
+	}
+	c := NewUserDataClient(cl)
+	c.maxCache = 3
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for _, id := range ids[:3] {
+		c.GetUsers(testCtx, []uuid.UUID{id})
+	}
+	if len(c.cache) != 3 {
+		t.Fatalf("cached = %d, want 3", len(c.cache))
+	}
+	// the cache is full of live profiles, so some of them make room for the new ones
+	c.GetUsers(testCtx, ids[3:])
+	if len(c.cache) != 3 {
+		t.Errorf("cached = %d, want 3", len(c.cache))
+	}
+	for _, id := range ids[3:] {
+		if _, ok := c.cache[id]; !ok {
+			t.Errorf("profile %v is not cached", id)
+		}
+	}
+
+	// the expired profiles are dropped first
+	now = now.Add(profileTTL)
+	var evicted uuid.UUID
+	for _, id := range ids[:3] {
+		if _, ok := c.cache[id]; !ok {
+			evicted = id
+			break
+		}
+	}
+	c.GetUsers(testCtx, []uuid.UUID{evicted})
+	if len(c.cache) != 1 {
+		t.Errorf("cached = %d, want 1", len(c.cache))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"our-little-chatik/internal/chat/internal"
 	models2 "our-little-chatik/internal/chat/internal/models"
 	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/seq"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,122 +15,490 @@ import (
 )
 
 type ChatUseCase struct {
-	repo  internal.ChatRepo
-	queue internal.QueueRepo
-	users internal.UserDataInteractor
+	repo   internal.ChatRepo
+	queue  internal.QueueRepo
+	users  internal.UserDataInteractor
+	events internal.EventBus
 }
 
 func NewChatUseCase(rep internal.ChatRepo, queue internal.QueueRepo,
-	usersConnector internal.UserDataInteractor) *ChatUseCase {
-	return &ChatUseCase{repo: rep, queue: queue, users: usersConnector}
+	usersConnector internal.UserDataInteractor, events internal.EventBus) *ChatUseCase {
+	return &ChatUseCase{repo: rep, queue: queue, users: usersConnector, events: events}
 }
 
-func (ch *ChatUseCase) GetChatMessages(ctx context.Context, chat models.Chat,
+// GetChatMessages returns the window of the chat history. The newest messages are in the queue,
+// the older ones are in the repo, and the ones being flushed may be in both, so both storages
+// are asked for the whole window and the results are merged.
+func (ch *ChatUseCase) GetChatMessages(ctx context.Context, chat models.Chat, user models.User,
 	opts models.Opts) (models.Messages, models.StatusCode) {
+	// the membership is checked before either store is read, the queue included
+	status := ch.checkMember(ctx, chat, user)
+	if status != models.OK {
+		return nil, status
+	}
+	opts.Before, status = ch.resolveCursor(ctx, chat, opts.Before)
+	if status != models.OK {
+		return nil, status
+	}
+	opts.After, status = ch.resolveCursor(ctx, chat, opts.After)
+	if status != models.OK {
+		return nil, status
+	}
+
 	msgs, status := ch.queue.GetChatMessages(chat, opts)
 	if status != models.OK {
-		slog.Error("failed to fetch messages from queue %d", status)
+		slog.Error("failed to fetch messages from queue", "status", status)
 	}
-	if len(msgs) < int(opts.Limit) {
-		opts.Limit = opts.Limit - int64(len(msgs))
-		oldMsgs, status := ch.repo.GetChatMessages(ctx, chat, opts)
-		if status != models.OK {
-			slog.Error("failed to fetch messages from repo %d", status)
-		}
-		msgs = append(msgs, oldMsgs...)
+	oldMsgs, status := ch.repo.GetChatMessages(ctx, chat, opts)
+	if status != models.OK {
+		slog.Error("failed to fetch messages from repo", "status", status)
+		return nil, status
 	}
-	return msgs, models.OK
+	return append(msgs, oldMsgs...).Window(opts), models.OK
+}
+
+// resolveCursor finds the seq of the message the cursor points to by its id.
+func (ch *ChatUseCase) resolveCursor(ctx context.Context, chat models.Chat,
+	cursor models.Cursor) (models.Cursor, models.StatusCode) {
+	if cursor.Seq != 0 || cursor.MsgID == uuid.Nil {
+		return cursor, models.OK
+	}
+	msg, status := ch.getMessage(ctx, chat, models.Message{MsgID: cursor.MsgID})
+	if status != models.OK {
+		return models.Cursor{}, status
+	}
+	return models.Cursor{Seq: msg.Seq, MsgID: msg.MsgID}, models.OK
 }
 
 func (ch *ChatUseCase) GetChatList(ctx context.Context, user models.User) ([]models.Chat, models.StatusCode) {
-	return ch.repo.FetchChatList(ctx, user)
+	chatList, status := ch.repo.FetchChatList(ctx, user)
+	if status != models.OK {
+		return nil, status
+	}
+	ch.titleDirectChats(ctx, user, chatList)
+	// The repo knows only the flushed messages, the rest are still in the queue.
+	ch.overlayQueuedMessages(ctx, user, chatList)
+	return chatList, models.OK
+}
+
+// titleDirectChats names the direct chats after the current profile of the other participant
+// and shows its avatar. The profiles are looked up at once; the name stored when the chat
+// was created is kept if the profile cannot be found, and the name the user has chosen is always kept.
+func (ch *ChatUseCase) titleDirectChats(ctx context.Context, user models.User, chats []models.Chat) {
+	peers := make(map[int]uuid.UUID)
+	ids := make([]uuid.UUID, 0)
+	for i, chat := range chats {
+		if chat.Kind != models.DirectChat {
+			continue
+		}
+		for _, participant := range chat.Participants {
+			if participant != user.ID {
+				peers[i] = participant
+				ids = append(ids, participant)
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	profiles, status := ch.users.GetUsers(ctx, ids)
+	if status != models.OK {
+		slog.Error("failed to get profiles of direct chats", "status", status)
+		return
+	}
+	for i, peer := range peers {
+		profile, ok := profiles[peer]
+		if !ok {
+			continue
+		}
+		if profile.Nickname != "" && !chats[i].CustomName {
+			chats[i].Name = profile.Nickname
+		}
+		if profile.Avatar != "" {
+			chats[i].PhotoURL = profile.Avatar
+		}
+	}
+}
+
+// overlayQueuedMessages updates the last message, the messages count and the unread count
+// of the chats with the queued messages, which are read for all the chats at once.
+func (ch *ChatUseCase) overlayQueuedMessages(ctx context.Context, user models.User, chats []models.Chat) {
+	if len(chats) == 0 {
+		return
+	}
+	queued, status := ch.queue.GetQueuedMessages(ctx, chats)
+	if status != models.OK {
+		slog.Error("failed to get queued messages", "status", status)
+		return
+	}
+	// the repo has counted the flushed messages, the queued ones are all newer
+	for i := range chats {
+		chat := &chats[i]
+		for _, msg := range queued[i] {
+			if msg.Seq > chat.LastMessage.Seq {
+				chat.LastMessage = msg
+			}
+			chat.MessagesCount++
+			if msg.Seq > chat.LastReadSeq && msg.SenderID != user.ID {
+				chat.UnreadCount++
+			}
+		}
+	}
 }
 
 const defaultPhotoURL = "default.png"
 
+// groupChatName is the name the participants see the group chat under, unless they rename it.
+func groupChatName(chat models.Chat) string {
+	if chat.Name != "" {
+		return chat.Name
+	}
+	return "Group chat " + chat.ChatID.String()
+}
+
+// CreateChat creates the chat of the kind asked for. The users have a single personal chat,
+// so if they have one already it is returned along with Conflict.
 func (ch *ChatUseCase) CreateChat(ctx context.Context, request models2.CreateChatRequest) (models.Chat, models.StatusCode) {
 	chat := models.Chat{
-		ChatID:       uuid.New(),
-		CreatedAt:    time.Now().Unix(),
-		Participants: request.Participants,
-		PhotoURL:     *request.PhotoURL,
-		Name:         *request.Name,
+		ChatID:    uuid.New(),
+		CreatedAt: time.Now().Unix(),
+		PhotoURL:  defaultPhotoURL,
+	}
+	if request.PhotoURL != nil {
+		chat.PhotoURL = *request.PhotoURL
+	}
+	if request.Name != nil {
+		chat.Name = *request.Name
 	}
 
-	includeSelf := true
+	chat.Participants = make([]uuid.UUID, 0, len(request.Participants)+1)
+	chat.Participants = append(chat.Participants, request.IssuerID)
 	for _, participant := range request.Participants {
 		if participant == uuid.Nil {
-			err := fmt.Errorf("added unexisting participant")
-			slog.Error(err.Error())
-			if err != nil {
-				return models.Chat{}, models.BadRequest
-			}
+			slog.Error("added unexisting participant")
+			return models.Chat{}, models.BadRequest
 		}
-		if participant == request.IssuerID {
-			includeSelf = false
+		if !slices.Contains(chat.Participants, participant) {
+			chat.Participants = append(chat.Participants, participant)
 		}
 	}
-	if includeSelf {
-		slog.Info("Adding a participant "+request.IssuerID.String()+" in list", "list",
-			request.Participants)
-		chat.Participants = append(request.Participants, request.IssuerID)
+
+	chat.Kind = chatKind(request.Kind, len(chat.Participants))
+	switch {
+	case chat.Kind == models.DirectChat && len(chat.Participants) != 2,
+		chat.Kind == models.SavedMessagesChat && len(chat.Participants) != 1:
+		return models.Chat{}, models.BadRequest
+	}
+	if chat.Kind.IsPersonal() {
+		existing, status := ch.repo.GetPersonalChat(ctx, chat, models.User{ID: request.IssuerID})
+		switch status {
+		case models.OK:
+			return existing, models.Conflict
+		case models.NotFound:
+		default:
+			return models.Chat{}, status
+		}
+	}
+
+	// The creator owns the chat, the rest of the participants are plain members.
+	chat.Members = make([]models.ChatMember, 0, len(chat.Participants))
+	for _, participant := range chat.Participants {
+		role := models.MemberRole
+		if participant == request.IssuerID {
+			role = models.OwnerRole
+		}
+		chat.Members = append(chat.Members, models.ChatMember{UserID: participant, Role: role})
 	}
 
 	chatName := make(map[string]string)
-	if len(chat.Participants) == 2 {
+	switch chat.Kind {
+	case models.DirectChat:
 		for i := range chat.Participants {
-			user, status := ch.users.GetUser(models.User{
-				ID: chat.Participants[(i+1)%2],
-			})
-			if status != models.OK {
-				chatName[chat.Participants[i].String()] = chat.ChatID.String()
-			} else {
-				chatName[chat.Participants[i].String()] = user.Nickname
-			}
+			chatName[chat.Participants[i].String()] = ch.nicknameOr(ctx, chat.Participants[(i+1)%2], chat.ChatID.String())
 		}
-	} else if len(chat.Participants) == 1 {
-		user, status := ch.users.GetUser(models.User{
-			ID: chat.Participants[0],
-		})
-		if status != models.OK {
-			chatName[chat.Participants[0].String()] = chat.ChatID.String()
-		} else {
-			chatName[chat.Participants[0].String()] = user.Nickname
-		}
-	} else {
+	case models.SavedMessagesChat:
+		chatName[request.IssuerID.String()] = ch.nicknameOr(ctx, request.IssuerID, chat.ChatID.String())
+	default:
+		name := groupChatName(chat)
 		for _, participant := range chat.Participants {
-			chatName[participant.String()] = "Group chat " + chat.ChatID.String()
+			chatName[participant.String()] = name
 		}
 	}
-	if request.PhotoURL == nil {
-		chat.PhotoURL = defaultPhotoURL
-	}
+
 	status := ch.repo.CreateChat(ctx, chat, chatName)
+	if status == models.Conflict && chat.Kind.IsPersonal() {
+		// the chat has been created by the other participant meanwhile
+		existing, status := ch.repo.GetPersonalChat(ctx, chat, models.User{ID: request.IssuerID})
+		if status != models.OK {
+			return models.Chat{}, status
+		}
+		return existing, models.Conflict
+	}
 	if status != models.OK {
 		return models.Chat{}, status
 	}
+	ch.postSystemMessage(ctx, chat, models.User{ID: request.IssuerID}, models.ChatCreatedMessage,
+		models.SystemMessageBody{Name: chat.Name, Users: chat.Participants})
 	return chat, models.OK
 }
 
+// chatKind returns the kind asked for, if none is, the kind is told by the number of the participants.
+func chatKind(kind *models.ChatKind, participants int) models.ChatKind {
+	switch {
+	case kind != nil:
+		return *kind
+	case participants == 1:
+		return models.SavedMessagesChat
+	case participants == 2:
+		return models.DirectChat
+	default:
+		return models.GroupChat
+	}
+}
+
+// nicknameOr returns the nickname of the user, or the fallback if the user cannot be found.
+func (ch *ChatUseCase) nicknameOr(ctx context.Context, userID uuid.UUID, fallback string) string {
+	user, status := ch.users.GetUser(ctx, models.User{ID: userID})
+	if status != models.OK {
+		return fallback
+	}
+	return user.Nickname
+}
+
+// issuerRole returns the role of the user changing the chat, Forbidden if the user does not participate in it.
+func (ch *ChatUseCase) issuerRole(ctx context.Context, chat models.Chat,
+	issuer models.User) (models.ChatRole, models.StatusCode) {
+	role, status := ch.repo.GetParticipantRole(ctx, chat, issuer)
+	if status == models.NotFound {
+		return "", models.Forbidden
+	}
+	return role, status
+}
+
+// RemoveUserFromChat removes the participants the issuer outranks: the admins may remove
+// the members and the owner may remove anyone but themselves.
 func (ch *ChatUseCase) RemoveUserFromChat(ctx context.Context,
-	chat models.Chat, users ...models.User) models.StatusCode {
-	return ch.repo.RemoveUserFromChat(ctx, chat, users...)
+	chat models.Chat, issuer models.User, users ...models.User) models.StatusCode {
+	role, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if !role.CanManage() {
+		return models.Forbidden
+	}
+
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if chatFullInfo.Kind.IsPersonal() {
+		return models.Forbidden
+	}
+	for _, user := range users {
+		if !slices.Contains(chatFullInfo.Participants, user.ID) {
+			return models.NotFound
+		}
+		if !role.Outranks(chatFullInfo.RoleOf(user.ID)) {
+			return models.Forbidden
+		}
+	}
+
+	status = ch.repo.RemoveUserFromChat(ctx, chat, users...)
+	if status != models.OK {
+		return status
+	}
+
+	removed := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		removed = append(removed, user.ID)
+	}
+	ch.publishChatUpdate(ctx, chat, models.ChatUpdateEvent{
+		Action: models.MembersRemovedAction,
+		UserID: issuer.ID,
+		Users:  removed,
+	})
+	// the removed users are still subscribed to the chat, so they see why they are kicked
+	ch.postSystemMessage(ctx, chat, issuer, models.UsersRemovedMessage, models.SystemMessageBody{Users: removed})
+	ch.publishUsersRemoved(ctx, chat, removed)
+	return models.OK
+}
+
+// LeaveChat removes the user from the chat. The owner has to transfer the ownership first,
+// unless no one else is left in the chat, then the chat is deleted. A direct chat cannot be left,
+// as it would not let its participants have another one, it is deleted instead.
+func (ch *ChatUseCase) LeaveChat(ctx context.Context, chat models.Chat, user models.User) models.StatusCode {
+	role, status := ch.repo.GetParticipantRole(ctx, chat, user)
+	if status != models.OK {
+		return status
+	}
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, user)
+	if status != models.OK {
+		return status
+	}
+	if chatFullInfo.Kind == models.DirectChat {
+		return models.Forbidden
+	}
+	if role == models.OwnerRole {
+		if len(chatFullInfo.Participants) > 1 {
+			return models.Conflict
+		}
+		return ch.deleteChat(ctx, chatFullInfo, user)
+	}
+
+	status = ch.repo.RemoveUserFromChat(ctx, chat, user)
+	if status != models.OK {
+		return status
+	}
+	ch.publishChatUpdate(ctx, chat, models.ChatUpdateEvent{
+		Action: models.ChatLeftAction,
+		UserID: user.ID,
+	})
+	// the members of a channel do not post to it, so they leave it quietly
+	if chatFullInfo.Kind.CanPost(role) {
+		ch.postSystemMessage(ctx, chat, user, models.UsersRemovedMessage,
+			models.SystemMessageBody{Users: []uuid.UUID{user.ID}})
+	}
+	ch.publishUsersRemoved(ctx, chat, []uuid.UUID{user.ID})
+	return models.OK
+}
+
+// RenameChat renames the chat. A group is renamed for everyone by its admins and the owner,
+// a personal chat is renamed only for the issuer, as its name is personal.
+func (ch *ChatUseCase) RenameChat(ctx context.Context, chat models.Chat,
+	issuer models.User, name string) models.StatusCode {
+	role, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+
+	if chatFullInfo.Kind.IsPersonal() {
+		status = ch.repo.UpdateChatName(ctx, chat, name, issuer)
+		if status != models.OK {
+			return status
+		}
+		// the other participant must not see the name, so only the sessions of the issuer are told
+		event := models.ChatEvent{
+			Type:   models.ChatRenamedForUsers,
+			ChatID: chat.ChatID,
+			Users:  []uuid.UUID{issuer.ID},
+			Name:   name,
+		}
+		if status := ch.events.PublishChatEvent(ctx, event); status != models.OK {
+			slog.Error("failed to publish chat event", "chat", chat.ChatID.String(), "status", status)
+		}
+		return models.OK
+	}
+
+	if !role.CanManage() {
+		return models.Forbidden
+	}
+	status = ch.repo.UpdateChatName(ctx, chat, name)
+	if status != models.OK {
+		return status
+	}
+	ch.publishChatUpdate(ctx, chat, models.ChatUpdateEvent{
+		Action: models.ChatRenamedAction,
+		UserID: issuer.ID,
+		Name:   name,
+	})
+	return models.OK
+}
+
+// publishUsersRemoved tells peer service the users must lose their live sessions of the chat.
+func (ch *ChatUseCase) publishUsersRemoved(ctx context.Context, chat models.Chat, users []uuid.UUID) {
+	event := models.ChatEvent{
+		Type:   models.UsersRemovedFromChat,
+		ChatID: chat.ChatID,
+		Users:  users,
+	}
+	if status := ch.events.PublishChatEvent(ctx, event); status != models.OK {
+		slog.Error("failed to publish chat event", "chat", chat.ChatID.String(), "status", status)
+	}
+}
+
+// publishChatUpdate tells the participants connected to the chat about the change of it.
+func (ch *ChatUseCase) publishChatUpdate(ctx context.Context, chat models.Chat, event models.ChatUpdateEvent) {
+	event.ChatID = chat.ChatID
+	notification := models.Notification{
+		Type: models.UpdateMessage,
+		Body: &event,
+	}
+	if status := ch.events.PublishChatNotification(ctx, chat, notification); status != models.OK {
+		slog.Error("failed to publish chat update", "chat", chat.ChatID.String(), "status", status)
+	}
+}
+
+// postSystemMessage queues the system message about the change the issuer has made to the chat,
+// so that it is flushed along with the messages of the users, and broadcasts it to the chat.
+// The change is made already, so a failure is only logged.
+func (ch *ChatUseCase) postSystemMessage(ctx context.Context, chat models.Chat, issuer models.User,
+	kind models.MessageKind, body models.SystemMessageBody) {
+	msg, err := models.NewSystemMessage(chat, issuer, kind, body)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	msgSeq, status := ch.nextSeq(ctx, chat)
+	if status != models.OK {
+		slog.Error("failed to assign system message seq", "chat", chat.ChatID.String(), "status", status)
+		return
+	}
+	msg.Seq = msgSeq
+	if status := ch.queue.SaveMessage(ctx, msg); status != models.OK {
+		slog.Error("failed to save system message", "chat", chat.ChatID.String(), "status", status)
+		return
+	}
+	notification := models.Notification{
+		Type: models.ChatMessage,
+		Body: &msg,
+	}
+	if status := ch.events.PublishChatNotification(ctx, chat, notification); status != models.OK {
+		slog.Error("failed to publish system message", "chat", chat.ChatID.String(), "status", status)
+	}
+}
+
+// nextSeq assigns the sequence number to a new message of the chat from the counter shared with the peer service.
+func (ch *ChatUseCase) nextSeq(ctx context.Context, chat models.Chat) (int64, models.StatusCode) {
+	msgSeq, err := seq.Next(ctx, ch.queue, chat.ChatID, func(ctx context.Context, chatID uuid.UUID) (int64, error) {
+		lastSeq, status := ch.repo.GetLastSeq(ctx, models.Chat{ChatID: chatID})
+		if status != models.OK {
+			return 0, fmt.Errorf("failed to get the last seq of chat %s: status %v", chatID, status)
+		}
+		return lastSeq, nil
+	})
+	if err != nil {
+		slog.Error(err.Error())
+		return 0, models.InternalError
+	}
+	return msgSeq, models.OK
 }
 
+// AddUsersToChat adds the users as members, only the admins and the owner may add them.
 func (ch *ChatUseCase) AddUsersToChat(ctx context.Context,
-	chat models.Chat, users ...models.User) models.StatusCode {
+	chat models.Chat, issuer models.User, users ...models.User) models.StatusCode {
 	if len(users) == 0 {
 		return models.BadRequest
 	}
 
-	chatFullInfo, status := ch.repo.GetChat(ctx, chat)
+	role, status := ch.issuerRole(ctx, chat, issuer)
 	if status != models.OK {
 		return status
 	}
+	if !role.CanManage() {
+		return models.Forbidden
+	}
 
-	if len(chatFullInfo.Participants) == 1 {
-		err := fmt.Errorf("it is not allowed to add users to the chat")
-		slog.Error(err.Error())
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+
+	if chatFullInfo.Kind.IsPersonal() {
 		return models.Forbidden
 	}
 
@@ -141,29 +510,307 @@ func (ch *ChatUseCase) AddUsersToChat(ctx context.Context,
 	}
 
 	chatNames := make(map[string]string)
+	name := groupChatName(models.Chat{ChatID: chat.ChatID, Name: chatFullInfo.Name})
 	for _, user := range usersToAdd {
-		if chatFullInfo.Name != "" {
-			chatNames[user.ID.String()] = chatFullInfo.Name
-		} else {
-			chatNames[user.ID.String()] = "group chat " + chat.ChatID.String()[len(chat.ChatID.String())-5:]
-		}
+		chatNames[user.ID.String()] = name
 	}
-	return ch.repo.AddUsersToChat(ctx, chat, chatNames, usersToAdd...)
+	status = ch.repo.AddUsersToChat(ctx, chat, chatNames, usersToAdd...)
+	if status != models.OK || len(usersToAdd) == 0 {
+		return status
+	}
+
+	added := make([]uuid.UUID, 0, len(usersToAdd))
+	for _, user := range usersToAdd {
+		added = append(added, user.ID)
+	}
+	ch.postSystemMessage(ctx, chat, issuer, models.UsersAddedMessage, models.SystemMessageBody{Users: added})
+	return models.OK
 }
 
+// UpdateChatPhotoURL changes the photo of the chat, only the admins and the owner may change it.
+// A direct chat shows the photo of the other participant, so it has none of its own.
 func (ch *ChatUseCase) UpdateChatPhotoURL(ctx context.Context, chat models.Chat,
-	photoURL string) models.StatusCode {
-	return ch.repo.UpdateChatPhotoURL(ctx, chat, photoURL)
+	issuer models.User, photoURL string) models.StatusCode {
+	role, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if !role.CanManage() {
+		return models.Forbidden
+	}
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if chatFullInfo.Kind == models.DirectChat {
+		return models.Forbidden
+	}
+	status = ch.repo.UpdateChatPhotoURL(ctx, chat, photoURL)
+	if status != models.OK {
+		return status
+	}
+	ch.postSystemMessage(ctx, chat, issuer, models.PhotoChangedMessage, models.SystemMessageBody{PhotoURL: photoURL})
+	return models.OK
 }
 
-func (ch *ChatUseCase) GetChat(ctx context.Context, chat models.Chat) (models.Chat, models.StatusCode) {
-	return ch.repo.GetChat(ctx, chat)
+// GetChat returns the chat to its member.
+func (ch *ChatUseCase) GetChat(ctx context.Context, chat models.Chat, user models.User) (models.Chat, models.StatusCode) {
+	if status := ch.checkMember(ctx, chat, user); status != models.OK {
+		return models.Chat{}, status
+	}
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, user)
+	if status != models.OK {
+		return models.Chat{}, status
+	}
+	chats := []models.Chat{chatFullInfo}
+	ch.titleDirectChats(ctx, user, chats)
+	return chats[0], models.OK
+}
+
+// checkMember returns NotFound if the user does not participate in the chat,
+// so that the users outside of the chat can't tell whether it exists.
+func (ch *ChatUseCase) checkMember(ctx context.Context, chat models.Chat, user models.User) models.StatusCode {
+	isMember, status := ch.repo.IsChatParticipant(ctx, chat, user)
+	if status != models.OK {
+		return status
+	}
+	if !isMember {
+		return models.NotFound
+	}
+	return models.OK
+}
+
+// DeleteChat deletes the chat along with its messages, only the owner may delete it,
+// while a personal chat may be deleted by any of its participants.
+func (ch *ChatUseCase) DeleteChat(ctx context.Context, chat models.Chat, issuer models.User) models.StatusCode {
+	role, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	chatFullInfo, status := ch.repo.GetChat(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if role != models.OwnerRole && !chatFullInfo.Kind.IsPersonal() {
+		return models.Forbidden
+	}
+	return ch.deleteChat(ctx, chatFullInfo, issuer)
+}
+
+// deleteChat deletes the chat and closes the live sessions of its participants.
+func (ch *ChatUseCase) deleteChat(ctx context.Context, chat models.Chat, issuer models.User) models.StatusCode {
+	status := ch.repo.DeleteChat(ctx, chat)
+	if status != models.Deleted {
+		return status
+	}
+	// the queued messages would be rejected by the flusher, as the chat is gone
+	if status := ch.queue.DeleteChatMessages(ctx, chat); status != models.OK {
+		slog.Error("failed to drop queued messages", "chat", chat.ChatID.String(), "status", status)
+	}
+	ch.publishChatUpdate(ctx, chat, models.ChatUpdateEvent{
+		Action: models.ChatDeletedAction,
+		UserID: issuer.ID,
+	})
+	ch.publishUsersRemoved(ctx, chat, chat.Participants)
+	return models.Deleted
+}
+
+// PromoteParticipant makes the member an admin, only the owner may promote the members.
+func (ch *ChatUseCase) PromoteParticipant(ctx context.Context, chat models.Chat,
+	issuer models.User, user models.User) models.StatusCode {
+	return ch.changeRole(ctx, chat, issuer, user, models.MemberRole, models.AdminRole)
 }
 
-func (ch *ChatUseCase) DeleteChat(ctx context.Context, chat models.Chat) models.StatusCode {
-	return ch.repo.DeleteChat(ctx, chat)
+// DemoteParticipant makes the admin a plain member, only the owner may demote the admins.
+func (ch *ChatUseCase) DemoteParticipant(ctx context.Context, chat models.Chat,
+	issuer models.User, user models.User) models.StatusCode {
+	return ch.changeRole(ctx, chat, issuer, user, models.AdminRole, models.MemberRole)
+}
+
+// changeRole moves the participant having the from role to the to role. The participant
+// already having the to role is left as is, the owner's role is changed only by a transfer.
+func (ch *ChatUseCase) changeRole(ctx context.Context, chat models.Chat,
+	issuer models.User, user models.User, from models.ChatRole, to models.ChatRole) models.StatusCode {
+	issuerRole, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if issuerRole != models.OwnerRole {
+		return models.Forbidden
+	}
+
+	role, status := ch.repo.GetParticipantRole(ctx, chat, user)
+	if status != models.OK {
+		return status
+	}
+	switch role {
+	case to:
+		return models.OK
+	case from:
+	default:
+		return models.BadRequest
+	}
+
+	status = ch.repo.UpdateParticipantRole(ctx, chat, user, to)
+	if status != models.OK {
+		return status
+	}
+	ch.publishRoleChange(ctx, chat, issuer, user, to)
+	return models.OK
+}
+
+// TransferOwnership makes the participant the owner of the chat and the former owner an admin.
+func (ch *ChatUseCase) TransferOwnership(ctx context.Context, chat models.Chat,
+	issuer models.User, user models.User) models.StatusCode {
+	if user.ID == issuer.ID {
+		return models.BadRequest
+	}
+	role, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	if role != models.OwnerRole {
+		return models.Forbidden
+	}
+
+	status = ch.repo.TransferOwnership(ctx, chat, issuer, user)
+	if status != models.OK {
+		return status
+	}
+	ch.publishRoleChange(ctx, chat, issuer, user, models.OwnerRole)
+	ch.publishRoleChange(ctx, chat, issuer, issuer, models.AdminRole)
+	return models.OK
+}
+
+// publishRoleChange tells the participants connected to the chat about the new role of the user.
+func (ch *ChatUseCase) publishRoleChange(ctx context.Context, chat models.Chat,
+	issuer models.User, user models.User, role models.ChatRole) {
+	notification := models.Notification{
+		Type: models.RoleMessage,
+		Body: &models.RoleEvent{
+			ChatID:    chat.ChatID,
+			UserID:    user.ID,
+			Role:      role,
+			ChangedBy: issuer.ID,
+		},
+	}
+	if status := ch.events.PublishChatNotification(ctx, chat, notification); status != models.OK {
+		slog.Error("failed to publish role change", "chat", chat.ChatID.String(), "status", status)
+	}
+}
+
+// DeleteMessage deletes the message of the chat, the sender may delete their messages
+// and the admins and the owner may delete any message.
+func (ch *ChatUseCase) DeleteMessage(ctx context.Context, chat models.Chat,
+	issuer models.User, message models.Message) models.StatusCode {
+	role, status := ch.issuerRole(ctx, chat, issuer)
+	if status != models.OK {
+		return status
+	}
+	msg, status := ch.getMessage(ctx, chat, message)
+	if status != models.OK {
+		return status
+	}
+	if msg.SenderID != issuer.ID && !role.CanManage() {
+		return models.Forbidden
+	}
+
+	// the message may be both flushed and still queued, it is deleted from both stores
+	if status := ch.queue.DeleteMessage(ctx, msg); status != models.OK {
+		return status
+	}
+	status = ch.repo.DeleteMessage(ctx, msg)
+	if status != models.Deleted {
+		return status
+	}
+	ch.publishChatUpdate(ctx, chat, models.ChatUpdateEvent{
+		Action: models.MessageDeletedAction,
+		UserID: issuer.ID,
+		MsgID:  &msg.MsgID,
+	})
+	return models.Deleted
+}
+
+// GetMembership returns the kind of the chat along with the role of the user in it,
+// NotFound if the user does not participate in the chat.
+func (ch *ChatUseCase) GetMembership(ctx context.Context, chat models.Chat,
+	user models.User) (models.ChatKind, models.ChatRole, models.StatusCode) {
+	return ch.repo.GetMembership(ctx, chat, user)
+}
+
+// GetUserChatIDs returns the ids of the chats the user participates in, without building the chat list.
+func (ch *ChatUseCase) GetUserChatIDs(ctx context.Context, user models.User) ([]uuid.UUID, models.StatusCode) {
+	return ch.repo.GetUserChatIDs(ctx, user)
+}
+
+// getMessage looks the message up in the queue first, as the recent messages are read most often.
+func (ch *ChatUseCase) getMessage(ctx context.Context, chat models.Chat,
+	message models.Message) (models.Message, models.StatusCode) {
+	message.ChatID = chat.ChatID
+	msg, status := ch.queue.GetMessage(ctx, message)
+	if status == models.NotFound {
+		msg, status = ch.repo.GetMessage(ctx, message)
+	}
+	if status != models.OK {
+		return models.Message{}, status
+	}
+	if msg.ChatID != chat.ChatID {
+		return models.Message{}, models.NotFound
+	}
+	msg.MsgID = message.MsgID
+	return msg, models.OK
+}
+
+// MarkRead moves the read cursor of the user to the message and tells the participants about it.
+func (ch *ChatUseCase) MarkRead(ctx context.Context, chat models.Chat,
+	user models.User, message models.Message) models.StatusCode {
+	if status := ch.checkMember(ctx, chat, user); status != models.OK {
+		return status
+	}
+
+	msg, status := ch.getMessage(ctx, chat, message)
+	if status != models.OK {
+		return status
+	}
+
+	advanced, status := ch.repo.UpdateLastRead(ctx, chat, user, msg)
+	if status != models.OK {
+		return status
+	}
+	if !advanced {
+		return models.OK
+	}
+
+	notification := models.Notification{
+		Type: models.ReadMessage,
+		Body: &models.ReadEvent{
+			ChatID: chat.ChatID,
+			UserID: user.ID,
+			MsgID:  msg.MsgID,
+			Seq:    msg.Seq,
+			ReadAt: time.Now().Unix(),
+		},
+	}
+	if status := ch.events.PublishChatNotification(ctx, chat, notification); status != models.OK {
+		slog.Error("failed to publish read event", "chat", chat.ChatID.String(), "status", status)
+	}
+	return models.OK
+}
+
+// GetSeenBy returns the participants who have read the message.
+func (ch *ChatUseCase) GetSeenBy(ctx context.Context, chat models.Chat,
+	user models.User, message models.Message) ([]models.ReadReceipt, models.StatusCode) {
+	if status := ch.checkMember(ctx, chat, user); status != models.OK {
+		return nil, status
+	}
+
+	msg, status := ch.getMessage(ctx, chat, message)
+	if status != models.OK {
+		return nil, status
+	}
+	return ch.repo.GetSeenBy(ctx, chat, msg)
 }
 
-func (ch *ChatUseCase) DeleteMessage(ctx context.Context, message models.Message) models.StatusCode {
-	return ch.repo.DeleteMessage(ctx, message)
+func (ch *ChatUseCase) GetLastSeq(ctx context.Context, chat models.Chat) (int64, models.StatusCode) {
+	return ch.repo.GetLastSeq(ctx, chat)
 }
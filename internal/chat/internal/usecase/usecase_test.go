@@ -2,25 +2,30 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"go.uber.org/mock/gomock"
 	"our-little-chatik/internal/chat/internal/mocks/chat"
 	models2 "our-little-chatik/internal/chat/internal/models"
 	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/seq"
 	"reflect"
 	"testing"
 )
 
 func TestChatUseCase_AddUsersToChat(t *testing.T) {
 	type fields struct {
-		repo  *chat.MockChatRepo
-		queue *chat.MockQueueRepo
-		users *chat.MockUserDataInteractor
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		users  *chat.MockUserDataInteractor
+		events *chat.MockEventBus
 	}
 	type args struct {
-		ctx   context.Context
-		chat  models.Chat
-		users []models.User
+		ctx    context.Context
+		chat   models.Chat
+		issuer models.User
+		users  []models.User
 	}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -35,10 +40,12 @@ func TestChatUseCase_AddUsersToChat(t *testing.T) {
 		Nickname: "test3",
 		Surname:  "test3",
 	}
+	testIssuer := models.User{ID: testUserID1}
 	testCtx := context.Background()
 
 	testChat := models.Chat{
 		ChatID: uuid.New(),
+		Kind:   models.GroupChat,
 		Participants: []uuid.UUID{
 			testUserID1,
 			testUserID2,
@@ -47,6 +54,7 @@ func TestChatUseCase_AddUsersToChat(t *testing.T) {
 
 	testNamedChat := models.Chat{
 		ChatID: uuid.New(),
+		Kind:   models.GroupChat,
 		Participants: []uuid.UUID{
 			testUserID1,
 			testUserID2,
@@ -54,6 +62,12 @@ func TestChatUseCase_AddUsersToChat(t *testing.T) {
 		Name: "chat",
 	}
 
+	testDirectChat := models.Chat{
+		ChatID:       uuid.New(),
+		Kind:         models.DirectChat,
+		Participants: []uuid.UUID{testUserID1, testUserID2},
+	}
+
 	tests := []struct {
 		name   string
 		fields fields
@@ -64,61 +78,126 @@ func TestChatUseCase_AddUsersToChat(t *testing.T) {
 		{
 			name: "success",
 			fields: fields{
-				repo:  chat.NewMockChatRepo(ctrl),
-				queue: chat.NewMockQueueRepo(ctrl),
-				users: chat.NewMockUserDataInteractor(ctrl),
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
 			},
 			args: args{
-				ctx:   testCtx,
-				chat:  testChat,
-				users: []models.User{testUser3},
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser3},
 			},
 			pre: func(f *fields) {
-				f.repo.EXPECT().GetChat(testCtx, testChat).Return(testChat, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(testChat, models.OK)
 				f.repo.EXPECT().AddUsersToChat(testCtx, testChat, gomock.Cond(func(x any) bool {
+					// the added users see the unnamed chat under the name it was created with
 					chatNames := x.(map[string]string)
-					if _, ok := chatNames[testUser3.ID.String()]; !ok {
-						return false
-					}
-					return true
-				}), testUser3)
+					return chatNames[testUser3.ID.String()] == "Group chat "+testChat.ChatID.String()
+				}), testUser3).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testIssuer, models.UsersAddedMessage)
 			},
 			status: models.OK,
 		},
 		{
 			name: "success chat is named",
 			fields: fields{
-				repo:  chat.NewMockChatRepo(ctrl),
-				queue: chat.NewMockQueueRepo(ctrl),
-				users: chat.NewMockUserDataInteractor(ctrl),
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
 			},
 			args: args{
-				ctx:   testCtx,
-				chat:  testNamedChat,
-				users: []models.User{testUser3},
+				ctx:    testCtx,
+				chat:   testNamedChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser3},
 			},
 			pre: func(f *fields) {
-				f.repo.EXPECT().GetChat(testCtx, testNamedChat).Return(testNamedChat, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testNamedChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testNamedChat, testIssuer).Return(testNamedChat, models.OK)
 				f.repo.EXPECT().AddUsersToChat(testCtx, testNamedChat, gomock.Cond(func(x any) bool {
 					chatNames := x.(map[string]string)
 					if name, ok := chatNames[testUser3.ID.String()]; !ok && name != testNamedChat.Name {
 						return false
 					}
 					return true
-				}), testUser3)
+				}), testUser3).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testIssuer, models.UsersAddedMessage)
 			},
 			status: models.OK,
 		},
+		{
+			name: "members may not add users",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser3},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "issuer is not in the chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser3},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.ChatRole(""), models.NotFound)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "no one is added to a direct chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testDirectChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser3},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testDirectChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testDirectChat, testIssuer).Return(testDirectChat, models.OK)
+			},
+			status: models.Forbidden,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ch := &ChatUseCase{
-				repo:  tt.fields.repo,
-				queue: tt.fields.queue,
-				users: tt.fields.users,
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				users:  tt.fields.users,
+				events: tt.fields.events,
 			}
 			tt.pre(&tt.fields)
-			if status := ch.AddUsersToChat(tt.args.ctx, tt.args.chat, tt.args.users...); status != tt.status {
+			if status := ch.AddUsersToChat(tt.args.ctx, tt.args.chat, tt.args.issuer, tt.args.users...); status != tt.status {
 				t.Errorf("AddUsersToChat() error = %v, status %v", status, tt.status)
 			}
 		})
@@ -127,9 +206,10 @@ func TestChatUseCase_AddUsersToChat(t *testing.T) {
 
 func TestChatUseCase_CreateChat(t *testing.T) {
 	type fields struct {
-		repo  *chat.MockChatRepo
-		queue *chat.MockQueueRepo
-		users *chat.MockUserDataInteractor
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		users  *chat.MockUserDataInteractor
+		events *chat.MockEventBus
 	}
 
 	ctrl := gomock.NewController(t)
@@ -175,6 +255,28 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 		PhotoURL:     &testPhotoURL,
 	}
 
+	groupKind := models.GroupChat
+	testChatRequest4 := models2.CreateChatRequest{
+		Participants: []uuid.UUID{testUserID2},
+		IssuerID:     testUserID1,
+		Name:         &testName,
+		PhotoURL:     &testPhotoURL,
+		Kind:         &groupKind,
+	}
+	directKind := models.DirectChat
+	testChatRequest5 := models2.CreateChatRequest{
+		Participants: []uuid.UUID{testUserID2, uuid.New()},
+		IssuerID:     testUserID1,
+		Name:         &testName,
+		PhotoURL:     &testPhotoURL,
+		Kind:         &directKind,
+	}
+	existingChat := models.Chat{
+		ChatID:       uuid.New(),
+		Kind:         models.DirectChat,
+		Participants: []uuid.UUID{testUserID2, testUserID1},
+	}
+
 	type args struct {
 		ctx     context.Context
 		request models2.CreateChatRequest
@@ -190,17 +292,19 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 		{
 			name: "success",
 			fields: fields{
-				repo:  chat.NewMockChatRepo(ctrl),
-				queue: chat.NewMockQueueRepo(ctrl),
-				users: chat.NewMockUserDataInteractor(ctrl),
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
 			},
 			args: args{
 				ctx:     testCtx,
 				request: testChatRequest1,
 			},
 			pre: func(f *fields) {
-				f.users.EXPECT().GetUser(models.User{ID: testUserID2}).Return(testUser2, models.OK)
-				f.users.EXPECT().GetUser(models.User{ID: testUserID1}).Return(testUser1, models.OK)
+				f.users.EXPECT().GetUser(gomock.Any(), models.User{ID: testUserID2}).Return(testUser2, models.OK)
+				f.users.EXPECT().GetUser(gomock.Any(), models.User{ID: testUserID1}).Return(testUser1, models.OK)
+				f.repo.EXPECT().GetPersonalChat(testCtx, gomock.Any(), models.User{ID: testUserID1}).Return(models.Chat{}, models.NotFound)
 				f.repo.EXPECT().CreateChat(testCtx, gomock.Cond(func(x any) bool {
 					ch := x.(models.Chat)
 					if ch.ChatID == uuid.Nil {
@@ -215,6 +319,9 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 					if ch.Name != testUser1.Name && ch.Name != testUser2.Name {
 						return false
 					}
+					if ch.RoleOf(testChatRequest1.IssuerID) != models.OwnerRole {
+						return false
+					}
 					return true
 				}), gomock.Cond(func(x any) bool {
 					chatNames := x.(map[string]string)
@@ -225,7 +332,17 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 						return false
 					}
 					return true
-				}))
+				})).Return(models.OK)
+				// the chat is new, so its message counter is seeded first
+				f.queue.EXPECT().NextMessageSeq(testCtx, gomock.Any()).Return(int64(0), nil)
+				f.repo.EXPECT().GetLastSeq(testCtx, gomock.Any()).Return(int64(0), models.OK)
+				f.queue.EXPECT().SeedMessageSeq(testCtx, gomock.Any(), int64(0)).Return(nil)
+				f.queue.EXPECT().NextMessageSeq(testCtx, gomock.Any()).Return(int64(1), nil)
+				f.queue.EXPECT().SaveMessage(testCtx, gomock.Cond(func(x any) bool {
+					msg := x.(models.Message)
+					return msg.Kind == models.ChatCreatedMessage && msg.Seq == 1 && msg.SenderID == testUserID1
+				})).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, gomock.Any(), gomock.Any()).Return(models.OK)
 			},
 			want: func(m models.Chat) bool {
 				if m.ChatID == uuid.Nil {
@@ -244,17 +361,19 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 		{
 			name: "success include self ",
 			fields: fields{
-				repo:  chat.NewMockChatRepo(ctrl),
-				queue: chat.NewMockQueueRepo(ctrl),
-				users: chat.NewMockUserDataInteractor(ctrl),
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
 			},
 			args: args{
 				ctx:     testCtx,
 				request: testChatRequest2,
 			},
 			pre: func(f *fields) {
-				f.users.EXPECT().GetUser(models.User{ID: testUserID1}).Return(testUser1, models.OK)
-				f.users.EXPECT().GetUser(models.User{ID: testUserID2}).Return(testUser2, models.OK)
+				f.users.EXPECT().GetUser(gomock.Any(), models.User{ID: testUserID1}).Return(testUser1, models.OK)
+				f.users.EXPECT().GetUser(gomock.Any(), models.User{ID: testUserID2}).Return(testUser2, models.OK)
+				f.repo.EXPECT().GetPersonalChat(testCtx, gomock.Any(), models.User{ID: testUserID1}).Return(models.Chat{}, models.NotFound)
 				f.repo.EXPECT().CreateChat(testCtx, gomock.Cond(func(x any) bool {
 					ch := x.(models.Chat)
 					if ch.ChatID == uuid.Nil {
@@ -279,7 +398,8 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 						return false
 					}
 					return true
-				}))
+				})).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testUser1, models.ChatCreatedMessage)
 			},
 			status: models.OK,
 			want: func(m models.Chat) bool {
@@ -298,16 +418,18 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 		{
 			name: "success single user chat",
 			fields: fields{
-				repo:  chat.NewMockChatRepo(ctrl),
-				queue: chat.NewMockQueueRepo(ctrl),
-				users: chat.NewMockUserDataInteractor(ctrl),
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
 			},
 			args: args{
 				ctx:     testCtx,
 				request: testChatRequest3,
 			},
 			pre: func(f *fields) {
-				f.users.EXPECT().GetUser(models.User{ID: testUserID1}).Return(testUser1, models.OK)
+				f.users.EXPECT().GetUser(gomock.Any(), models.User{ID: testUserID1}).Return(testUser1, models.OK)
+				f.repo.EXPECT().GetPersonalChat(testCtx, gomock.Any(), models.User{ID: testUserID1}).Return(models.Chat{}, models.NotFound)
 				f.repo.EXPECT().CreateChat(testCtx, gomock.Cond(func(x any) bool {
 					ch := x.(models.Chat)
 					if ch.ChatID == uuid.Nil {
@@ -329,7 +451,8 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 						return false
 					}
 					return true
-				}))
+				})).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testUser1, models.ChatCreatedMessage)
 			},
 			status: models.OK,
 			want: func(m models.Chat) bool {
@@ -345,13 +468,105 @@ func TestChatUseCase_CreateChat(t *testing.T) {
 				return true
 			},
 		},
+		{
+			name: "users already have a direct chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:     testCtx,
+				request: testChatRequest2,
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetPersonalChat(testCtx, gomock.Cond(func(x any) bool {
+					ch := x.(models.Chat)
+					return ch.Kind == models.DirectChat && ch.PersonalKey() == existingChat.PersonalKey()
+				}), models.User{ID: testUserID1}).Return(existingChat, models.OK)
+			},
+			status: models.Conflict,
+			want: func(m models.Chat) bool {
+				return m.ChatID == existingChat.ChatID
+			},
+		},
+		{
+			name: "direct chat created by the other user meanwhile",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:     testCtx,
+				request: testChatRequest2,
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetPersonalChat(testCtx, gomock.Any(), models.User{ID: testUserID1}).Return(models.Chat{}, models.NotFound)
+				f.users.EXPECT().GetUser(gomock.Any(), gomock.Any()).Return(testUser1, models.OK).Times(2)
+				f.repo.EXPECT().CreateChat(testCtx, gomock.Any(), gomock.Any()).Return(models.Conflict)
+				f.repo.EXPECT().GetPersonalChat(testCtx, gomock.Any(), models.User{ID: testUserID1}).Return(existingChat, models.OK)
+			},
+			status: models.Conflict,
+			want: func(m models.Chat) bool {
+				return m.ChatID == existingChat.ChatID
+			},
+		},
+		{
+			name: "group of two asked explicitly",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:     testCtx,
+				request: testChatRequest4,
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().CreateChat(testCtx, gomock.Cond(func(x any) bool {
+					ch := x.(models.Chat)
+					return ch.Kind == models.GroupChat && len(ch.Participants) == 2
+				}), map[string]string{
+					testUserID1.String(): testName,
+					testUserID2.String(): testName,
+				}).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testUser1, models.ChatCreatedMessage)
+			},
+			status: models.OK,
+			want: func(m models.Chat) bool {
+				return m.Kind == models.GroupChat
+			},
+		},
+		{
+			name: "direct chat of three users",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				users:  chat.NewMockUserDataInteractor(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:     testCtx,
+				request: testChatRequest5,
+			},
+			pre:    func(f *fields) {},
+			status: models.BadRequest,
+			want: func(m models.Chat) bool {
+				return m.ChatID == uuid.Nil
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ch := &ChatUseCase{
-				repo:  tt.fields.repo,
-				queue: tt.fields.queue,
-				users: tt.fields.users,
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				users:  tt.fields.users,
+				events: tt.fields.events,
 			}
 			tt.pre(&tt.fields)
 			got, status := ch.CreateChat(tt.args.ctx, tt.args.request)
@@ -377,42 +592,34 @@ func TestChatUseCase_GetChatMessages(t *testing.T) {
 	defer ctrl.Finish()
 
 	testCtx := context.Background()
-	testOpts1 := models.Opts{
-		Limit: 3,
-		Page:  0,
-	}
-	testOpts2 := models.Opts{
-		Limit: 1,
-		Page:  0,
-	}
 
 	testChat := models.Chat{
 		ChatID: uuid.New(),
 	}
+	testUser := models.User{ID: uuid.New()}
 
-	testMsg1 := models.Message{
-		ChatID:    testChat.ChatID,
-		MsgID:     uuid.New(),
-		Payload:   "",
-		CreatedAt: 10,
+	newTestMsg := func(seq int64) models.Message {
+		return models.Message{
+			ChatID:    testChat.ChatID,
+			MsgID:     uuid.New(),
+			CreatedAt: 10,
+			Seq:       seq,
+		}
 	}
+	testMsg2 := newTestMsg(2)
+	testMsg3 := newTestMsg(3)
+	testMsg4 := newTestMsg(4)
+	testMsg5 := newTestMsg(5)
 
-	testMsg2 := models.Message{
-		ChatID:    testChat.ChatID,
-		MsgID:     uuid.New(),
-		Payload:   "",
-		CreatedAt: 9,
-	}
-	testMsg3 := models.Message{
-		ChatID:    testChat.ChatID,
-		MsgID:     uuid.New(),
-		Payload:   "",
-		CreatedAt: 8,
-	}
+	newestOpts := models.Opts{Limit: 3}
+	byIDOpts := models.Opts{Limit: 2, Before: models.Cursor{MsgID: testMsg4.MsgID}}
+	resolvedOpts := models.Opts{Limit: 2, Before: models.Cursor{Seq: 4, MsgID: testMsg4.MsgID}}
+	afterOpts := models.Opts{Limit: 2, After: models.Cursor{Seq: 2}}
 
 	type args struct {
 		ctx  context.Context
 		chat models.Chat
+		user models.User
 		opts models.Opts
 	}
 	tests := []struct {
@@ -424,7 +631,7 @@ func TestChatUseCase_GetChatMessages(t *testing.T) {
 		status models.StatusCode
 	}{
 		{
-			name: "fetch from both storages",
+			name: "flushing messages are not duplicated",
 			fields: fields{
 				repo:  chat.NewMockChatRepo(ctrl),
 				queue: chat.NewMockQueueRepo(ctrl),
@@ -433,19 +640,69 @@ func TestChatUseCase_GetChatMessages(t *testing.T) {
 			args: args{
 				ctx:  testCtx,
 				chat: testChat,
-				opts: testOpts1,
+				user: testUser,
+				opts: newestOpts,
 			},
 			pre: func(f *fields) {
-				f.queue.EXPECT().GetChatMessages(testChat, testOpts1).
-					Return(models.Messages{testMsg1, testMsg2}, models.OK)
-				f.repo.EXPECT().GetChatMessages(testCtx, testChat, testOpts2).
-					Return(models.Messages{testMsg3}, models.OK)
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetChatMessages(testChat, newestOpts).
+					Return(models.Messages{testMsg5, testMsg4}, models.OK)
+				f.repo.EXPECT().GetChatMessages(testCtx, testChat, newestOpts).
+					Return(models.Messages{testMsg4, testMsg3, testMsg2}, models.OK)
+			},
+			want:   models.Messages{testMsg5, testMsg4, testMsg3},
+			status: models.OK,
+		},
+		{
+			name: "queue failure",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			args: args{
+				ctx:  testCtx,
+				chat: testChat,
+				user: testUser,
+				opts: newestOpts,
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetChatMessages(testChat, newestOpts).
+					Return(nil, models.InternalError)
+				f.repo.EXPECT().GetChatMessages(testCtx, testChat, newestOpts).
+					Return(models.Messages{testMsg3, testMsg2}, models.OK)
+			},
+			want:   models.Messages{testMsg3, testMsg2},
+			status: models.OK,
+		},
+		{
+			name: "cursor by message id",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			args: args{
+				ctx:  testCtx,
+				chat: testChat,
+				user: testUser,
+				opts: byIDOpts,
 			},
-			want:   models.Messages{testMsg1, testMsg2, testMsg3},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, models.Message{MsgID: testMsg4.MsgID, ChatID: testChat.ChatID}).
+					Return(testMsg4, models.OK)
+				f.queue.EXPECT().GetChatMessages(testChat, resolvedOpts).
+					Return(models.Messages{}, models.OK)
+				f.repo.EXPECT().GetChatMessages(testCtx, testChat, resolvedOpts).
+					Return(models.Messages{testMsg3, testMsg2}, models.OK)
+			},
+			want:   models.Messages{testMsg3, testMsg2},
 			status: models.OK,
 		},
 		{
-			name: "fetch only from queue",
+			name: "newer messages closest to the cursor",
 			fields: fields{
 				repo:  chat.NewMockChatRepo(ctrl),
 				queue: chat.NewMockQueueRepo(ctrl),
@@ -454,15 +711,60 @@ func TestChatUseCase_GetChatMessages(t *testing.T) {
 			args: args{
 				ctx:  testCtx,
 				chat: testChat,
-				opts: testOpts2,
+				user: testUser,
+				opts: afterOpts,
 			},
 			pre: func(f *fields) {
-				f.queue.EXPECT().GetChatMessages(testChat, testOpts2).
-					Return(models.Messages{testMsg1}, models.OK)
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetChatMessages(testChat, afterOpts).
+					Return(models.Messages{testMsg5, testMsg4}, models.OK)
+				f.repo.EXPECT().GetChatMessages(testCtx, testChat, afterOpts).
+					Return(models.Messages{testMsg3}, models.OK)
 			},
-			want:   models.Messages{testMsg1},
+			want:   models.Messages{testMsg4, testMsg3},
 			status: models.OK,
 		},
+		{
+			name: "unknown cursor message",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			args: args{
+				ctx:  testCtx,
+				chat: testChat,
+				user: testUser,
+				opts: byIDOpts,
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				lookup := models.Message{MsgID: testMsg4.MsgID, ChatID: testChat.ChatID}
+				f.queue.EXPECT().GetMessage(testCtx, lookup).Return(models.Message{}, models.NotFound)
+				f.repo.EXPECT().GetMessage(testCtx, lookup).Return(models.Message{}, models.NotFound)
+			},
+			want:   nil,
+			status: models.NotFound,
+		},
+		{
+			name: "not a member, the queue is not read",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			args: args{
+				ctx:  testCtx,
+				chat: testChat,
+				user: testUser,
+				opts: newestOpts,
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(false, models.OK)
+			},
+			want:   nil,
+			status: models.NotFound,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -472,7 +774,7 @@ func TestChatUseCase_GetChatMessages(t *testing.T) {
 				users: tt.fields.users,
 			}
 			tt.pre(&tt.fields)
-			got, status := ch.GetChatMessages(tt.args.ctx, tt.args.chat, tt.args.opts)
+			got, status := ch.GetChatMessages(tt.args.ctx, tt.args.chat, tt.args.user, tt.args.opts)
 			if status != tt.status {
 				t.Errorf("GetChatMessages() error = %v, status %v", status, tt.status)
 				return
@@ -483,3 +785,1578 @@ func TestChatUseCase_GetChatMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestChatUseCase_RemoveUserFromChat(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		events *chat.MockEventBus
+	}
+	type args struct {
+		ctx    context.Context
+		chat   models.Chat
+		issuer models.User
+		users  []models.User
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testIssuer := models.User{ID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testAdmin := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	testFullChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Kind:         models.GroupChat,
+		Participants: []uuid.UUID{testIssuer.ID, testUser.ID, testAdmin.ID},
+		Members: []models.ChatMember{
+			{UserID: testIssuer.ID, Role: models.AdminRole},
+			{UserID: testUser.ID, Role: models.MemberRole},
+			{UserID: testAdmin.ID, Role: models.AdminRole},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "success, removed users are kicked",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(testFullChat, models.OK)
+				f.repo.EXPECT().RemoveUserFromChat(testCtx, testChat, testUser).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, models.Notification{
+					Type: models.UpdateMessage,
+					Body: &models.ChatUpdateEvent{
+						ChatID: testChat.ChatID,
+						Action: models.MembersRemovedAction,
+						UserID: testIssuer.ID,
+						Users:  []uuid.UUID{testUser.ID},
+					},
+				}).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testIssuer, models.UsersRemovedMessage)
+				f.events.EXPECT().PublishChatEvent(testCtx, models.ChatEvent{
+					Type:   models.UsersRemovedFromChat,
+					ChatID: testChat.ChatID,
+					Users:  []uuid.UUID{testUser.ID},
+				}).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "failed to publish the events and the system message",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(testFullChat, models.OK)
+				f.repo.EXPECT().RemoveUserFromChat(testCtx, testChat, testUser).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, gomock.Any()).Return(models.InternalError)
+				f.queue.EXPECT().NextMessageSeq(testCtx, seq.Key(testChat.ChatID)).Return(int64(0), errors.New("redis is down"))
+				f.events.EXPECT().PublishChatEvent(testCtx, gomock.Any()).Return(models.InternalError)
+			},
+			status: models.OK,
+		},
+		{
+			name: "failed to remove, no event",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(testFullChat, models.OK)
+				f.repo.EXPECT().RemoveUserFromChat(testCtx, testChat, testUser).Return(models.InternalError)
+			},
+			status: models.InternalError,
+		},
+		{
+			name: "admins may not remove admins",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testAdmin},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(testFullChat, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "members may not remove anyone",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "user is not in the chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{{ID: uuid.New()}},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(testFullChat, models.OK)
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "users may not be removed from a direct chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{
+				ctx:    testCtx,
+				chat:   testChat,
+				issuer: testIssuer,
+				users:  []models.User{testUser},
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(models.Chat{
+					ChatID:       testChat.ChatID,
+					Kind:         models.DirectChat,
+					Participants: []uuid.UUID{testIssuer.ID, testUser.ID},
+				}, models.OK)
+			},
+			status: models.Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.RemoveUserFromChat(tt.args.ctx, tt.args.chat, tt.args.issuer, tt.args.users...); status != tt.status {
+				t.Errorf("RemoveUserFromChat() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_GetChatList(t *testing.T) {
+	type fields struct {
+		repo  *chat.MockChatRepo
+		queue *chat.MockQueueRepo
+		users *chat.MockUserDataInteractor
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{
+		ChatID:        uuid.New(),
+		UnreadCount:   2,
+		LastMessage:   models.Message{MsgID: uuid.New(), Seq: 4},
+		MessagesCount: 4,
+	}
+	testSender := uuid.New()
+	queuedMsg5 := models.Message{ChatID: testChat.ChatID, MsgID: uuid.New(), SenderID: testSender, Seq: 5}
+	queuedMsg6 := models.Message{ChatID: testChat.ChatID, MsgID: uuid.New(), SenderID: testSender, Seq: 6}
+	ownMsg7 := models.Message{ChatID: testChat.ChatID, MsgID: uuid.New(), SenderID: testUser.ID, Seq: 7}
+	overlaidChat := testChat
+	overlaidChat.LastMessage = queuedMsg6
+	overlaidChat.MessagesCount = 6
+	readChat := testChat
+	readChat.LastReadSeq = 5
+	readChat.UnreadCount = 0
+	overlaidReadChat := readChat
+	overlaidReadChat.LastMessage = ownMsg7
+	overlaidReadChat.MessagesCount = 7
+	testPeer := models.User{ID: uuid.New(), Nickname: "renamed", Avatar: "avatar.png"}
+	directChat := models.Chat{
+		ChatID:       uuid.New(),
+		Kind:         models.DirectChat,
+		Participants: []uuid.UUID{testUser.ID, testPeer.ID},
+		Name:         "old nickname",
+		PhotoURL:     "default.png",
+	}
+	titledChat := directChat
+	titledChat.Name = testPeer.Nickname
+	titledChat.PhotoURL = testPeer.Avatar
+	unknownPeerChat := models.Chat{
+		ChatID:       uuid.New(),
+		Kind:         models.DirectChat,
+		Participants: []uuid.UUID{testUser.ID, uuid.New()},
+		Name:         "snapshot",
+	}
+	withUnread := func(chat models.Chat, unread int64) models.Chat {
+		chat.UnreadCount = unread
+		return chat
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		want   []models.Chat
+		status models.StatusCode
+	}{
+		{
+			name: "nothing queued",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().FetchChatList(testCtx, testUser).Return([]models.Chat{testChat}, models.OK)
+				f.queue.EXPECT().GetQueuedMessages(testCtx, []models.Chat{testChat}).
+					Return([]models.Messages{{}}, models.OK)
+			},
+			want:   []models.Chat{testChat},
+			status: models.OK,
+		},
+		{
+			name: "last message is not flushed yet",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().FetchChatList(testCtx, testUser).Return([]models.Chat{testChat}, models.OK)
+				f.queue.EXPECT().GetQueuedMessages(testCtx, []models.Chat{testChat}).
+					Return([]models.Messages{{queuedMsg5, queuedMsg6}}, models.OK)
+			},
+			want:   []models.Chat{withUnread(overlaidChat, 4)},
+			status: models.OK,
+		},
+		{
+			name: "queued messages read or sent by the user are not unread",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().FetchChatList(testCtx, testUser).Return([]models.Chat{readChat}, models.OK)
+				f.queue.EXPECT().GetQueuedMessages(testCtx, []models.Chat{readChat}).
+					Return([]models.Messages{{queuedMsg5, queuedMsg6, ownMsg7}}, models.OK)
+			},
+			want:   []models.Chat{withUnread(overlaidReadChat, 1)},
+			status: models.OK,
+		},
+		{
+			name: "queue failure keeps the persisted chat",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().FetchChatList(testCtx, testUser).Return([]models.Chat{testChat}, models.OK)
+				f.queue.EXPECT().GetQueuedMessages(testCtx, []models.Chat{testChat}).Return(nil, models.InternalError)
+			},
+			want:   []models.Chat{testChat},
+			status: models.OK,
+		},
+		{
+			name: "repo failure",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().FetchChatList(testCtx, testUser).Return(nil, models.InternalError)
+			},
+			want:   nil,
+			status: models.InternalError,
+		},
+		{
+			name: "direct chats are titled after the other participant",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().FetchChatList(testCtx, testUser).
+					Return([]models.Chat{directChat, unknownPeerChat, testChat}, models.OK)
+				f.users.EXPECT().GetUsers(testCtx, []uuid.UUID{testPeer.ID, unknownPeerChat.Participants[1]}).
+					Return(map[uuid.UUID]models.User{testPeer.ID: testPeer}, models.OK)
+				f.queue.EXPECT().GetQueuedMessages(testCtx, []models.Chat{titledChat, unknownPeerChat, testChat}).
+					Return([]models.Messages{nil, nil, nil}, models.OK)
+			},
+			want:   []models.Chat{titledChat, unknownPeerChat, testChat},
+			status: models.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:  tt.fields.repo,
+				queue: tt.fields.queue,
+				users: tt.fields.users,
+			}
+			tt.pre(&tt.fields)
+			got, status := ch.GetChatList(testCtx, testUser)
+			if status != tt.status {
+				t.Errorf("GetChatList() error = %v, status %v", status, tt.status)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetChatList() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_MarkRead(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	testMsgID := uuid.New()
+	lookupMsg := models.Message{MsgID: testMsgID, ChatID: testChat.ChatID}
+	testMsg := models.Message{
+		MsgID:     testMsgID,
+		ChatID:    testChat.ChatID,
+		SenderID:  uuid.New(),
+		Payload:   "test",
+		CreatedAt: 1,
+	}
+	otherChatMsg := testMsg
+	otherChatMsg.ChatID = uuid.New()
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "queued message, read event is published",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookupMsg).Return(testMsg, models.OK)
+				f.repo.EXPECT().UpdateLastRead(testCtx, testChat, testUser, testMsg).Return(true, models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ models.Chat, n models.Notification) models.StatusCode {
+						event := n.Body.(*models.ReadEvent)
+						if n.Type != models.ReadMessage || event.UserID != testUser.ID || event.MsgID != testMsgID {
+							t.Errorf("unexpected read notification %v", n)
+						}
+						return models.OK
+					})
+			},
+			status: models.OK,
+		},
+		{
+			name: "persisted message, cursor is already further",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookupMsg).Return(models.Message{}, models.NotFound)
+				f.repo.EXPECT().GetMessage(testCtx, lookupMsg).Return(testMsg, models.OK)
+				f.repo.EXPECT().UpdateLastRead(testCtx, testChat, testUser, testMsg).Return(false, models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "message of another chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookupMsg).Return(models.Message{}, models.NotFound)
+				f.repo.EXPECT().GetMessage(testCtx, lookupMsg).Return(otherChatMsg, models.OK)
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "not a member",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(false, models.OK)
+			},
+			// the users outside of the chat can't tell whether it exists
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.MarkRead(testCtx, testChat, testUser, models.Message{MsgID: testMsgID}); status != tt.status {
+				t.Errorf("MarkRead() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_GetSeenBy(t *testing.T) {
+	type fields struct {
+		repo  *chat.MockChatRepo
+		queue *chat.MockQueueRepo
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	testMsg := models.Message{MsgID: uuid.New(), ChatID: testChat.ChatID, Seq: 3}
+	lookupMsg := models.Message{MsgID: testMsg.MsgID, ChatID: testChat.ChatID}
+	receipts := []models.ReadReceipt{{UserID: uuid.New(), LastReadMsgID: testMsg.MsgID}}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		want   []models.ReadReceipt
+		status models.StatusCode
+	}{
+		{
+			name: "member",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookupMsg).Return(testMsg, models.OK)
+				f.repo.EXPECT().GetSeenBy(testCtx, testChat, testMsg).Return(receipts, models.OK)
+			},
+			want:   receipts,
+			status: models.OK,
+		},
+		{
+			name: "not a member",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				queue: chat.NewMockQueueRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(false, models.OK)
+			},
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:  tt.fields.repo,
+				queue: tt.fields.queue,
+			}
+			tt.pre(&tt.fields)
+			got, status := ch.GetSeenBy(testCtx, testChat, testUser, models.Message{MsgID: testMsg.MsgID})
+			if status != tt.status {
+				t.Errorf("GetSeenBy() error = %v, status %v", status, tt.status)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSeenBy() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_PromoteParticipant(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testOwner := models.User{ID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "success, participants are notified",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().UpdateParticipantRole(testCtx, testChat, testUser, models.AdminRole).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, models.Notification{
+					Type: models.RoleMessage,
+					Body: &models.RoleEvent{
+						ChatID:    testChat.ChatID,
+						UserID:    testUser.ID,
+						Role:      models.AdminRole,
+						ChangedBy: testOwner.ID,
+					},
+				}).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "already an admin",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.AdminRole, models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "admins may not promote",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.AdminRole, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "user is not in the chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.ChatRole(""), models.NotFound)
+			},
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.PromoteParticipant(testCtx, testChat, testOwner, testUser); status != tt.status {
+				t.Errorf("PromoteParticipant() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_DemoteParticipant(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testOwner := models.User{ID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "success",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().UpdateParticipantRole(testCtx, testChat, testUser, models.MemberRole).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, gomock.Any()).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "owner is demoted only by a transfer",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.OwnerRole, models.OK)
+			},
+			status: models.BadRequest,
+		},
+		{
+			name: "failed to update, no event",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().UpdateParticipantRole(testCtx, testChat, testUser, models.MemberRole).
+					Return(models.InternalError)
+			},
+			status: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.DemoteParticipant(testCtx, testChat, testOwner, testUser); status != tt.status {
+				t.Errorf("DemoteParticipant() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_TransferOwnership(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		events *chat.MockEventBus
+	}
+	type args struct {
+		issuer models.User
+		user   models.User
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testOwner := models.User{ID: uuid.New()}
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "success, both changes are published",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{issuer: testOwner, user: testUser},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().TransferOwnership(testCtx, testChat, testOwner, testUser).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, gomock.Any()).Return(models.OK).Times(2)
+			},
+			status: models.OK,
+		},
+		{
+			name: "to oneself",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args:   args{issuer: testOwner, user: testOwner},
+			pre:    func(f *fields) {},
+			status: models.BadRequest,
+		},
+		{
+			name: "not the owner",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{issuer: testOwner, user: testUser},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.AdminRole, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "user is not in the chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			args: args{issuer: testOwner, user: testUser},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().TransferOwnership(testCtx, testChat, testOwner, testUser).Return(models.NotFound)
+			},
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.TransferOwnership(testCtx, testChat, tt.args.issuer, tt.args.user); status != tt.status {
+				t.Errorf("TransferOwnership() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_GetChat(t *testing.T) {
+	type fields struct {
+		repo  *chat.MockChatRepo
+		users *chat.MockUserDataInteractor
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	testFullChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Name:         "chat",
+		Participants: []uuid.UUID{testUser.ID},
+	}
+	testPeer := models.User{ID: uuid.New(), Nickname: "renamed", Avatar: "avatar.png"}
+	directChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Kind:         models.DirectChat,
+		Name:         "old nickname",
+		PhotoURL:     "default.png",
+		Participants: []uuid.UUID{testUser.ID, testPeer.ID},
+	}
+	titledChat := directChat
+	titledChat.Name = testPeer.Nickname
+	titledChat.PhotoURL = testPeer.Avatar
+	renamedChat := directChat
+	renamedChat.Name = "my friend"
+	renamedChat.CustomName = true
+	titledRenamedChat := renamedChat
+	titledRenamedChat.PhotoURL = testPeer.Avatar
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		want   models.Chat
+		status models.StatusCode
+	}{
+		{
+			name: "member",
+			fields: fields{
+				repo: chat.NewMockChatRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(testFullChat, models.OK)
+			},
+			want:   testFullChat,
+			status: models.OK,
+		},
+		{
+			name: "not a member",
+			fields: fields{
+				repo: chat.NewMockChatRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(false, models.OK)
+			},
+			want:   models.Chat{},
+			status: models.NotFound,
+		},
+		{
+			name: "failed to check the membership",
+			fields: fields{
+				repo: chat.NewMockChatRepo(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(false, models.InternalError)
+			},
+			want:   models.Chat{},
+			status: models.InternalError,
+		},
+		{
+			name: "direct chat is titled after the other participant",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(directChat, models.OK)
+				f.users.EXPECT().GetUsers(testCtx, []uuid.UUID{testPeer.ID}).
+					Return(map[uuid.UUID]models.User{testPeer.ID: testPeer}, models.OK)
+			},
+			want:   titledChat,
+			status: models.OK,
+		},
+		{
+			name: "direct chat keeps its name if the profile is not found",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(directChat, models.OK)
+				f.users.EXPECT().GetUsers(testCtx, []uuid.UUID{testPeer.ID}).
+					Return(map[uuid.UUID]models.User{}, models.OK)
+			},
+			want:   directChat,
+			status: models.OK,
+		},
+		{
+			name: "renamed direct chat keeps its custom name",
+			fields: fields{
+				repo:  chat.NewMockChatRepo(ctrl),
+				users: chat.NewMockUserDataInteractor(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().IsChatParticipant(testCtx, testChat, testUser).Return(true, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(renamedChat, models.OK)
+				f.users.EXPECT().GetUsers(testCtx, []uuid.UUID{testPeer.ID}).
+					Return(map[uuid.UUID]models.User{testPeer.ID: testPeer}, models.OK)
+			},
+			want:   titledRenamedChat,
+			status: models.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:  tt.fields.repo,
+				users: tt.fields.users,
+			}
+			tt.pre(&tt.fields)
+			got, status := ch.GetChat(testCtx, testChat, testUser)
+			if status != tt.status {
+				t.Errorf("GetChat() error = %v, status %v", status, tt.status)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetChat() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_LeaveChat(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testUser := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "member leaves, sessions are closed",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(models.Chat{
+					ChatID:       testChat.ChatID,
+					Kind:         models.GroupChat,
+					Participants: []uuid.UUID{testUser.ID, uuid.New()},
+				}, models.OK)
+				f.repo.EXPECT().RemoveUserFromChat(testCtx, testChat, testUser).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, models.Notification{
+					Type: models.UpdateMessage,
+					Body: &models.ChatUpdateEvent{
+						ChatID: testChat.ChatID,
+						Action: models.ChatLeftAction,
+						UserID: testUser.ID,
+					},
+				}).Return(models.OK)
+				expectSystemMessage(f.queue, f.events, testUser, models.UsersRemovedMessage)
+				f.events.EXPECT().PublishChatEvent(testCtx, models.ChatEvent{
+					Type:   models.UsersRemovedFromChat,
+					ChatID: testChat.ChatID,
+					Users:  []uuid.UUID{testUser.ID},
+				}).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "member leaves a channel without a system message",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(models.Chat{
+					ChatID:       testChat.ChatID,
+					Kind:         models.ChannelChat,
+					Participants: []uuid.UUID{testUser.ID, uuid.New()},
+				}, models.OK)
+				f.repo.EXPECT().RemoveUserFromChat(testCtx, testChat, testUser).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, models.Notification{
+					Type: models.UpdateMessage,
+					Body: &models.ChatUpdateEvent{
+						ChatID: testChat.ChatID,
+						Action: models.ChatLeftAction,
+						UserID: testUser.ID,
+					},
+				}).Return(models.OK)
+				f.events.EXPECT().PublishChatEvent(testCtx, models.ChatEvent{
+					Type:   models.UsersRemovedFromChat,
+					ChatID: testChat.ChatID,
+					Users:  []uuid.UUID{testUser.ID},
+				}).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "owner has to transfer the ownership first",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(models.Chat{
+					ChatID:       testChat.ChatID,
+					Kind:         models.GroupChat,
+					Participants: []uuid.UUID{testUser.ID, uuid.New()},
+				}, models.OK)
+			},
+			status: models.Conflict,
+		},
+		{
+			name: "last participant deletes the chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				fullChat := models.Chat{
+					ChatID:       testChat.ChatID,
+					Kind:         models.SavedMessagesChat,
+					Participants: []uuid.UUID{testUser.ID},
+				}
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(fullChat, models.OK)
+				f.repo.EXPECT().DeleteChat(testCtx, fullChat).Return(models.Deleted)
+				f.queue.EXPECT().DeleteChatMessages(testCtx, fullChat).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, fullChat, gomock.Any()).Return(models.OK)
+				f.events.EXPECT().PublishChatEvent(testCtx, gomock.Any()).Return(models.OK)
+			},
+			status: models.Deleted,
+		},
+		{
+			name: "not a participant",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.ChatRole(""), models.NotFound)
+			},
+			status: models.NotFound,
+		},
+		{
+			name: "direct chat cannot be left",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testUser).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testUser).Return(models.Chat{
+					ChatID:       testChat.ChatID,
+					Kind:         models.DirectChat,
+					Participants: []uuid.UUID{testUser.ID, uuid.New()},
+				}, models.OK)
+			},
+			status: models.Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.LeaveChat(testCtx, testChat, testUser); status != tt.status {
+				t.Errorf("LeaveChat() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_RenameChat(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testIssuer := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	testName := "renamed"
+	directChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Kind:         models.DirectChat,
+		Participants: []uuid.UUID{testIssuer.ID, uuid.New()},
+	}
+	groupChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Kind:         models.GroupChat,
+		Participants: []uuid.UUID{testIssuer.ID, uuid.New(), uuid.New()},
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "group is renamed for everyone",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(groupChat, models.OK)
+				f.repo.EXPECT().UpdateChatName(testCtx, testChat, testName).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, models.Notification{
+					Type: models.UpdateMessage,
+					Body: &models.ChatUpdateEvent{
+						ChatID: testChat.ChatID,
+						Action: models.ChatRenamedAction,
+						UserID: testIssuer.ID,
+						Name:   testName,
+					},
+				}).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "members may not rename a group",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(groupChat, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "direct chat is renamed for the issuer only",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(directChat, models.OK)
+				f.repo.EXPECT().UpdateChatName(testCtx, testChat, testName, testIssuer).Return(models.OK)
+				f.events.EXPECT().PublishChatEvent(testCtx, models.ChatEvent{
+					Type:   models.ChatRenamedForUsers,
+					ChatID: testChat.ChatID,
+					Users:  []uuid.UUID{testIssuer.ID},
+					Name:   testName,
+				}).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "not a participant",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.ChatRole(""), models.NotFound)
+			},
+			status: models.Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.RenameChat(testCtx, testChat, testIssuer, testName); status != tt.status {
+				t.Errorf("RenameChat() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_DeleteChat(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testOwner := models.User{ID: uuid.New()}
+	testMember := uuid.New()
+	testChat := models.Chat{ChatID: uuid.New()}
+	fullChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Kind:         models.GroupChat,
+		Participants: []uuid.UUID{testOwner.ID, testMember},
+	}
+
+	directChat := models.Chat{
+		ChatID:       testChat.ChatID,
+		Kind:         models.DirectChat,
+		Participants: []uuid.UUID{testOwner.ID, testMember},
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "owner deletes, every participant is kicked",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testOwner).Return(fullChat, models.OK)
+				f.repo.EXPECT().DeleteChat(testCtx, fullChat).Return(models.Deleted)
+				f.queue.EXPECT().DeleteChatMessages(testCtx, fullChat).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, fullChat, models.Notification{
+					Type: models.UpdateMessage,
+					Body: &models.ChatUpdateEvent{
+						ChatID: testChat.ChatID,
+						Action: models.ChatDeletedAction,
+						UserID: testOwner.ID,
+					},
+				}).Return(models.OK)
+				f.events.EXPECT().PublishChatEvent(testCtx, models.ChatEvent{
+					Type:   models.UsersRemovedFromChat,
+					ChatID: testChat.ChatID,
+					Users:  []uuid.UUID{testOwner.ID, testMember},
+				}).Return(models.OK)
+			},
+			status: models.Deleted,
+		},
+		{
+			name: "admins may not delete",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testOwner).Return(fullChat, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "failed to delete, no events",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testOwner).Return(fullChat, models.OK)
+				f.repo.EXPECT().DeleteChat(testCtx, fullChat).Return(models.InternalError)
+			},
+			status: models.InternalError,
+		},
+		{
+			name: "any participant deletes a direct chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testOwner).Return(models.MemberRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testOwner).Return(directChat, models.OK)
+				f.repo.EXPECT().DeleteChat(testCtx, directChat).Return(models.Deleted)
+				f.queue.EXPECT().DeleteChatMessages(testCtx, directChat).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, directChat, gomock.Any()).Return(models.OK)
+				f.events.EXPECT().PublishChatEvent(testCtx, gomock.Any()).Return(models.OK)
+			},
+			status: models.Deleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.DeleteChat(testCtx, testChat, testOwner); status != tt.status {
+				t.Errorf("DeleteChat() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_DeleteMessage(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testIssuer := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	ownMsg := models.Message{MsgID: uuid.New(), ChatID: testChat.ChatID, SenderID: testIssuer.ID, Seq: 3}
+	otherMsg := models.Message{MsgID: uuid.New(), ChatID: testChat.ChatID, SenderID: uuid.New(), Seq: 4}
+	lookup := func(msg models.Message) models.Message {
+		return models.Message{MsgID: msg.MsgID, ChatID: testChat.ChatID}
+	}
+
+	tests := []struct {
+		name    string
+		fields  fields
+		message models.Message
+		pre     func(f *fields)
+		status  models.StatusCode
+	}{
+		{
+			name: "sender deletes own message",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			message: models.Message{MsgID: ownMsg.MsgID},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookup(ownMsg)).Return(models.Message{}, models.NotFound)
+				f.repo.EXPECT().GetMessage(testCtx, lookup(ownMsg)).Return(ownMsg, models.OK)
+				f.queue.EXPECT().DeleteMessage(testCtx, ownMsg).Return(models.OK)
+				f.repo.EXPECT().DeleteMessage(testCtx, ownMsg).Return(models.Deleted)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, models.Notification{
+					Type: models.UpdateMessage,
+					Body: &models.ChatUpdateEvent{
+						ChatID: testChat.ChatID,
+						Action: models.MessageDeletedAction,
+						UserID: testIssuer.ID,
+						MsgID:  &ownMsg.MsgID,
+					},
+				}).Return(models.OK)
+			},
+			status: models.Deleted,
+		},
+		{
+			name: "admin deletes a message of another participant",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			message: models.Message{MsgID: otherMsg.MsgID},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookup(otherMsg)).Return(otherMsg, models.OK)
+				f.queue.EXPECT().DeleteMessage(testCtx, otherMsg).Return(models.OK)
+				f.repo.EXPECT().DeleteMessage(testCtx, otherMsg).Return(models.Deleted)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, gomock.Any()).Return(models.OK)
+			},
+			status: models.Deleted,
+		},
+		{
+			name: "member may not delete a message of another participant",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			message: models.Message{MsgID: otherMsg.MsgID},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookup(otherMsg)).Return(otherMsg, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "message of another chat",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			message: models.Message{MsgID: otherMsg.MsgID},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.queue.EXPECT().GetMessage(testCtx, lookup(otherMsg)).Return(models.Message{}, models.NotFound)
+				f.repo.EXPECT().GetMessage(testCtx, lookup(otherMsg)).
+					Return(models.Message{MsgID: otherMsg.MsgID, ChatID: uuid.New()}, models.OK)
+			},
+			status: models.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.DeleteMessage(testCtx, testChat, testIssuer, tt.message); status != tt.status {
+				t.Errorf("DeleteMessage() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChatUseCase_UpdateChatPhotoURL(t *testing.T) {
+	type fields struct {
+		repo   *chat.MockChatRepo
+		queue  *chat.MockQueueRepo
+		events *chat.MockEventBus
+	}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testCtx := context.Background()
+	testIssuer := models.User{ID: uuid.New()}
+	testChat := models.Chat{ChatID: uuid.New()}
+	testPhotoURL := "photo.png"
+	groupChat := models.Chat{ChatID: testChat.ChatID, Kind: models.GroupChat}
+
+	tests := []struct {
+		name   string
+		fields fields
+		pre    func(f *fields)
+		status models.StatusCode
+	}{
+		{
+			name: "photo is changed, the participants are told",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.AdminRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(groupChat, models.OK)
+				f.repo.EXPECT().UpdateChatPhotoURL(testCtx, testChat, testPhotoURL).Return(models.OK)
+				f.queue.EXPECT().NextMessageSeq(testCtx, seq.Key(testChat.ChatID)).Return(int64(12), nil)
+				f.queue.EXPECT().SaveMessage(testCtx, gomock.Cond(func(x any) bool {
+					msg := x.(models.Message)
+					body := models.SystemMessageBody{}
+					if err := json.Unmarshal([]byte(msg.Payload), &body); err != nil {
+						return false
+					}
+					return msg.ChatID == testChat.ChatID && msg.SenderID == testIssuer.ID &&
+						msg.Kind == models.PhotoChangedMessage && msg.Seq == 12 && body.PhotoURL == testPhotoURL
+				})).Return(models.OK)
+				f.events.EXPECT().PublishChatNotification(testCtx, testChat, gomock.Cond(func(x any) bool {
+					notification := x.(models.Notification)
+					msg, ok := notification.Body.(*models.Message)
+					return notification.Type == models.ChatMessage && ok && msg.Kind == models.PhotoChangedMessage
+				})).Return(models.OK)
+			},
+			status: models.OK,
+		},
+		{
+			name: "failed to save the system message",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).Return(groupChat, models.OK)
+				f.repo.EXPECT().UpdateChatPhotoURL(testCtx, testChat, testPhotoURL).Return(models.OK)
+				f.queue.EXPECT().NextMessageSeq(testCtx, seq.Key(testChat.ChatID)).Return(int64(12), nil)
+				f.queue.EXPECT().SaveMessage(testCtx, gomock.Any()).Return(models.InternalError)
+			},
+			status: models.OK,
+		},
+		{
+			name: "members may not change the photo",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.MemberRole, models.OK)
+			},
+			status: models.Forbidden,
+		},
+		{
+			name: "direct chat has no photo of its own",
+			fields: fields{
+				repo:   chat.NewMockChatRepo(ctrl),
+				queue:  chat.NewMockQueueRepo(ctrl),
+				events: chat.NewMockEventBus(ctrl),
+			},
+			pre: func(f *fields) {
+				f.repo.EXPECT().GetParticipantRole(testCtx, testChat, testIssuer).Return(models.OwnerRole, models.OK)
+				f.repo.EXPECT().GetChat(testCtx, testChat, testIssuer).
+					Return(models.Chat{ChatID: testChat.ChatID, Kind: models.DirectChat}, models.OK)
+			},
+			status: models.Forbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &ChatUseCase{
+				repo:   tt.fields.repo,
+				queue:  tt.fields.queue,
+				events: tt.fields.events,
+			}
+			tt.pre(&tt.fields)
+			if status := ch.UpdateChatPhotoURL(testCtx, testChat, testIssuer, testPhotoURL); status != tt.status {
+				t.Errorf("UpdateChatPhotoURL() error = %v, status %v", status, tt.status)
+			}
+		})
+	}
+}
+
+// expectSystemMessage expects a system message of the kind sent by the sender to be queued and broadcast.
+func expectSystemMessage(queue *chat.MockQueueRepo, events *chat.MockEventBus,
+	sender models.User, kind models.MessageKind) {
+	queue.EXPECT().NextMessageSeq(gomock.Any(), gomock.Any()).Return(int64(8), nil)
+	queue.EXPECT().SaveMessage(gomock.Any(), gomock.Cond(func(x any) bool {
+		msg := x.(models.Message)
+		return msg.SenderID == sender.ID && msg.Kind == kind && msg.Seq == 8
+	})).Return(models.OK)
+	events.EXPECT().PublishChatNotification(gomock.Any(), gomock.Any(), gomock.Cond(func(x any) bool {
+		notification := x.(models.Notification)
+		msg, ok := notification.Body.(*models.Message)
+		return notification.Type == models.ChatMessage && ok && msg.Kind == kind
+	})).Return(models.OK)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"time"
+
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/tracing"
+)
+
+// Config is the configuration of flusher service.
+type Config struct {
+	Port   int           `config:"port" env:"FLUSHER_PORT" required:"true" min:"1" max:"65535" usage:"port of the control API"`
+	Period time.Duration `config:"period" env:"FLUSHER_PERIOD" required:"true" min:"1s" usage:"period of the flushes"`
+	// Threshold is the backlog size that triggers a flush before the period ends, 0 disables it.
+	Threshold   int64           `config:"threshold" env:"FLUSHER_THRESHOLD" default:"1000" min:"0" usage:"backlog size that triggers a flush, 0 disables it"`
+	DatabaseURL string          `config:"database_url" env:"DATABASE_URL" required:"true" secret:"true"`
+	Redis       config.Redis    `config:"redis"`
+	Tracing     tracing.Config  `config:"tracing"`
+	Shutdown    graceful.Config `config:"shutdown"`
+	// AdminToken guards the endpoints flushing and managing the dead letters, they are refused without it.
+	AdminToken string `config:"admin_token" env:"FLUSHER_ADMIN_TOKEN" secret:"true" usage:"bearer token of the admin endpoints of the control API"`
+	// BatchSize bounds the messages read from redis and persisted by one transaction.
+	BatchSize int `config:"batch_size" env:"FLUSHER_BATCH_SIZE" default:"1000" min:"1" usage:"most messages persisted by one transaction"`
+}
@@ -2,105 +2,94 @@ package main
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/exp/slog"
 	"log"
 	"os"
 	"our-little-chatik/internal/flusher/internal/delivery"
 	"our-little-chatik/internal/flusher/internal/repo"
-	"time"
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/health"
+	"our-little-chatik/internal/pkg/metrics"
+	"our-little-chatik/internal/pkg/tracing"
+	"strconv"
 
 	"github.com/jackc/pgx/v5"
 )
 
-type PostgresConfig struct {
-	URI      string
-	Username string
-	Password string
-}
-
-type PeerDBConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-}
-
-type AppConfig struct {
-	Port   string
-	DB     PostgresConfig
-	Redis  PeerDBConfig
-	Period time.Duration
-}
-
-func GetConnectionString() (string, error) {
-	key, ok := os.LookupEnv("DATABASE_URL")
-	if !ok {
-		return "", errors.New("connection string not found")
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
 	}
-	return key, nil
 }
 
-func main() {
-	redisHost := os.Getenv("REDIS_HOST")
-	if redisHost == "" {
-		panic("empty redis host")
-	}
-	redisPort := os.Getenv("REDIS_PORT")
-	if redisPort == "" {
-		panic("empty redis port")
-	}
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	if redisPassword == "" {
-		panic("empty redis password")
-	}
-	flusherPort := os.Getenv("FLUSHER_PORT")
-	if flusherPort == "" {
-		panic("empty flusher port")
-	}
-	flusherPeriod := os.Getenv("FLUSHER_PERIOD")
-	if flusherPeriod == "" {
-		panic("empty flusher period")
-	}
+func run() error {
+	cfg := Config{}
+	config.MustLoad("flusher", &cfg)
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 
-	period, err := time.ParseDuration(flusherPeriod)
+	shutdownTracing, err := tracing.Init(context.Background(), "flusher", cfg.Tracing)
 	if err != nil {
-		panic(err.Error())
+		panic(err)
 	}
-
-	appConfig := AppConfig{}
-	appConfig.Port = flusherPort
-	appConfig.Redis.Host = redisHost
-	appConfig.Redis.Port = redisPort
-	appConfig.Redis.Password = redisPassword
-	appConfig.Period = period
-
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	defer shutdownTracing(context.Background())
 
 	redisClient := redis.NewClient(&redis.Options{
-		Addr:     appConfig.Redis.Host + ":" + appConfig.Redis.Port,
-		Password: appConfig.Redis.Password,
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
 	})
 
 	ctx := context.Background()
-	connStr, err := GetConnectionString()
+	conn, err := pgx.Connect(ctx, cfg.DatabaseURL)
 	if err != nil {
-		log.Fatal(err)
+		panic(err)
 	}
+	peristRepo := repo.NewPostgresRepo(cfg.DatabaseURL, conn)
+	queueRepo := repo.NewRedisRepo(redisClient)
 
-	conn, err := pgx.Connect(ctx, connStr)
+	moved, err := queueRepo.MigrateLegacyMessages(ctx, peristRepo.GetLastSeq)
 	if err != nil {
 		panic(err)
 	}
-	peristRepo := repo.NewPostgresRepo(conn)
-	queueRepo := repo.NewRedisRepo(redisClient)
+	slog.Info("migrated legacy message keys", "messages", moved)
+
+	daemon := delivery.NewFlusherD(queueRepo, peristRepo, cfg.Threshold, cfg.BatchSize)
+	controlHandler := delivery.NewControlHandler(daemon, queueRepo)
+
+	e := echo.New()
+	e.Use(middleware.Recover())
+	e.Use(metrics.EchoMiddleware())
+	e.Use(tracing.EchoMiddleware())
+	e.GET(metrics.Path, echo.WrapHandler(metrics.Handler()))
+	e.GET("/status", controlHandler.Status)
+	// the dead letters hold the messages of the users, so they are managed by the admins only
+	admin := e.Group("", delivery.AdminAuth(cfg.AdminToken))
+	admin.POST("/flush", controlHandler.Flush)
+	admin.GET("/dead-letters", controlHandler.GetDeadLetters)
+	admin.POST("/dead-letters/replay", controlHandler.ReplayDeadLetters)
+	admin.DELETE("/dead-letters", controlHandler.PurgeDeadLetters)
+	checker := health.NewChecker()
+	checker.Add("postgres", peristRepo.Ping)
+	checker.Add("redis", health.Redis(redisClient))
+	e.GET(health.LivePath, echo.WrapHandler(health.LiveHandler()))
+	e.GET(health.ReadyPath, echo.WrapHandler(checker))
 
-	daemon := delivery.NewFlusherD(queueRepo, peristRepo)
+	ctx, stop := graceful.NotifyContext(context.Background())
+	defer stop()
+	// The loop also stops if the control API fails, its last flush is waited for before the exit.
+	workCtx, cancel := context.WithCancel(ctx)
+	worked := make(chan struct{})
+	go func() {
+		daemon.Work(workCtx, cfg.Period)
+		close(worked)
+	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	fmt.Println(appConfig.Period)
-	daemon.Work(ctx, appConfig.Period)
+	err = graceful.Run(ctx, cfg.Shutdown.Timeout, graceful.Echo("http", e, ":"+strconv.Itoa(cfg.Port)))
+	cancel()
+	<-worked
+	return err
 }
@@ -0,0 +1,29 @@
+package delivery
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"our-little-chatik/internal/pkg"
+)
+
+// AdminAuth lets through the requests bearing the admin token in the Authorization header.
+// The admin endpoints are refused altogether while no token is configured.
+func AdminAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if token == "" {
+				return pkg.ForbiddenResponse(c, errors.New("admin token is not configured"))
+			}
+			scheme, given, found := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") ||
+				subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				return pkg.UnauthorizedResponse(c, errors.New("admin token is required"))
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestAdminAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		wantCode      int
+	}{
+		{
+			name:          "valid token",
+			token:         "secret",
+			authorization: "Bearer secret",
+			wantCode:      http.StatusOK,
+		},
+		{
+			name:     "no token",
+			token:    "secret",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "wrong token",
+			token:         "secret",
+			authorization: "Bearer guess",
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "token is not configured",
+			authorization: "Bearer ",
+			wantCode:      http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/dead-letters", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler := AdminAuth(tt.token)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			err := handler(e.NewContext(req, rec))
+			if err != nil {
+				t.Fatalf("AdminAuth() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("AdminAuth() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -2,39 +2,220 @@ package delivery
 
 import (
 	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slog"
 	"log"
+	"sync"
 	"time"
 
 	"our-little-chatik/internal/flusher/internal"
+	models2 "our-little-chatik/internal/flusher/internal/models"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/tracing"
 )
 
+var tracer = otel.Tracer("our-little-chatik/internal/flusher/internal/delivery")
+
+// backlogCheckPeriod is how often the backlog is compared with the threshold.
+const backlogCheckPeriod = 5 * time.Second
+
+// Status describes the backlog and the outcome of the last flush.
+type Status struct {
+	Backlog     int64  `json:"backlog"`
+	LastFlushAt int64  `json:"last_flush_at,omitempty"`
+	LastFlushed int    `json:"last_flushed"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
 type FlusherD struct {
 	queueRepo      internal.QueueRepo
 	persistantRepo internal.PersistantRepo
+	// threshold is the backlog size that triggers a flush before the period ends, 0 disables it.
+	threshold int64
+	// batchSize is the most messages persisted by one transaction.
+	batchSize int
+
+	// flushMu serializes the flushes triggered by the period, the threshold and the API.
+	flushMu  sync.Mutex
+	statusMu sync.RWMutex
+	status   Status
 }
 
-func NewFlusherD(queueRepo internal.QueueRepo, persistantRepo internal.PersistantRepo) *FlusherD {
-	return &FlusherD{queueRepo: queueRepo, persistantRepo: persistantRepo}
+func NewFlusherD(queueRepo internal.QueueRepo, persistantRepo internal.PersistantRepo,
+	threshold int64, batchSize int) *FlusherD {
+	return &FlusherD{queueRepo: queueRepo, persistantRepo: persistantRepo, threshold: threshold, batchSize: batchSize}
 }
 
+// Work flushes the messages every period and whenever the backlog exceeds the threshold.
+// Once the context is cancelled it flushes the messages queued so far and returns.
 func (d *FlusherD) Work(ctx context.Context, period time.Duration) {
-	ticker := time.NewTicker(time.Minute * period)
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	backlogTicker := time.NewTicker(backlogCheckPeriod)
+	defer backlogTicker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			messages, err := d.queueRepo.FetchAllMessages()
+			_, err := d.Flush(ctx)
+			if err != nil {
+				log.Println(err)
+			}
+		case <-backlogTicker.C:
+			backlog, err := d.queueRepo.BacklogSize()
 			if err != nil {
+				log.Println(err)
 				continue
 			}
-			err = d.persistantRepo.PersistAllMessages(messages)
-			slog.Info("persisted", messages)
+			backlogSize.Set(float64(backlog))
+			if d.threshold <= 0 || backlog < d.threshold {
+				continue
+			}
+			slog.Info("backlog exceeded the threshold", "backlog", backlog)
+			_, err = d.Flush(ctx)
 			if err != nil {
 				log.Println(err)
 			}
 		case <-ctx.Done():
 			log.Println("work loop ended")
+			// the context of the loop is done already, the last flush must not be cancelled with it
+			flushed, err := d.Flush(context.Background())
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			slog.Info("final flush", "messages", flushed)
 			return
 		}
 	}
 }
+
+// Flush persists the queued messages right away and returns the number of the flushed messages.
+func (d *FlusherD) Flush(ctx context.Context) (int, error) {
+	d.flushMu.Lock()
+	defer d.flushMu.Unlock()
+
+	ctx, span := tracer.Start(ctx, "flush")
+	defer span.End()
+
+	start := time.Now()
+	flushed, err := d.flush(ctx)
+	flushDuration.Observe(time.Since(start).Seconds())
+	flushedMessages.Add(float64(flushed))
+	span.SetAttributes(attribute.Int("messages.flushed", flushed))
+	if err != nil {
+		failedFlushes.Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+	d.status.LastFlushAt = time.Now().Unix()
+	d.status.LastFlushed = flushed
+	d.status.LastError = ""
+	if err != nil {
+		d.status.LastError = err.Error()
+	}
+	return flushed, err
+}
+
+// Status returns the current backlog size along with the outcome of the last flush.
+func (d *FlusherD) Status() (Status, error) {
+	backlog, err := d.queueRepo.BacklogSize()
+	if err != nil {
+		return Status{}, err
+	}
+	backlogSize.Set(float64(backlog))
+	d.statusMu.RLock()
+	defer d.statusMu.RUnlock()
+	status := d.status
+	status.Backlog = backlog
+	return status, nil
+}
+
+// flush persists the queued messages batch by batch till a batch is not full.
+func (d *FlusherD) flush(ctx context.Context) (int, error) {
+	flushed := 0
+	for {
+		batchFlushed, full, err := d.flushBatch(ctx)
+		flushed += batchFlushed
+		if err != nil || !full {
+			return flushed, err
+		}
+	}
+}
+
+// flushBatch persists a batch of the queued messages and removes them from the queue only after
+// they are committed, so that the messages of a failed flush are retried by the next one.
+// The messages rejected by the database are moved to the dead letters.
+func (d *FlusherD) flushBatch(ctx context.Context) (int, bool, error) {
+	queued, err := d.queueRepo.ClaimMessages(d.batchSize)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(queued) == 0 {
+		return 0, false, nil
+	}
+	messages := make([]models.Message, 0, len(queued))
+	members := make(map[uuid.UUID]string, len(queued))
+	for _, msg := range queued {
+		messages = append(messages, msg.Message)
+		members[msg.Message.MsgID] = msg.Member
+	}
+	rejected, err := d.persist(ctx, messages)
+	if err != nil {
+		if releaseErr := d.queueRepo.ReleaseMessages(queued); releaseErr != nil {
+			slog.Error("failed to release the claimed messages", "error", releaseErr.Error())
+		}
+		return 0, false, err
+	}
+	// The rejected messages are stored before the rest are acknowledged, so they are not lost meanwhile.
+	if len(rejected) != 0 {
+		slog.Warn("messages are rejected by the database", "messages", len(rejected))
+		for i := range rejected {
+			rejected[i].Member = members[rejected[i].Message.MsgID]
+		}
+		err = d.queueRepo.DeadLetterMessages(rejected)
+		if err != nil {
+			return 0, false, err
+		}
+		rejectedMessages.Add(float64(len(rejected)))
+	}
+	flushed := len(messages) - len(rejected)
+	slog.Info("persisted", "messages", flushed)
+	err = d.queueRepo.AckMessages(queued)
+	if err != nil {
+		return 0, false, err
+	}
+	return flushed, len(queued) == d.batchSize, nil
+}
+
+// persist persists the messages within a span linked to the traces of their senders,
+// so that the path of a message can be followed from the peer to the database.
+func (d *FlusherD) persist(ctx context.Context, messages []models.Message) ([]models2.RejectedMessage, error) {
+	links := make([]trace.Link, 0, len(messages))
+	for _, msg := range messages {
+		if link, ok := tracing.Link(msg.Trace); ok {
+			links = append(links, link)
+		}
+	}
+	_, span := tracer.Start(ctx, "persist_messages",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithLinks(links...),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, attribute.Int("messages.count", len(messages))))
+	defer span.End()
+
+	rejected, err := d.persistantRepo.PersistAllMessages(messages)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("messages.rejected", len(rejected)))
+	return rejected, nil
+}
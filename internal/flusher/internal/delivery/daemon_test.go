@@ -2,14 +2,18 @@ package delivery
 
 import (
 	"context"
+	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/mock/gomock"
 	"our-little-chatik/internal/flusher/internal/mocks/flusher"
+	models2 "our-little-chatik/internal/flusher/internal/models"
 	"our-little-chatik/internal/models"
 	"testing"
 	"time"
 )
 
+const testBatchSize = 10
+
 func TestFlusherD_Work(t *testing.T) {
 	type fields struct {
 		queueRepo      *flusher.MockQueueRepo
@@ -26,9 +30,12 @@ func TestFlusherD_Work(t *testing.T) {
 	testMsg := models.Message{
 		MsgID: uuid.New(),
 	}
+	testQueued := []models2.QueuedMessage{{Message: testMsg, Member: "queued message"}}
 
 	testCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	stoppedCtx, stop := context.WithCancel(context.Background())
+	stop()
 
 	tests := []struct {
 		name    string
@@ -39,22 +46,51 @@ func TestFlusherD_Work(t *testing.T) {
 		{
 			name: "success",
 			prepare: func(f *fields) {
-				f.queueRepo.EXPECT().FetchAllMessages().
-					Return([]models.Message{testMsg}, nil)
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).
+					Return(testQueued, nil)
 				f.persistantRepo.EXPECT().PersistAllMessages([]models.Message{testMsg}).
+					Return(nil, nil)
+				f.queueRepo.EXPECT().AckMessages(testQueued).
 					Return(nil)
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).
+					Return([]models2.QueuedMessage{}, nil).AnyTimes()
+			},
+			fields: fields{
+				queueRepo:      flusher.NewMockQueueRepo(ctrl),
+				persistantRepo: flusher.NewMockPersistantRepo(ctrl),
 			},
 			args: args{
 				ctx:    testCtx,
 				period: time.Millisecond,
 			},
 		},
+		{
+			name: "final flush on shutdown",
+			prepare: func(f *fields) {
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).
+					Return(testQueued, nil)
+				f.persistantRepo.EXPECT().PersistAllMessages([]models.Message{testMsg}).
+					Return(nil, nil)
+				f.queueRepo.EXPECT().AckMessages(testQueued).
+					Return(nil)
+			},
+			fields: fields{
+				queueRepo:      flusher.NewMockQueueRepo(ctrl),
+				persistantRepo: flusher.NewMockPersistantRepo(ctrl),
+			},
+			args: args{
+				ctx:    stoppedCtx,
+				period: time.Hour,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			tt.prepare(&tt.fields)
 			d := &FlusherD{
 				queueRepo:      tt.fields.queueRepo,
 				persistantRepo: tt.fields.persistantRepo,
+				batchSize:      testBatchSize,
 			}
 			go func() {
 				time.Sleep(time.Millisecond * 10)
@@ -64,3 +100,135 @@ func TestFlusherD_Work(t *testing.T) {
 		})
 	}
 }
+
+func TestFlusherD_flush(t *testing.T) {
+	type fields struct {
+		queueRepo      *flusher.MockQueueRepo
+		persistantRepo *flusher.MockPersistantRepo
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testMsgs := []models.Message{
+		{
+			MsgID: uuid.New(),
+			Seq:   1,
+		},
+	}
+	testQueued := []models2.QueuedMessage{{Message: testMsgs[0], Member: "queued message"}}
+
+	tests := []struct {
+		name      string
+		batchSize int
+		prepare   func(f *fields)
+		want      int
+		wantErr   bool
+	}{
+		{
+			name:      "persisted messages are acknowledged",
+			batchSize: testBatchSize,
+			prepare: func(f *fields) {
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).Return(testQueued, nil)
+				f.persistantRepo.EXPECT().PersistAllMessages(testMsgs).Return(nil, nil)
+				f.queueRepo.EXPECT().AckMessages(testQueued).Return(nil)
+			},
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:      "rejected messages are dead lettered by their queue entries",
+			batchSize: testBatchSize,
+			prepare: func(f *fields) {
+				rejected := []models2.RejectedMessage{{Message: testMsgs[0], Reason: "chat is deleted"}}
+				deadLettered := []models2.RejectedMessage{{Message: testMsgs[0], Reason: "chat is deleted",
+					Member: "queued message"}}
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).Return(testQueued, nil)
+				f.persistantRepo.EXPECT().PersistAllMessages(testMsgs).Return(rejected, nil)
+				f.queueRepo.EXPECT().DeadLetterMessages(deadLettered).Return(nil)
+				f.queueRepo.EXPECT().AckMessages(testQueued).Return(nil)
+			},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:      "failed messages stay in the queue and their chats are released",
+			batchSize: testBatchSize,
+			prepare: func(f *fields) {
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).Return(testQueued, nil)
+				f.persistantRepo.EXPECT().PersistAllMessages(testMsgs).Return(nil, fmt.Errorf("db is down"))
+				f.queueRepo.EXPECT().ReleaseMessages(testQueued).Return(nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:      "full batches are flushed till the queue is drained",
+			batchSize: 1,
+			prepare: func(f *fields) {
+				f.queueRepo.EXPECT().ClaimMessages(1).Return(testQueued, nil)
+				f.persistantRepo.EXPECT().PersistAllMessages(testMsgs).Return(nil, nil)
+				f.queueRepo.EXPECT().AckMessages(testQueued).Return(nil)
+				f.queueRepo.EXPECT().ClaimMessages(1).Return([]models2.QueuedMessage{}, nil)
+			},
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:      "empty queue",
+			batchSize: testBatchSize,
+			prepare: func(f *fields) {
+				f.queueRepo.EXPECT().ClaimMessages(testBatchSize).Return([]models2.QueuedMessage{}, nil)
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fields{
+				queueRepo:      flusher.NewMockQueueRepo(ctrl),
+				persistantRepo: flusher.NewMockPersistantRepo(ctrl),
+			}
+			tt.prepare(&f)
+			d := &FlusherD{
+				queueRepo:      f.queueRepo,
+				persistantRepo: f.persistantRepo,
+				batchSize:      tt.batchSize,
+			}
+			got, err := d.flush(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("flush() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("flush() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlusherD_Status(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queueRepo := flusher.NewMockQueueRepo(ctrl)
+	persistantRepo := flusher.NewMockPersistantRepo(ctrl)
+	d := NewFlusherD(queueRepo, persistantRepo, 0, testBatchSize)
+
+	testMsgs := []models.Message{{MsgID: uuid.New(), Seq: 1}}
+	testQueued := []models2.QueuedMessage{{Message: testMsgs[0], Member: "queued message"}}
+	queueRepo.EXPECT().ClaimMessages(testBatchSize).Return(testQueued, nil)
+	persistantRepo.EXPECT().PersistAllMessages(testMsgs).Return(nil, fmt.Errorf("db is down"))
+	queueRepo.EXPECT().ReleaseMessages(testQueued).Return(nil)
+	queueRepo.EXPECT().BacklogSize().Return(int64(1), nil)
+
+	if _, err := d.Flush(context.Background()); err == nil {
+		t.Fatalf("Flush() expected an error")
+	}
+	status, err := d.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Backlog != 1 || status.LastError != "db is down" || status.LastFlushAt == 0 {
+		t.Errorf("Status() got = %+v", status)
+	}
+}
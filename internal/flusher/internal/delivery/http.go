@@ -0,0 +1,134 @@
+package delivery
+
+import (
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"our-little-chatik/internal/flusher/internal"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg"
+)
+
+// ControlHandler exposes the flusher over HTTP, so that it can be flushed on demand and monitored.
+type ControlHandler struct {
+	daemon      *FlusherD
+	deadLetters internal.DeadLetterRepo
+}
+
+func NewControlHandler(daemon *FlusherD, deadLetters internal.DeadLetterRepo) *ControlHandler {
+	return &ControlHandler{daemon: daemon, deadLetters: deadLetters}
+}
+
+// Flush godoc
+// @Summary Flush the queued messages.
+// @Description flush the queued messages to the database right away.
+// @Produce json
+// @Tags flusher
+// @Success 200 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /flush [post]
+func (h *ControlHandler) Flush(c echo.Context) error {
+	flushed, err := h.daemon.Flush(c.Request().Context())
+	if err != nil {
+		return pkg.ServerErrorResponse(c, err)
+	}
+	response := models.EnvelopIntoHttpResponse(flushed, "flushed", http.StatusOK)
+	return c.JSON(http.StatusOK, &response)
+}
+
+// Status godoc
+// @Summary Get flusher status.
+// @Description get the backlog size and the outcome of the last flush.
+// @Produce json
+// @Tags flusher
+// @Success 200 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /status [get]
+func (h *ControlHandler) Status(c echo.Context) error {
+	status, err := h.daemon.Status()
+	if err != nil {
+		return pkg.ServerErrorResponse(c, err)
+	}
+	response := models.EnvelopIntoHttpResponse(status, "status", http.StatusOK)
+	return c.JSON(http.StatusOK, &response)
+}
+
+// GetDeadLetters godoc
+// @Summary Get dead letters.
+// @Description get the messages the flusher failed to persist along with the reasons.
+// @Produce json
+// @Tags flusher
+// @Success 200 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /dead-letters [get]
+func (h *ControlHandler) GetDeadLetters(c echo.Context) error {
+	deadLetters, err := h.deadLetters.GetDeadLetters()
+	if err != nil {
+		return pkg.ServerErrorResponse(c, err)
+	}
+	response := models.EnvelopIntoHttpResponse(deadLetters, "dead_letters", http.StatusOK)
+	return c.JSON(http.StatusOK, &response)
+}
+
+// ReplayDeadLetters godoc
+// @Summary Replay dead letters.
+// @Description put the dead letters back to the queue, all of them unless ids are given.
+// @Param id query string false "Dead letter ID"
+// @Produce json
+// @Tags flusher
+// @Success 200 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /dead-letters/replay [post]
+func (h *ControlHandler) ReplayDeadLetters(c echo.Context) error {
+	ids, ok := parseDeadLetterIDs(c)
+	if !ok {
+		return pkg.FailedValidationResponse(c, map[string]string{"id": "must be a valid uuid"})
+	}
+	replayed, err := h.deadLetters.ReplayDeadLetters(ids)
+	if err != nil {
+		return pkg.ServerErrorResponse(c, err)
+	}
+	response := models.EnvelopIntoHttpResponse(replayed, "replayed", http.StatusOK)
+	return c.JSON(http.StatusOK, &response)
+}
+
+// PurgeDeadLetters godoc
+// @Summary Purge dead letters.
+// @Description remove the dead letters for good, all of them unless ids are given.
+// @Param id query string false "Dead letter ID"
+// @Produce json
+// @Tags flusher
+// @Success 200 {object} models.HttpResponse
+// @Failure 422 {object} models.HttpResponse
+// @Failure 500 {object} models.HttpResponse
+// @Router /dead-letters [delete]
+func (h *ControlHandler) PurgeDeadLetters(c echo.Context) error {
+	ids, ok := parseDeadLetterIDs(c)
+	if !ok {
+		return pkg.FailedValidationResponse(c, map[string]string{"id": "must be a valid uuid"})
+	}
+	purged, err := h.deadLetters.PurgeDeadLetters(ids)
+	if err != nil {
+		return pkg.ServerErrorResponse(c, err)
+	}
+	response := models.EnvelopIntoHttpResponse(purged, "purged", http.StatusOK)
+	return c.JSON(http.StatusOK, &response)
+}
+
+// parseDeadLetterIDs parses the id query params, nil stands for all the dead letters.
+func parseDeadLetterIDs(c echo.Context) ([]uuid.UUID, bool) {
+	params := c.QueryParams()["id"]
+	if len(params) == 0 {
+		return nil, true
+	}
+	ids := make([]uuid.UUID, 0, len(params))
+	for _, param := range params {
+		id, err := uuid.Parse(param)
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
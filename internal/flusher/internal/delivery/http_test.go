@@ -0,0 +1,75 @@
+package delivery
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"our-little-chatik/internal/flusher/internal/mocks/flusher"
+	"testing"
+)
+
+func TestControlHandler_ReplayDeadLetters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testID := uuid.New()
+
+	tests := []struct {
+		name     string
+		query    string
+		prepare  func(deadLetters *flusher.MockDeadLetterRepo)
+		wantCode int
+	}{
+		{
+			name:  "all dead letters",
+			query: "",
+			prepare: func(deadLetters *flusher.MockDeadLetterRepo) {
+				deadLetters.EXPECT().ReplayDeadLetters(nil).Return(2, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:  "given dead letter",
+			query: "?id=" + testID.String(),
+			prepare: func(deadLetters *flusher.MockDeadLetterRepo) {
+				deadLetters.EXPECT().ReplayDeadLetters([]uuid.UUID{testID}).Return(1, nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "invalid id",
+			query:    "?id=1",
+			prepare:  func(deadLetters *flusher.MockDeadLetterRepo) {},
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:  "redis failure",
+			query: "",
+			prepare: func(deadLetters *flusher.MockDeadLetterRepo) {
+				deadLetters.EXPECT().ReplayDeadLetters(nil).Return(0, fmt.Errorf("down"))
+			},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadLetters := flusher.NewMockDeadLetterRepo(ctrl)
+			tt.prepare(deadLetters)
+			h := NewControlHandler(nil, deadLetters)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/dead-letters/replay"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			err := h.ReplayDeadLetters(e.NewContext(req, rec))
+			if err != nil {
+				t.Fatalf("ReplayDeadLetters() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("ReplayDeadLetters() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package delivery
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	backlogSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "flusher_backlog_messages",
+		Help: "Number of the messages waiting in redis to be persisted.",
+	})
+	flushDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "flusher_flush_duration_seconds",
+		Help:    "Time spent flushing the messages to the database.",
+		Buckets: prometheus.DefBuckets,
+	})
+	flushedMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "flusher_flushed_messages_total",
+		Help: "Number of the messages persisted to the database.",
+	})
+	rejectedMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "flusher_rejected_messages_total",
+		Help: "Number of the messages moved to the dead letters.",
+	})
+	failedFlushes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "flusher_failed_flushes_total",
+		Help: "Number of the flushes failed as a whole.",
+	})
+)
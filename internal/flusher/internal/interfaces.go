@@ -1,11 +1,34 @@
 package internal
 
-import "our-little-chatik/internal/models"
+import (
+	"github.com/google/uuid"
+	models2 "our-little-chatik/internal/flusher/internal/models"
+	"our-little-chatik/internal/models"
+)
 
 type QueueRepo interface {
-	FetchAllMessages() ([]models.Message, error)
+	// ClaimMessages claims up to limit messages waiting to be persisted. The messages stay
+	// in the queue till they are acknowledged with AckMessages, and their chats are not claimed
+	// by the other flushes till then.
+	ClaimMessages(limit int) ([]models2.QueuedMessage, error)
+	// AckMessages removes the persisted messages from the queue and releases the claims of their chats.
+	AckMessages(msgs []models2.QueuedMessage) error
+	// ReleaseMessages releases the claims of the chats of the messages, leaving the messages in the queue.
+	ReleaseMessages(msgs []models2.QueuedMessage) error
+	// BacklogSize returns the number of the messages waiting to be persisted.
+	BacklogSize() (int64, error)
+	// DeadLetterMessages stores the rejected messages as dead letters and removes them from the queue.
+	DeadLetterMessages(rejected []models2.RejectedMessage) error
 }
 
 type PersistantRepo interface {
-	PersistAllMessages(msgs []models.Message) error
+	// PersistAllMessages returns the messages the database rejected, the rest are persisted.
+	PersistAllMessages(msgs []models.Message) ([]models2.RejectedMessage, error)
+}
+
+// DeadLetterRepo manages the dead letters, nil ids stand for all of them.
+type DeadLetterRepo interface {
+	GetDeadLetters() ([]models2.DeadLetter, error)
+	ReplayDeadLetters(ids []uuid.UUID) (int, error)
+	PurgeDeadLetters(ids []uuid.UUID) (int, error)
 }
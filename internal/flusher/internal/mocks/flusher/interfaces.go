@@ -9,9 +9,11 @@
 package flusher
 
 import (
-	models "our-little-chatik/internal/models"
+	models "our-little-chatik/internal/flusher/internal/models"
+	models0 "our-little-chatik/internal/models"
 	reflect "reflect"
 
+	uuid "github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -38,19 +40,76 @@ func (m *MockQueueRepo) EXPECT() *MockQueueRepoMockRecorder {
 	return m.recorder
 }
 
-// FetchAllMessages mocks base method.
-func (m *MockQueueRepo) FetchAllMessages() ([]models.Message, error) {
+// AckMessages mocks base method.
+func (m *MockQueueRepo) AckMessages(msgs []models.QueuedMessage) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FetchAllMessages")
-	ret0, _ := ret[0].([]models.Message)
+	ret := m.ctrl.Call(m, "AckMessages", msgs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AckMessages indicates an expected call of AckMessages.
+func (mr *MockQueueRepoMockRecorder) AckMessages(msgs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AckMessages", reflect.TypeOf((*MockQueueRepo)(nil).AckMessages), msgs)
+}
+
+// BacklogSize mocks base method.
+func (m *MockQueueRepo) BacklogSize() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BacklogSize")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BacklogSize indicates an expected call of BacklogSize.
+func (mr *MockQueueRepoMockRecorder) BacklogSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BacklogSize", reflect.TypeOf((*MockQueueRepo)(nil).BacklogSize))
+}
+
+// ClaimMessages mocks base method.
+func (m *MockQueueRepo) ClaimMessages(limit int) ([]models.QueuedMessage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimMessages", limit)
+	ret0, _ := ret[0].([]models.QueuedMessage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// FetchAllMessages indicates an expected call of FetchAllMessages.
-func (mr *MockQueueRepoMockRecorder) FetchAllMessages() *gomock.Call {
+// ClaimMessages indicates an expected call of ClaimMessages.
+func (mr *MockQueueRepoMockRecorder) ClaimMessages(limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimMessages", reflect.TypeOf((*MockQueueRepo)(nil).ClaimMessages), limit)
+}
+
+// DeadLetterMessages mocks base method.
+func (m *MockQueueRepo) DeadLetterMessages(rejected []models.RejectedMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeadLetterMessages", rejected)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeadLetterMessages indicates an expected call of DeadLetterMessages.
+func (mr *MockQueueRepoMockRecorder) DeadLetterMessages(rejected any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeadLetterMessages", reflect.TypeOf((*MockQueueRepo)(nil).DeadLetterMessages), rejected)
+}
+
+// ReleaseMessages mocks base method.
+func (m *MockQueueRepo) ReleaseMessages(msgs []models.QueuedMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseMessages", msgs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseMessages indicates an expected call of ReleaseMessages.
+func (mr *MockQueueRepoMockRecorder) ReleaseMessages(msgs any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchAllMessages", reflect.TypeOf((*MockQueueRepo)(nil).FetchAllMessages))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseMessages", reflect.TypeOf((*MockQueueRepo)(nil).ReleaseMessages), msgs)
 }
 
 // MockPersistantRepo is a mock of PersistantRepo interface.
@@ -77,11 +136,12 @@ func (m *MockPersistantRepo) EXPECT() *MockPersistantRepoMockRecorder {
 }
 
 // PersistAllMessages mocks base method.
-func (m *MockPersistantRepo) PersistAllMessages(msgs []models.Message) error {
+func (m *MockPersistantRepo) PersistAllMessages(msgs []models0.Message) ([]models.RejectedMessage, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "PersistAllMessages", msgs)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]models.RejectedMessage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // PersistAllMessages indicates an expected call of PersistAllMessages.
@@ -89,3 +149,71 @@ func (mr *MockPersistantRepoMockRecorder) PersistAllMessages(msgs any) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PersistAllMessages", reflect.TypeOf((*MockPersistantRepo)(nil).PersistAllMessages), msgs)
 }
+
+// MockDeadLetterRepo is a mock of DeadLetterRepo interface.
+type MockDeadLetterRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeadLetterRepoMockRecorder
+}
+
+// MockDeadLetterRepoMockRecorder is the mock recorder for MockDeadLetterRepo.
+type MockDeadLetterRepoMockRecorder struct {
+	mock *MockDeadLetterRepo
+}
+
+// NewMockDeadLetterRepo creates a new mock instance.
+func NewMockDeadLetterRepo(ctrl *gomock.Controller) *MockDeadLetterRepo {
+	mock := &MockDeadLetterRepo{ctrl: ctrl}
+	mock.recorder = &MockDeadLetterRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeadLetterRepo) EXPECT() *MockDeadLetterRepoMockRecorder {
+	return m.recorder
+}
+
+// GetDeadLetters mocks base method.
+func (m *MockDeadLetterRepo) GetDeadLetters() ([]models.DeadLetter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeadLetters")
+	ret0, _ := ret[0].([]models.DeadLetter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeadLetters indicates an expected call of GetDeadLetters.
+func (mr *MockDeadLetterRepoMockRecorder) GetDeadLetters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeadLetters", reflect.TypeOf((*MockDeadLetterRepo)(nil).GetDeadLetters))
+}
+
+// PurgeDeadLetters mocks base method.
+func (m *MockDeadLetterRepo) PurgeDeadLetters(ids []uuid.UUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeadLetters", ids)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeadLetters indicates an expected call of PurgeDeadLetters.
+func (mr *MockDeadLetterRepoMockRecorder) PurgeDeadLetters(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeadLetters", reflect.TypeOf((*MockDeadLetterRepo)(nil).PurgeDeadLetters), ids)
+}
+
+// ReplayDeadLetters mocks base method.
+func (m *MockDeadLetterRepo) ReplayDeadLetters(ids []uuid.UUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayDeadLetters", ids)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayDeadLetters indicates an expected call of ReplayDeadLetters.
+func (mr *MockDeadLetterRepoMockRecorder) ReplayDeadLetters(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayDeadLetters", reflect.TypeOf((*MockDeadLetterRepo)(nil).ReplayDeadLetters), ids)
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"our-little-chatik/internal/models"
+)
+
+// DeadLettersKey is the redis HASH of the dead letters by their ids.
+const DeadLettersKey = "dead_letters"
+
+// DeadLetter is a queued message the flusher could not persist. Payload is the message as it
+// was stored in the queue, ChatID and Seq tell where it is put back when it is replayed.
+type DeadLetter struct {
+	ID       uuid.UUID `json:"id"`
+	ChatID   uuid.UUID `json:"chat_id"`
+	Seq      int64     `json:"seq"`
+	Payload  string    `json:"payload"`
+	Reason   string    `json:"reason"`
+	FailedAt int64     `json:"failed_at"`
+}
+
+// RejectedMessage is a message the database refused to store for a reason of its own,
+// e.g. the chat it belongs to has been deleted. Member is the entry of the message in the queue.
+type RejectedMessage struct {
+	Message models.Message
+	Reason  string
+	Member  string
+}
@@ -0,0 +1,14 @@
+package models
+
+import "our-little-chatik/internal/models"
+
+// ClaimKeyFormat is the redis key holding the claim of a flush on the queued messages of the chat,
+// the other flushes skip the chat till the claim is released or expires.
+const ClaimKeyFormat = "flushing_%s"
+
+// QueuedMessage is a message claimed from the queue. Member is the entry of the message
+// in the queue as it is stored there, the message is removed from the queue by it.
+type QueuedMessage struct {
+	Message models.Message
+	Member  string
+}
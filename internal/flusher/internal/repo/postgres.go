@@ -2,38 +2,294 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"sync"
 
+	models2 "our-little-chatik/internal/flusher/internal/models"
 	"our-little-chatik/internal/models"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/exp/slog"
 )
 
 const (
-	InsertMsgQuery = "INSERT INTO messages VALUES ($1, $2, $3, $4, $5)"
+	InsertMsgQuery = "INSERT INTO messages (msg_id, chat_id, sender_id, payload, created_at, seq, kind) VALUES ($1, $2, $3, $4, $5, $6, $7) " +
+		"ON CONFLICT (msg_id) DO NOTHING"
+	// The last message only moves forward, as the messages of the chat may be flushed out of order.
+	UpdateChatQuery = `UPDATE chats SET messages_count = messages_count + $2,
+    last_msg_id = CASE WHEN last_msg_seq < $3 THEN $4 ELSE last_msg_id END,
+    last_msg_seq = GREATEST(last_msg_seq, $3)
+    WHERE chat_id = $1`
+	GetLastSeqQuery = "SELECT COALESCE(MAX(seq), 0) FROM messages WHERE chat_id=$1"
+	// The chats are locked in the same order by every flush, the chat service locks the chat
+	// of the message it deletes, so a deletion either leaves its tombstone before the flush reads
+	// the tombstones or finds the message already persisted.
+	LockChatsQuery          = "SELECT chat_id FROM chats WHERE chat_id = ANY($1::uuid[]) ORDER BY chat_id FOR UPDATE"
+	GetDeletedMessagesQuery = "SELECT COALESCE(array_agg(msg_id::text), '{}') FROM deleted_messages WHERE msg_id = ANY($1::uuid[])"
 )
 
+// PostgresRepo works with a single connection, which is replaced by a new one once it breaks.
 type PostgresRepo struct {
+	url  string
+	mu   sync.Mutex
 	conn *pgx.Conn
 }
 
-func NewPostgresRepo(conn *pgx.Conn) *PostgresRepo {
-	return &PostgresRepo{conn: conn}
+func NewPostgresRepo(url string, conn *pgx.Conn) *PostgresRepo {
+	return &PostgresRepo{url: url, conn: conn}
 }
 
-func (pr PostgresRepo) PersistAllMessages(msgs []models.Message) error {
+// Ping checks the connection the flusher works with and reconnects if it is broken.
+// A connection busy with a flush is not waited for, the flush reports its own failure.
+func (pr *PostgresRepo) Ping(ctx context.Context) error {
+	if !pr.mu.TryLock() {
+		return nil
+	}
+	defer pr.mu.Unlock()
+	err := pr.conn.Ping(ctx)
+	if err == nil {
+		return nil
+	}
+	slog.Warn("postgres connection is broken, reconnecting", "error", err.Error())
+	return pr.reconnect(ctx)
+}
+
+// connection returns the connection to work with, reconnecting if the previous one is closed.
+// It must be called with the mutex locked.
+func (pr *PostgresRepo) connection(ctx context.Context) (*pgx.Conn, error) {
+	if pr.conn.IsClosed() {
+		if err := pr.reconnect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return pr.conn, nil
+}
+
+func (pr *PostgresRepo) reconnect(ctx context.Context) error {
+	_ = pr.conn.Close(ctx)
+	conn, err := pgx.Connect(ctx, pr.url)
+	if err != nil {
+		return err
+	}
+	pr.conn = conn
+	return nil
+}
+
+// chatUpdate is the change of the chat made by the flushed messages.
+type chatUpdate struct {
+	inserted    int64
+	lastMessage models.Message
+}
+
+// chatUpdates collects the changes of the chats in the order the chats are met.
+type chatUpdates struct {
+	byChat  map[uuid.UUID]*chatUpdate
+	chatIDs []uuid.UUID
+}
+
+func newChatUpdates() *chatUpdates {
+	return &chatUpdates{byChat: make(map[uuid.UUID]*chatUpdate)}
+}
+
+// add takes the message into account as the last message of its chat and returns the change
+// of the chat, the inserted counter is left to the caller.
+func (u *chatUpdates) add(msg models.Message) *chatUpdate {
+	update, ok := u.byChat[msg.ChatID]
+	if !ok {
+		update = &chatUpdate{lastMessage: msg}
+		u.byChat[msg.ChatID] = update
+		u.chatIDs = append(u.chatIDs, msg.ChatID)
+	}
+	if msg.Seq > update.lastMessage.Seq {
+		update.lastMessage = msg
+	}
+	return update
+}
+
+// apply updates the chats, the counters are known only when the inserts are done,
+// so the chats are updated by a batch of its own.
+func (u *chatUpdates) apply(ctx context.Context, tx pgx.Tx) error {
+	chatBatch := &pgx.Batch{}
+	for _, chatID := range u.chatIDs {
+		update := u.byChat[chatID]
+		chatBatch.Queue(UpdateChatQuery, chatID, update.inserted, update.lastMessage.Seq, update.lastMessage.MsgID)
+	}
+	return tx.SendBatch(ctx, chatBatch).Close()
+}
+
+// querier is the part of the transaction skipDeleted works with.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// skipDeleted locks the chats of the messages and drops the messages deleted after they were fetched.
+func skipDeleted(ctx context.Context, q querier, msgs []models.Message) ([]models.Message, error) {
+	chatIDs := make([]string, 0)
+	seen := make(map[uuid.UUID]struct{})
+	msgIDs := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		if _, ok := seen[msg.ChatID]; !ok {
+			seen[msg.ChatID] = struct{}{}
+			chatIDs = append(chatIDs, msg.ChatID.String())
+		}
+		msgIDs = append(msgIDs, msg.MsgID.String())
+	}
+	if _, err := q.Exec(ctx, LockChatsQuery, chatIDs); err != nil {
+		return nil, err
+	}
+	var deletedIDs []string
+	if err := q.QueryRow(ctx, GetDeletedMessagesQuery, msgIDs).Scan(&deletedIDs); err != nil {
+		return nil, err
+	}
+	if len(deletedIDs) == 0 {
+		return msgs, nil
+	}
+	deleted := make(map[string]struct{}, len(deletedIDs))
+	for _, id := range deletedIDs {
+		deleted[id] = struct{}{}
+	}
+	kept := make([]models.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		if _, ok := deleted[msg.MsgID.String()]; ok {
+			slog.Info("skipping deleted message", "msg", msg.MsgID.String())
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept, nil
+}
+
+// isRejected tells whether the error is caused by the message itself, i.e. it is a data exception
+// or an integrity constraint violation, rather than by the database being unavailable.
+func isRejected(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || len(pgErr.Code) < 2 {
+		return false
+	}
+	class := pgErr.Code[:2]
+	return class == "22" || class == "23"
+}
+
+// PersistAllMessages inserts the messages and updates the last message and the counters
+// of their chats in one transaction. The messages persisted by a flush that failed to
+// acknowledge them are skipped, so the messages may be flushed again, and so are the
+// messages deleted since they were fetched. If the database
+// rejects a message, the messages are inserted one by one and the rejected ones are returned.
+func (pr *PostgresRepo) PersistAllMessages(msgs []models.Message) ([]models2.RejectedMessage, error) {
 	ctx := context.Background()
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	conn, err := pr.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = persistBatch(ctx, conn, msgs)
+	if err == nil {
+		return nil, nil
+	}
+	if !isRejected(err) {
+		return nil, err
+	}
+	slog.Warn("batch of messages is rejected, persisting them one by one", "error", err.Error())
+	return persistEach(ctx, conn, msgs)
+}
+
+func persistBatch(ctx context.Context, conn *pgx.Conn, msgs []models.Message) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	msgs, err = skipDeleted(ctx, tx, msgs)
+	if err != nil {
+		return err
+	}
+
+	updates := newChatUpdates()
 	batch := &pgx.Batch{}
 	for _, msg := range msgs {
-		batch.Queue(InsertMsgQuery, msg.MsgID, msg.ChatID, msg.SenderID, msg.Payload, msg.CreatedAt).
+		update := updates.add(msg)
+		batch.Queue(InsertMsgQuery, msg.MsgID, msg.ChatID, msg.SenderID, msg.Payload, msg.CreatedAt, msg.Seq, msg.KindOrText()).
 			Exec(func(ct pgconn.CommandTag) error {
+				update.inserted += ct.RowsAffected()
 				return nil
 			})
 	}
-	err := pr.conn.SendBatch(ctx, batch).Close()
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return err
+	}
+	err = updates.apply(ctx, tx)
 	if err != nil {
 		return err
 	}
+	return tx.Commit(ctx)
+}
 
-	return nil
+// persistEach inserts every message under a savepoint of its own, so a rejected message
+// is rolled back alone.
+func persistEach(ctx context.Context, conn *pgx.Conn, msgs []models.Message) ([]models2.RejectedMessage, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	msgs, err = skipDeleted(ctx, tx, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := newChatUpdates()
+	rejected := make([]models2.RejectedMessage, 0)
+	for _, msg := range msgs {
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ct, err := savepoint.Exec(ctx, InsertMsgQuery, msg.MsgID, msg.ChatID, msg.SenderID,
+			msg.Payload, msg.CreatedAt, msg.Seq, msg.KindOrText())
+		if err != nil {
+			rollbackErr := savepoint.Rollback(ctx)
+			if !isRejected(err) {
+				return nil, err
+			}
+			if rollbackErr != nil {
+				return nil, rollbackErr
+			}
+			rejected = append(rejected, models2.RejectedMessage{Message: msg, Reason: err.Error()})
+			continue
+		}
+		err = savepoint.Commit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		updates.add(msg).inserted += ct.RowsAffected()
+	}
+	err = updates.apply(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// GetLastSeq returns the sequence number of the newest persisted message of the chat, 0 for an empty chat.
+func (pr *PostgresRepo) GetLastSeq(ctx context.Context, chatID uuid.UUID) (int64, error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	conn, err := pr.connection(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var lastSeq int64
+	err = conn.QueryRow(ctx, GetLastSeqQuery, chatID).Scan(&lastSeq)
+	return lastSeq, err
 }
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"our-little-chatik/internal/models"
+	"reflect"
+	"testing"
+)
+
+// fakeTombstones plays the part of the transaction skipDeleted works with, it keeps the tombstones
+// left by the chat service and the queries run in the order they come.
+type fakeTombstones struct {
+	deleted map[string]struct{}
+	lockErr error
+	queries []string
+}
+
+func (f *fakeTombstones) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	f.queries = append(f.queries, sql)
+	return pgconn.CommandTag{}, f.lockErr
+}
+
+func (f *fakeTombstones) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	f.queries = append(f.queries, sql)
+	found := make([]string, 0)
+	for _, id := range args[0].([]string) {
+		if _, ok := f.deleted[id]; ok {
+			found = append(found, id)
+		}
+	}
+	return fakeRow{ids: found}
+}
+
+type fakeRow struct {
+	ids []string
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	*dest[0].(*[]string) = r.ids
+	return nil
+}
+
+func TestSkipDeleted(t *testing.T) {
+	chatID := uuid.New()
+	kept := models.Message{MsgID: uuid.New(), ChatID: chatID, Seq: 1}
+	deleted := models.Message{MsgID: uuid.New(), ChatID: chatID, Seq: 2}
+
+	tests := []struct {
+		name    string
+		tx      *fakeTombstones
+		pre     func(tx *fakeTombstones)
+		want    []models.Message
+		wantErr bool
+	}{
+		{
+			name: "message deleted after the fetch is skipped",
+			tx:   &fakeTombstones{deleted: map[string]struct{}{}},
+			// the flusher has fetched both messages, then the chat service deletes one of them
+			pre: func(tx *fakeTombstones) {
+				tx.deleted[deleted.MsgID.String()] = struct{}{}
+			},
+			want: []models.Message{kept},
+		},
+		{
+			name: "no deleted messages",
+			tx:   &fakeTombstones{deleted: map[string]struct{}{}},
+			pre:  func(tx *fakeTombstones) {},
+			want: []models.Message{kept, deleted},
+		},
+		{
+			name:    "lock failure",
+			tx:      &fakeTombstones{deleted: map[string]struct{}{}, lockErr: errors.New("connection reset")},
+			pre:     func(tx *fakeTombstones) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := []models.Message{kept, deleted}
+			tt.pre(tt.tx)
+			got, err := skipDeleted(context.Background(), tt.tx, fetched)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("skipDeleted() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("skipDeleted() got = %v, want %v", got, tt.want)
+			}
+			// the tombstones are read only once the chats are locked
+			if want := []string{LockChatsQuery, GetDeletedMessagesQuery}; !reflect.DeepEqual(tt.tx.queries, want) {
+				t.Errorf("skipDeleted() queries = %v, want %v", tt.tx.queries, want)
+			}
+		})
+	}
+}
@@ -3,52 +3,457 @@ package repo
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/prometheus/common/log"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/exp/slog"
+	models2 "our-little-chatik/internal/flusher/internal/models"
 	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/seq"
+	"sort"
+	"time"
 )
 
 type RedisRepo struct {
-	cl *redis.Client
+	cl   *redis.Client
+	seqs *seq.RedisCounter
+	// owner tells the claims of this flusher from the claims of the other ones.
+	owner string
 }
 
 func NewRedisRepo(cl *redis.Client) *RedisRepo {
 	return &RedisRepo{
-		cl: cl,
+		cl:    cl,
+		seqs:  seq.NewRedisCounter(cl),
+		owner: uuid.NewString(),
 	}
 }
 
-func (r RedisRepo) FetchAllMessages() ([]models.Message, error) {
-	keys, err := r.cl.Keys(context.Background(), "*").Result()
+// legacyMessageKeysPattern matches the <chat_id>_<msg_id> keys the messages used to be stored in
+// before they were moved to the ZSETs of the chats.
+const legacyMessageKeysPattern = "????????-????-????-????-????????????_????????-????-????-????-????????????"
+
+const legacyScanCount = 100
+
+// claimTTL bounds the claims of a flusher that died before releasing them.
+const claimTTL = time.Minute
+
+// ClaimMessages claims the pending chats no other flush has claimed and reads up to limit
+// of their messages. The messages are only read here, so the history reads still see them,
+// they are removed by AckMessages once they are persisted, so a failed flush does not lose them.
+func (r RedisRepo) ClaimMessages(limit int) ([]models2.QueuedMessage, error) {
+	ctx := context.Background()
+	chatIDs, err := r.cl.SMembers(ctx, models.PendingChatsKey).Result()
 	if err != nil {
 		return nil, err
 	}
+	if len(chatIDs) == 0 {
+		return []models2.QueuedMessage{}, nil
+	}
 
-	values, err := r.cl.MGet(context.Background(), keys...).Result()
+	pipe := r.cl.Pipeline()
+	claims := make([]*redis.BoolCmd, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		claims = append(claims, pipe.SetNX(ctx, fmt.Sprintf(models2.ClaimKeyFormat, chatID), r.owner, claimTTL))
+	}
+	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return nil, err
 	}
+	claimed := make([]string, 0, len(chatIDs))
+	for i := range claims {
+		if claims[i].Val() {
+			claimed = append(claimed, chatIDs[i])
+		}
+	}
+	if len(claimed) == 0 {
+		return []models2.QueuedMessage{}, nil
+	}
 
-	num, err := r.cl.Del(context.Background(), keys...).Result()
+	// The sizes of the chats are not known beforehand, so every chat is read up to the limit.
+	pipe = r.cl.Pipeline()
+	values := make([]*redis.ZSliceCmd, 0, len(claimed))
+	for _, chatID := range claimed {
+		values = append(values, pipe.ZRangeWithScores(ctx, fmt.Sprintf(models.MessagesKeyFormat, chatID), 0, int64(limit-1)))
+	}
+	_, err = pipe.Exec(ctx)
 	if err != nil {
-		log.Errorf("failed to delete keys in db: %v", err)
+		return nil, err
 	}
 
-	log.Infof("removed %d records", num)
-
-	// We merge result of both requests in one slice. The same order of keys and values
-	// in both slices is ensured.
-	messages := make([]models.Message, 0)
+	messages := make([]models2.QueuedMessage, 0)
+	deadLetters := make([]models2.DeadLetter, 0)
+	// the chats none of the messages of which fit the batch are released right away
+	unused := make([]string, 0)
 	for i := range values {
-		var msg models.Message
-		valStr := values[i].(string)
-		err := json.Unmarshal([]byte(valStr), &msg)
+		taken := 0
+		for _, val := range values[i].Val() {
+			if len(messages) == limit {
+				break
+			}
+			valStr, _ := val.Member.(string)
+			var msg models.Message
+			err := json.Unmarshal([]byte(valStr), &msg)
+			if err != nil {
+				slog.Warn("failed to cast a value from redis to models.Message", "chat", claimed[i])
+				chatID, _ := uuid.Parse(claimed[i])
+				deadLetters = append(deadLetters, newDeadLetter(chatID, int64(val.Score), valStr,
+					"failed to decode message: "+err.Error()))
+				continue
+			}
+			messages = append(messages, models2.QueuedMessage{Message: msg, Member: valStr})
+			taken++
+		}
+		if taken == 0 {
+			unused = append(unused, claimed[i])
+		}
+	}
+	// The messages that cannot be decoded would otherwise be fetched by every flush.
+	if len(deadLetters) != 0 {
+		err = r.moveToDeadLetters(ctx, deadLetters)
 		if err != nil {
-			slog.Warn("failed to cast a value from redis to models.Message")
-			continue
+			return nil, err
+		}
+	}
+	for _, chatID := range unused {
+		err = r.ack(ctx, chatID, nil)
+		if err != nil {
+			return nil, err
 		}
-		messages = append(messages, msg)
 	}
+	log.Infof("claimed %d messages of %d chats", len(messages), len(claimed)-len(unused))
 	return messages, nil
 }
+
+// ackMessages removes the given members (ARGV[3:]) from the ZSET of the chat (ARGV[1]) along with
+// their seqs indexed in KEYS[4], drops the chat from the pending ones if no messages were added to it
+// meanwhile and releases the claim of the chat if it is still held by the owner (ARGV[2]).
+var ackMessages = redis.NewScript(`
+for i = 3, #ARGV do
+	redis.call("ZREM", KEYS[1], ARGV[i])
+	local ok, msg = pcall(cjson.decode, ARGV[i])
+	if ok and type(msg) == "table" and msg.msg_id then
+		redis.call("HDEL", KEYS[4], msg.msg_id)
+	end
+end
+if redis.call("ZCARD", KEYS[1]) == 0 then
+	redis.call("SREM", KEYS[2], ARGV[1])
+	redis.call("DEL", KEYS[4])
+end
+if redis.call("GET", KEYS[3]) == ARGV[2] then
+	redis.call("DEL", KEYS[3])
+end
+return 0
+`)
+
+// AckMessages removes the persisted messages from the queue. The messages are matched
+// by their entries, so a message queued again meanwhile under the same seq is not lost.
+func (r RedisRepo) AckMessages(msgs []models2.QueuedMessage) error {
+	ctx := context.Background()
+	chatMembers, chatIDs := membersByChat(msgs)
+	for _, chatID := range chatIDs {
+		err := r.ack(ctx, chatID, chatMembers[chatID])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseMessages gives the chats of the messages back to the other flushes, so a failed flush
+// does not keep them till the claims expire.
+func (r RedisRepo) ReleaseMessages(msgs []models2.QueuedMessage) error {
+	ctx := context.Background()
+	_, chatIDs := membersByChat(msgs)
+	for _, chatID := range chatIDs {
+		err := r.ack(ctx, chatID, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r RedisRepo) ack(ctx context.Context, chatID string, members []interface{}) error {
+	keys := []string{
+		fmt.Sprintf(models.MessagesKeyFormat, chatID),
+		models.PendingChatsKey,
+		fmt.Sprintf(models2.ClaimKeyFormat, chatID),
+		fmt.Sprintf(models.MessageSeqsKeyFormat, chatID),
+	}
+	args := append([]interface{}{chatID, r.owner}, members...)
+	return ackMessages.Run(ctx, r.cl, keys, args...).Err()
+}
+
+// membersByChat groups the entries of the messages by their chats in the order the chats are met.
+func membersByChat(msgs []models2.QueuedMessage) (map[string][]interface{}, []string) {
+	chatMembers := make(map[string][]interface{})
+	chatIDs := make([]string, 0)
+	for _, msg := range msgs {
+		chatID := msg.Message.ChatID.String()
+		if _, ok := chatMembers[chatID]; !ok {
+			chatIDs = append(chatIDs, chatID)
+		}
+		chatMembers[chatID] = append(chatMembers[chatID], msg.Member)
+	}
+	return chatMembers, chatIDs
+}
+
+func (r RedisRepo) BacklogSize() (int64, error) {
+	ctx := context.Background()
+	chatIDs, err := r.cl.SMembers(ctx, models.PendingChatsKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if len(chatIDs) == 0 {
+		return 0, nil
+	}
+	pipe := r.cl.Pipeline()
+	counts := make([]*redis.IntCmd, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		counts = append(counts, pipe.ZCard(ctx, fmt.Sprintf(models.MessagesKeyFormat, chatID)))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var backlog int64
+	for _, count := range counts {
+		backlog += count.Val()
+	}
+	return backlog, nil
+}
+
+// legacyMessage is a message found in a <chat_id>_<msg_id> key.
+type legacyMessage struct {
+	key string
+	msg models.Message
+}
+
+// MigrateLegacyMessages moves the messages stored in the <chat_id>_<msg_id> keys
+// to the ZSETs of their chats and returns the number of the moved messages.
+// The messages stored before the sequence numbers were introduced have none, so they
+// are numbered by the counters of their chats in the order they were sent.
+func (r RedisRepo) MigrateLegacyMessages(ctx context.Context, lastSeq seq.LastSeqFunc) (int, error) {
+	byChat, chatIDs, err := r.fetchLegacyMessages(ctx)
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, chatID := range chatIDs {
+		legacy := byChat[chatID]
+		sort.SliceStable(legacy, func(i, j int) bool {
+			return legacy[i].msg.CreatedAt < legacy[j].msg.CreatedAt
+		})
+		pipe := r.cl.TxPipeline()
+		for _, l := range legacy {
+			if l.msg.Seq == 0 {
+				l.msg.Seq, err = seq.Next(ctx, r.seqs, chatID, lastSeq)
+				if err != nil {
+					return moved, err
+				}
+			}
+			bMsg, err := json.Marshal(&l.msg)
+			if err != nil {
+				return moved, err
+			}
+			pipe.ZAdd(ctx, fmt.Sprintf(models.MessagesKeyFormat, chatID.String()), redis.Z{
+				Score:  float64(l.msg.Seq),
+				Member: string(bMsg),
+			})
+			pipe.HSet(ctx, fmt.Sprintf(models.MessageSeqsKeyFormat, chatID.String()), l.msg.MsgID.String(), l.msg.Seq)
+			pipe.Del(ctx, l.key)
+		}
+		pipe.SAdd(ctx, models.PendingChatsKey, chatID.String())
+		_, err = pipe.Exec(ctx)
+		if err != nil {
+			return moved, err
+		}
+		moved += len(legacy)
+	}
+	return moved, nil
+}
+
+// fetchLegacyMessages reads the messages of all the <chat_id>_<msg_id> keys, as the messages
+// of a chat have to be numbered together. The keys of the messages that cannot be decoded are left.
+func (r RedisRepo) fetchLegacyMessages(ctx context.Context) (map[uuid.UUID][]legacyMessage, []uuid.UUID, error) {
+	byChat := make(map[uuid.UUID][]legacyMessage)
+	chatIDs := make([]uuid.UUID, 0)
+	var cursor uint64
+	for {
+		keys, next, err := r.cl.Scan(ctx, cursor, legacyMessageKeysPattern, legacyScanCount).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(keys) != 0 {
+			values, err := r.cl.MGet(ctx, keys...).Result()
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := range values {
+				valStr, ok := values[i].(string)
+				if !ok {
+					continue
+				}
+				var msg models.Message
+				err := json.Unmarshal([]byte(valStr), &msg)
+				if err != nil {
+					slog.Warn("failed to cast a value from redis to models.Message", "key", keys[i])
+					continue
+				}
+				if _, ok := byChat[msg.ChatID]; !ok {
+					chatIDs = append(chatIDs, msg.ChatID)
+				}
+				byChat[msg.ChatID] = append(byChat[msg.ChatID], legacyMessage{key: keys[i], msg: msg})
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			return byChat, chatIDs, nil
+		}
+	}
+}
+
+func newDeadLetter(chatID uuid.UUID, seq int64, payload string, reason string) models2.DeadLetter {
+	return models2.DeadLetter{
+		ID:       uuid.New(),
+		ChatID:   chatID,
+		Seq:      seq,
+		Payload:  payload,
+		Reason:   reason,
+		FailedAt: time.Now().Unix(),
+	}
+}
+
+// moveToDeadLetters stores the dead letters and removes their messages from the queue in one transaction.
+func (r RedisRepo) moveToDeadLetters(ctx context.Context, deadLetters []models2.DeadLetter) error {
+	pipe := r.cl.TxPipeline()
+	for _, deadLetter := range deadLetters {
+		bDeadLetter, err := json.Marshal(&deadLetter)
+		if err != nil {
+			return err
+		}
+		pipe.HSet(ctx, models2.DeadLettersKey, deadLetter.ID.String(), string(bDeadLetter))
+		pipe.ZRem(ctx, fmt.Sprintf(models.MessagesKeyFormat, deadLetter.ChatID.String()), deadLetter.Payload)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r RedisRepo) DeadLetterMessages(rejected []models2.RejectedMessage) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+	deadLetters := make([]models2.DeadLetter, 0, len(rejected))
+	for _, rejectedMsg := range rejected {
+		deadLetters = append(deadLetters, newDeadLetter(rejectedMsg.Message.ChatID, rejectedMsg.Message.Seq,
+			rejectedMsg.Member, rejectedMsg.Reason))
+	}
+	return r.moveToDeadLetters(context.Background(), deadLetters)
+}
+
+func (r RedisRepo) GetDeadLetters() ([]models2.DeadLetter, error) {
+	values, err := r.cl.HVals(context.Background(), models2.DeadLettersKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	deadLetters := make([]models2.DeadLetter, 0, len(values))
+	for _, val := range values {
+		deadLetter := models2.DeadLetter{}
+		err := json.Unmarshal([]byte(val), &deadLetter)
+		if err != nil {
+			slog.Error(err.Error())
+			continue
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	sort.Slice(deadLetters, func(i, j int) bool {
+		return deadLetters[i].FailedAt < deadLetters[j].FailedAt
+	})
+	return deadLetters, nil
+}
+
+// getDeadLetters returns the dead letters with the given ids, all of them if ids are nil.
+func (r RedisRepo) getDeadLetters(ctx context.Context, ids []uuid.UUID) ([]models2.DeadLetter, error) {
+	if ids == nil {
+		return r.GetDeadLetters()
+	}
+	fields := make([]string, 0, len(ids))
+	for _, id := range ids {
+		fields = append(fields, id.String())
+	}
+	values, err := r.cl.HMGet(ctx, models2.DeadLettersKey, fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	deadLetters := make([]models2.DeadLetter, 0, len(values))
+	for _, val := range values {
+		valStr, ok := val.(string)
+		if !ok {
+			continue
+		}
+		deadLetter := models2.DeadLetter{}
+		err := json.Unmarshal([]byte(valStr), &deadLetter)
+		if err != nil {
+			slog.Error(err.Error())
+			continue
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
+
+// ReplayDeadLetters puts the messages of the dead letters back to the queue, so the next flush retries them.
+func (r RedisRepo) ReplayDeadLetters(ids []uuid.UUID) (int, error) {
+	ctx := context.Background()
+	deadLetters, err := r.getDeadLetters(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(deadLetters) == 0 {
+		return 0, nil
+	}
+	pipe := r.cl.TxPipeline()
+	for _, deadLetter := range deadLetters {
+		chatID := deadLetter.ChatID.String()
+		pipe.ZAdd(ctx, fmt.Sprintf(models.MessagesKeyFormat, chatID), redis.Z{
+			Score:  float64(deadLetter.Seq),
+			Member: deadLetter.Payload,
+		})
+		var msg models.Message
+		if json.Unmarshal([]byte(deadLetter.Payload), &msg) == nil && msg.MsgID != uuid.Nil {
+			pipe.HSet(ctx, fmt.Sprintf(models.MessageSeqsKeyFormat, chatID), msg.MsgID.String(), deadLetter.Seq)
+		}
+		pipe.SAdd(ctx, models.PendingChatsKey, chatID)
+		pipe.HDel(ctx, models2.DeadLettersKey, deadLetter.ID.String())
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(deadLetters), nil
+}
+
+func (r RedisRepo) PurgeDeadLetters(ids []uuid.UUID) (int, error) {
+	ctx := context.Background()
+	if ids == nil {
+		pipe := r.cl.TxPipeline()
+		count := pipe.HLen(ctx, models2.DeadLettersKey)
+		pipe.Del(ctx, models2.DeadLettersKey)
+		_, err := pipe.Exec(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return int(count.Val()), nil
+	}
+	fields := make([]string, 0, len(ids))
+	for _, id := range ids {
+		fields = append(fields, id.String())
+	}
+	count, err := r.cl.HDel(ctx, models2.DeadLettersKey, fields...).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
@@ -1,16 +1,22 @@
 package repo
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/go-redis/redismock/v9"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	models2 "our-little-chatik/internal/flusher/internal/models"
 	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/seq"
 	"reflect"
 	"testing"
 )
 
-func TestRedisRepo_FetchAllMessages(t *testing.T) {
+const testOwner = "test flusher"
+
+func TestRedisRepo_ClaimMessages(t *testing.T) {
 	type fields struct {
 		cl *redis.Client
 	}
@@ -18,31 +24,114 @@ func TestRedisRepo_FetchAllMessages(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 
 	testMsg := models.Message{
-		MsgID: uuid.New(),
-	}
-	keys := []string{
-		"test",
+		ChatID: uuid.New(),
+		MsgID:  uuid.New(),
+		Seq:    1,
 	}
+	chatIDs := []string{testMsg.ChatID.String()}
+	key := fmt.Sprintf(models.MessagesKeyFormat, testMsg.ChatID.String())
+	claimKey := fmt.Sprintf(models2.ClaimKeyFormat, testMsg.ChatID.String())
+
+	otherChatID := uuid.New().String()
+	otherKeys := []string{fmt.Sprintf(models.MessagesKeyFormat, otherChatID), models.PendingChatsKey,
+		fmt.Sprintf(models2.ClaimKeyFormat, otherChatID), fmt.Sprintf(models.MessageSeqsKeyFormat, otherChatID)}
 
 	testMsgByte, _ := json.Marshal(testMsg)
+	testQueued := models2.QueuedMessage{Message: testMsg, Member: string(testMsgByte)}
 
 	tests := []struct {
 		name    string
 		fields  fields
-		want    []models.Message
+		limit   int
+		want    []models2.QueuedMessage
 		pre     func()
 		wantErr bool
 	}{
 		{
-			name: "",
+			name: "messages of the claimed chats",
+			fields: fields{
+				cl: db,
+			},
+			limit: 10,
+			want:  []models2.QueuedMessage{testQueued},
+			pre: func() {
+				mock.ExpectSMembers(models.PendingChatsKey).SetVal(chatIDs)
+				mock.ExpectSetNX(claimKey, testOwner, claimTTL).SetVal(true)
+				mock.ExpectZRangeWithScores(key, 0, 9).SetVal([]redis.Z{{Score: 1, Member: string(testMsgByte)}})
+			},
+			wantErr: false,
+		},
+		{
+			name: "chats claimed by another flush are skipped",
+			fields: fields{
+				cl: db,
+			},
+			limit: 10,
+			want:  []models2.QueuedMessage{},
+			pre: func() {
+				mock.ExpectSMembers(models.PendingChatsKey).SetVal(chatIDs)
+				mock.ExpectSetNX(claimKey, testOwner, claimTTL).SetVal(false)
+			},
+			wantErr: false,
+		},
+		{
+			name: "chats beyond the limit are released",
 			fields: fields{
 				cl: db,
 			},
-			want: []models.Message{testMsg},
+			limit: 1,
+			want:  []models2.QueuedMessage{testQueued},
 			pre: func() {
-				mock.ExpectKeys("*").SetVal(keys)
-				mock.ExpectMGet(keys...).SetVal([]interface{}{string(testMsgByte)})
-				mock.ExpectDel(keys...).SetVal(1)
+				mock.ExpectSMembers(models.PendingChatsKey).SetVal(append(chatIDs, otherChatID))
+				mock.ExpectSetNX(claimKey, testOwner, claimTTL).SetVal(true)
+				mock.ExpectSetNX(otherKeys[2], testOwner, claimTTL).SetVal(true)
+				mock.ExpectZRangeWithScores(key, 0, 0).SetVal([]redis.Z{{Score: 1, Member: string(testMsgByte)}})
+				mock.ExpectZRangeWithScores(otherKeys[0], 0, 0).SetVal([]redis.Z{{Score: 1, Member: "{}"}})
+				mock.ExpectEvalSha(ackMessages.Hash(), otherKeys, otherChatID, testOwner).SetVal(int64(0))
+			},
+			wantErr: false,
+		},
+		{
+			name: "undecodable messages are dead lettered",
+			fields: fields{
+				cl: db,
+			},
+			limit: 10,
+			want:  []models2.QueuedMessage{testQueued},
+			pre: func() {
+				mock.ExpectSMembers(models.PendingChatsKey).SetVal(chatIDs)
+				mock.ExpectSetNX(claimKey, testOwner, claimTTL).SetVal(true)
+				mock.ExpectZRangeWithScores(key, 0, 9).SetVal([]redis.Z{
+					{Score: 1, Member: string(testMsgByte)},
+					{Score: 2, Member: "{broken"},
+				})
+				mock.ExpectTxPipeline()
+				mock.CustomMatch(func(expected, actual []interface{}) error {
+					deadLetter := models2.DeadLetter{}
+					err := json.Unmarshal([]byte(fmt.Sprint(actual[3])), &deadLetter)
+					if err != nil {
+						return err
+					}
+					if actual[1] != models2.DeadLettersKey || deadLetter.Payload != "{broken" ||
+						deadLetter.Seq != 2 || deadLetter.ChatID != testMsg.ChatID {
+						return fmt.Errorf("unexpected dead letter %v", actual)
+					}
+					return nil
+				}).ExpectHSet(models2.DeadLettersKey, "", "").SetVal(1)
+				mock.ExpectZRem(key, "{broken").SetVal(1)
+				mock.ExpectTxPipelineExec()
+			},
+			wantErr: false,
+		},
+		{
+			name: "no pending chats",
+			fields: fields{
+				cl: db,
+			},
+			limit: 10,
+			want:  []models2.QueuedMessage{},
+			pre: func() {
+				mock.ExpectSMembers(models.PendingChatsKey).SetVal([]string{})
 			},
 			wantErr: false,
 		},
@@ -50,16 +139,301 @@ func TestRedisRepo_FetchAllMessages(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := RedisRepo{
-				cl: tt.fields.cl,
+				cl:    tt.fields.cl,
+				owner: testOwner,
 			}
 			tt.pre()
-			got, err := r.FetchAllMessages()
+			got, err := r.ClaimMessages(tt.limit)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("FetchAllMessages() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ClaimMessages() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("FetchAllMessages() got = %v, want %v", got, tt.want)
+				t.Errorf("ClaimMessages() got = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_AckMessages(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testChatID := uuid.New()
+	testMsgs := []models2.QueuedMessage{
+		{Message: models.Message{ChatID: testChatID, MsgID: uuid.New(), Seq: 1}, Member: "first"},
+		{Message: models.Message{ChatID: testChatID, MsgID: uuid.New(), Seq: 2}, Member: "second"},
+	}
+	keys := []string{fmt.Sprintf(models.MessagesKeyFormat, testChatID.String()), models.PendingChatsKey,
+		fmt.Sprintf(models2.ClaimKeyFormat, testChatID.String()), fmt.Sprintf(models.MessageSeqsKeyFormat, testChatID.String())}
+
+	tests := []struct {
+		name    string
+		release bool
+		pre     func()
+		wantErr bool
+	}{
+		{
+			name: "acknowledged by the queue entries",
+			pre: func() {
+				mock.ExpectEvalSha(ackMessages.Hash(), keys, testChatID.String(), testOwner, "first", "second").
+					SetVal(int64(0))
+			},
+			wantErr: false,
+		},
+		{
+			name:    "released without removing the messages",
+			release: true,
+			pre: func() {
+				mock.ExpectEvalSha(ackMessages.Hash(), keys, testChatID.String(), testOwner).SetVal(int64(0))
+			},
+			wantErr: false,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectEvalSha(ackMessages.Hash(), keys, testChatID.String(), testOwner, "first", "second").
+					SetErr(fmt.Errorf("down"))
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl:    db,
+				owner: testOwner,
+			}
+			tt.pre()
+			var err error
+			if tt.release {
+				err = r.ReleaseMessages(testMsgs)
+			} else {
+				err = r.AckMessages(testMsgs)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AckMessages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_BacklogSize(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	chatIDs := []string{uuid.New().String(), uuid.New().String()}
+	mock.ExpectSMembers(models.PendingChatsKey).SetVal(chatIDs)
+	mock.ExpectZCard(fmt.Sprintf(models.MessagesKeyFormat, chatIDs[0])).SetVal(2)
+	mock.ExpectZCard(fmt.Sprintf(models.MessagesKeyFormat, chatIDs[1])).SetVal(3)
+
+	r := RedisRepo{
+		cl: db,
+	}
+	got, err := r.BacklogSize()
+	if err != nil {
+		t.Fatalf("BacklogSize() error = %v", err)
+	}
+	if got != 5 {
+		t.Errorf("BacklogSize() got = %v, want 5", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisRepo_MigrateLegacyMessages(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	incrHash := redis.NewScript(seq.IncrExistingScript).Hash()
+	seedHash := redis.NewScript(seq.SeedScript).Hash()
+
+	legacyKey := func(msg models.Message) string {
+		return msg.ChatID.String() + "_" + msg.MsgID.String()
+	}
+	marshal := func(msg models.Message) string {
+		bMsg, _ := json.Marshal(&msg)
+		return string(bMsg)
+	}
+	withSeq := func(msg models.Message, seq int64) models.Message {
+		msg.Seq = seq
+		return msg
+	}
+
+	sequencedMsg := models.Message{
+		ChatID: uuid.New(),
+		MsgID:  uuid.New(),
+		Seq:    3,
+	}
+	chatID := uuid.New()
+	olderMsg := models.Message{ChatID: chatID, MsgID: uuid.New(), CreatedAt: 100}
+	newerMsg := models.Message{ChatID: chatID, MsgID: uuid.New(), CreatedAt: 200}
+	lastSeq := func(ctx context.Context, id uuid.UUID) (int64, error) {
+		if id != chatID {
+			return 0, fmt.Errorf("unexpected chat %s", id)
+		}
+		return 10, nil
+	}
+
+	tests := []struct {
+		name  string
+		pre   func()
+		moved int
+	}{
+		{
+			name: "message with seq keeps it",
+			pre: func() {
+				key := legacyKey(sequencedMsg)
+				mock.ExpectScan(0, legacyMessageKeysPattern, legacyScanCount).SetVal([]string{key}, 0)
+				mock.ExpectMGet(key).SetVal([]interface{}{marshal(sequencedMsg)})
+				mock.ExpectTxPipeline()
+				mock.ExpectZAdd(fmt.Sprintf(models.MessagesKeyFormat, sequencedMsg.ChatID.String()), redis.Z{
+					Score:  3,
+					Member: marshal(sequencedMsg),
+				}).SetVal(1)
+				mock.ExpectHSet(fmt.Sprintf(models.MessageSeqsKeyFormat, sequencedMsg.ChatID.String()),
+					sequencedMsg.MsgID.String(), int64(3)).SetVal(1)
+				mock.ExpectDel(key).SetVal(1)
+				mock.ExpectSAdd(models.PendingChatsKey, sequencedMsg.ChatID.String()).SetVal(1)
+				mock.ExpectTxPipelineExec()
+			},
+			moved: 1,
+		},
+		{
+			name: "messages without seq are numbered in the order they were sent",
+			pre: func() {
+				seqKey := seq.Key(chatID)
+				messagesKey := fmt.Sprintf(models.MessagesKeyFormat, chatID.String())
+				seqsKey := fmt.Sprintf(models.MessageSeqsKeyFormat, chatID.String())
+				mock.ExpectScan(0, legacyMessageKeysPattern, legacyScanCount).
+					SetVal([]string{legacyKey(newerMsg), legacyKey(olderMsg)}, 0)
+				mock.ExpectMGet(legacyKey(newerMsg), legacyKey(olderMsg)).
+					SetVal([]interface{}{marshal(newerMsg), marshal(olderMsg)})
+				mock.ExpectEvalSha(incrHash, []string{seqKey}).SetVal(int64(0))
+				mock.ExpectEvalSha(seedHash, []string{seqKey, messagesKey}, int64(10)).SetVal(int64(1))
+				mock.ExpectEvalSha(incrHash, []string{seqKey}).SetVal(int64(11))
+				mock.ExpectEvalSha(incrHash, []string{seqKey}).SetVal(int64(12))
+				mock.ExpectTxPipeline()
+				mock.ExpectZAdd(messagesKey, redis.Z{Score: 11, Member: marshal(withSeq(olderMsg, 11))}).SetVal(1)
+				mock.ExpectHSet(seqsKey, olderMsg.MsgID.String(), int64(11)).SetVal(1)
+				mock.ExpectDel(legacyKey(olderMsg)).SetVal(1)
+				mock.ExpectZAdd(messagesKey, redis.Z{Score: 12, Member: marshal(withSeq(newerMsg, 12))}).SetVal(1)
+				mock.ExpectHSet(seqsKey, newerMsg.MsgID.String(), int64(12)).SetVal(1)
+				mock.ExpectDel(legacyKey(newerMsg)).SetVal(1)
+				mock.ExpectSAdd(models.PendingChatsKey, chatID.String()).SetVal(1)
+				mock.ExpectTxPipelineExec()
+			},
+			moved: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRedisRepo(db)
+			tt.pre()
+			moved, err := r.MigrateLegacyMessages(context.Background(), lastSeq)
+			if err != nil {
+				t.Fatalf("MigrateLegacyMessages() error = %v", err)
+			}
+			if moved != tt.moved {
+				t.Errorf("MigrateLegacyMessages() moved = %v, want %v", moved, tt.moved)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRedisRepo_ReplayDeadLetters(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testMsg := models.Message{ChatID: uuid.New(), MsgID: uuid.New(), Seq: 4}
+	bMsg, _ := json.Marshal(&testMsg)
+	testDeadLetter := models2.DeadLetter{
+		ID:      uuid.New(),
+		ChatID:  testMsg.ChatID,
+		Seq:     4,
+		Payload: string(bMsg),
+		Reason:  "rejected",
+	}
+	bDeadLetter, _ := json.Marshal(&testDeadLetter)
+	chatID := testDeadLetter.ChatID.String()
+
+	mock.ExpectHMGet(models2.DeadLettersKey, testDeadLetter.ID.String()).SetVal([]interface{}{string(bDeadLetter)})
+	mock.ExpectTxPipeline()
+	mock.ExpectZAdd(fmt.Sprintf(models.MessagesKeyFormat, chatID), redis.Z{
+		Score:  4,
+		Member: string(bMsg),
+	}).SetVal(1)
+	mock.ExpectHSet(fmt.Sprintf(models.MessageSeqsKeyFormat, chatID), testMsg.MsgID.String(), int64(4)).SetVal(1)
+	mock.ExpectSAdd(models.PendingChatsKey, chatID).SetVal(1)
+	mock.ExpectHDel(models2.DeadLettersKey, testDeadLetter.ID.String()).SetVal(1)
+	mock.ExpectTxPipelineExec()
+
+	r := RedisRepo{
+		cl: db,
+	}
+	replayed, err := r.ReplayDeadLetters([]uuid.UUID{testDeadLetter.ID})
+	if err != nil {
+		t.Fatalf("ReplayDeadLetters() error = %v", err)
+	}
+	if replayed != 1 {
+		t.Errorf("ReplayDeadLetters() replayed = %v, want 1", replayed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisRepo_PurgeDeadLetters(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	testID := uuid.New()
+
+	tests := []struct {
+		name string
+		ids  []uuid.UUID
+		pre  func()
+		want int
+	}{
+		{
+			name: "given dead letters",
+			ids:  []uuid.UUID{testID},
+			pre: func() {
+				mock.ExpectHDel(models2.DeadLettersKey, testID.String()).SetVal(1)
+			},
+			want: 1,
+		},
+		{
+			name: "all dead letters",
+			ids:  nil,
+			pre: func() {
+				mock.ExpectTxPipeline()
+				mock.ExpectHLen(models2.DeadLettersKey).SetVal(3)
+				mock.ExpectDel(models2.DeadLettersKey).SetVal(1)
+				mock.ExpectTxPipelineExec()
+			},
+			want: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RedisRepo{
+				cl: db,
+			}
+			tt.pre()
+			got, err := r.PurgeDeadLetters(tt.ids)
+			if err != nil {
+				t.Fatalf("PurgeDeadLetters() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PurgeDeadLetters() got = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
 			}
 		})
 	}
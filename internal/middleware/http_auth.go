@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"golang.org/x/exp/slog"
+	"our-little-chatik/internal/pkg"
+)
+
+// TokenSubprotocol is the websocket subprotocol a client offers together with
+// the token itself, e.g. new WebSocket(url, ["bearer", token]), when it is not
+// able to pass the Token cookie or the Authorization header.
+const TokenSubprotocol = "bearer"
+
+type contextKey string
+
+const userIDContextKey contextKey = "user_id"
+
+const cookieAuthContextKey contextKey = "cookie_auth"
+
+// HTTPAuth is the net/http counterpart of Auth for the services built on top of
+// gorilla/mux. It verifies the same JWT and puts the user id from its claims into
+// the request context. Requests without a valid token get 401.
+func HTTPAuth(signedKey []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, fromCookie, err := lookUpToken(r)
+			if err != nil {
+				slog.Warn("unauthenticated request", "uri", r.RequestURI, "err", err.Error())
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			claims, err := pkg.ParseJWTToken(tokenString, signedKey)
+			if err != nil {
+				slog.Warn("invalid token", "uri", r.RequestURI, "err", err.Error())
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			userID, err := uuid.Parse(claims.UserID)
+			if err != nil {
+				slog.Warn("invalid user id in claims", "uri", r.RequestURI, "err", err.Error())
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+			ctx = context.WithValue(ctx, cookieAuthContextKey, fromCookie)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UserIDFromContext returns the user id set by HTTPAuth.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDContextKey).(uuid.UUID)
+	return userID, ok
+}
+
+// AuthenticatedByCookie tells whether HTTPAuth took the token from the Token cookie,
+// which the browsers send along with the requests of the other sites as well.
+func AuthenticatedByCookie(ctx context.Context) bool {
+	fromCookie, _ := ctx.Value(cookieAuthContextKey).(bool)
+	return fromCookie
+}
+
+// lookUpToken looks for the token in the Token cookie, the Authorization header
+// and the Sec-WebSocket-Protocol header in that order, and tells whether it was the cookie.
+func lookUpToken(r *http.Request) (string, bool, error) {
+	if cookie, err := r.Cookie("Token"); err == nil && cookie.Value != "" {
+		return cookie.Value, true, nil
+	}
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if found && strings.EqualFold(scheme, "Bearer") && token != "" {
+			return token, false, nil
+		}
+	}
+	protocols := websocket.Subprotocols(r)
+	for i := range protocols {
+		if protocols[i] == TokenSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1], false, nil
+		}
+	}
+	return "", false, fmt.Errorf("no token provided")
+}
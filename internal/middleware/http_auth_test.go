@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"our-little-chatik/internal/pkg"
+)
+
+func TestHTTPAuth(t *testing.T) {
+	testKey := []byte("test")
+	testUserID := uuid.New()
+
+	signToken := func(userID string, key []byte, expiresAt time.Time) string {
+		claims := &pkg.JwtCustomClaims{
+			UserID: userID,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	validToken := signToken(testUserID.String(), testKey, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		prepare    func(r *http.Request)
+		wantStatus int
+		wantUserID uuid.UUID
+		wantCookie bool
+	}{
+		{
+			name: "token in cookie",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "Token", Value: validToken})
+			},
+			wantStatus: http.StatusOK,
+			wantUserID: testUserID,
+			wantCookie: true,
+		},
+		{
+			name: "token in authorization header",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+validToken)
+			},
+			wantStatus: http.StatusOK,
+			wantUserID: testUserID,
+		},
+		{
+			name: "token in websocket subprotocol",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Sec-WebSocket-Protocol", TokenSubprotocol+", "+validToken)
+			},
+			wantStatus: http.StatusOK,
+			wantUserID: testUserID,
+		},
+		{
+			name:       "no token",
+			prepare:    func(r *http.Request) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "user_id query parameter is not trusted",
+			prepare: func(r *http.Request) {
+				q := r.URL.Query()
+				q.Set("user_id", testUserID.String())
+				r.URL.RawQuery = q.Encode()
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token signed with another key",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "Token",
+					Value: signToken(testUserID.String(), []byte("other"), time.Now().Add(time.Hour))})
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "Token",
+					Value: signToken(testUserID.String(), testKey, time.Now().Add(-time.Hour))})
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "malformed user id",
+			prepare: func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: "Token",
+					Value: signToken("not-a-uuid", testKey, time.Now().Add(time.Hour))})
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID uuid.UUID
+			var gotCookie bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = UserIDFromContext(r.Context())
+				gotCookie = AuthenticatedByCookie(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ws/chat", nil)
+			tt.prepare(req)
+			rec := httptest.NewRecorder()
+
+			HTTPAuth(testKey)(next).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("HTTPAuth() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("HTTPAuth() user id = %v, want %v", gotUserID, tt.wantUserID)
+			}
+			if gotCookie != tt.wantCookie {
+				t.Errorf("HTTPAuth() authenticated by cookie = %v, want %v", gotCookie, tt.wantCookie)
+			}
+		})
+	}
+}
@@ -1,14 +1,133 @@
 package models
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/google/uuid"
 )
 
 type Chat struct {
 	ChatID       uuid.UUID   `json:"chat_id,omitempty"`
+	Kind         ChatKind    `json:"kind,omitempty"`
 	Participants []uuid.UUID `json:"participants,omitempty"`
-	Name         string      `json:"name,omitempty"`
-	PhotoURL     string      `json:"photo_url,omitempty"`
-	CreatedAt    int64       `json:"created_at,omitempty"`
-	LastMessage  Message     `json:"last_message,omitempty"`
+	// Members are the participants along with their roles.
+	Members     []ChatMember `json:"members,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	PhotoURL    string       `json:"photo_url,omitempty"`
+	CreatedAt   int64        `json:"created_at,omitempty"`
+	LastMessage Message      `json:"last_message,omitempty"`
+	// LastReadMsgID is the read cursor of the user the chat is requested for,
+	// LastReadSeq is the sequence number of that message.
+	LastReadMsgID *uuid.UUID `json:"last_read_msg_id,omitempty"`
+	LastReadSeq   int64      `json:"last_read_seq,omitempty"`
+	UnreadCount   int64      `json:"unread_count,omitempty"`
+	MessagesCount int64      `json:"messages_count,omitempty"`
+	// CustomName tells whether Name has been chosen by the user the chat is requested for,
+	// rather than taken from the profile of the other participant.
+	CustomName bool `json:"custom_name,omitempty"`
+}
+
+// ChatKind tells how the chat behaves: who may be added to it and whose its name is.
+type ChatKind string
+
+const (
+	// DirectChat is the chat of two users, a pair of users has a single one.
+	DirectChat ChatKind = "direct"
+	// GroupChat is the chat of any number of users managed by its admins.
+	GroupChat ChatKind = "group"
+	// SavedMessagesChat is the chat of the user with themselves, a user has a single one.
+	SavedMessagesChat ChatKind = "saved_messages"
+	// ChannelChat is the group the admins post to for its members.
+	ChannelChat ChatKind = "channel"
+)
+
+// IsValid tells whether the kind is one of the known ones.
+func (k ChatKind) IsValid() bool {
+	switch k {
+	case DirectChat, GroupChat, SavedMessagesChat, ChannelChat:
+		return true
+	}
+	return false
+}
+
+// IsPersonal tells whether the chat belongs to its participants rather than to its admins:
+// no one may be added to or removed from it, and its name is set by every participant for themselves.
+func (k ChatKind) IsPersonal() bool {
+	return k == DirectChat || k == SavedMessagesChat
+}
+
+// CanPost tells whether the participant with the role may send messages to the chat of the kind,
+// only the admins and the owner post to a channel.
+func (k ChatKind) CanPost(role ChatRole) bool {
+	return k != ChannelChat || role.CanManage()
+}
+
+// PersonalKey identifies the personal chat of the participants regardless of their order,
+// so that the users have a single chat of the kind.
+func (c Chat) PersonalKey() string {
+	ids := make([]string, 0, len(c.Participants))
+	for _, participant := range c.Participants {
+		ids = append(ids, participant.String())
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ":")
+}
+
+// ReadReceipt tells up to which message the participant has read the chat.
+type ReadReceipt struct {
+	UserID        uuid.UUID `json:"user_id"`
+	LastReadMsgID uuid.UUID `json:"last_read_msg_id"`
+}
+
+// ChatRole tells what a participant is allowed to change in the chat.
+type ChatRole string
+
+const (
+	// OwnerRole is the role of the creator of the chat, a chat has a single owner,
+	// who manages the admins and may delete the chat.
+	OwnerRole ChatRole = "owner"
+	// AdminRole may manage the participants and the look of the chat.
+	AdminRole ChatRole = "admin"
+	// MemberRole may only send and read the messages.
+	MemberRole ChatRole = "member"
+)
+
+var chatRoleRanks = map[ChatRole]int{
+	MemberRole: 1,
+	AdminRole:  2,
+	OwnerRole:  3,
+}
+
+// IsValid tells whether the role is one of the known ones.
+func (r ChatRole) IsValid() bool {
+	_, ok := chatRoleRanks[r]
+	return ok
+}
+
+// CanManage tells whether the role may change the participants, the name and the photo of the chat.
+func (r ChatRole) CanManage() bool {
+	return chatRoleRanks[r] >= chatRoleRanks[AdminRole]
+}
+
+// Outranks tells whether the participant having the role may act on the one having the other role,
+// e.g. remove them from the chat.
+func (r ChatRole) Outranks(other ChatRole) bool {
+	return chatRoleRanks[r] > chatRoleRanks[other]
+}
+
+// ChatMember is a participant of the chat along with their role.
+type ChatMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   ChatRole  `json:"role"`
+}
+
+// RoleOf returns the role of the participant, the participants missing from the members are plain members.
+func (c Chat) RoleOf(userID uuid.UUID) ChatRole {
+	for _, member := range c.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return MemberRole
 }
@@ -0,0 +1,25 @@
+package models
+
+import "github.com/google/uuid"
+
+// ChatEventsChannel is the pub/sub channel chat service uses to tell
+// peer service about changes that affect live sessions.
+const ChatEventsChannel = "chat_events"
+
+type ChatEventType string
+
+const (
+	UsersRemovedFromChat ChatEventType = "users_removed"
+	// ChatRenamedForUsers is a rename of the chat seen by the Users only, e.g. of a direct chat.
+	ChatRenamedForUsers ChatEventType = "renamed_for_users"
+)
+
+// ChatEvent describes a change of a chat that connected peers must react to.
+type ChatEvent struct {
+	Type   ChatEventType `json:"type"`
+	ChatID uuid.UUID     `json:"chat_id"`
+	Users  []uuid.UUID   `json:"users,omitempty"`
+	Name   string        `json:"name,omitempty"`
+	// Trace carries the trace context of the publisher.
+	Trace map[string]string `json:"trace,omitempty"`
+}
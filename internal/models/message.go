@@ -1,19 +1,128 @@
 package models
 
 import (
+	"encoding/json"
 	"github.com/google/uuid"
+	"sort"
+	"time"
 )
 
+// MessagesKeyFormat is the redis ZSET of the messages of the chat that have not been
+// flushed to the database yet, the members are JSON encoded messages scored by their seq.
+const MessagesKeyFormat = "messages_%s"
+
+// MessageSeqsKeyFormat is the redis HASH of the seqs of the messages of MessagesKeyFormat by their ids,
+// so a queued message is looked up without reading the whole queue of the chat.
+const MessageSeqsKeyFormat = "message_seqs_%s"
+
+// PendingChatsKey is the redis SET of the chats that have messages to flush.
+const PendingChatsKey = "pending_chats"
+
+// SeqKeyFormat is the key of the message sequence counter of the chat.
+const SeqKeyFormat = "seq_%s"
+
+// MessageKind tells the messages written by the users from the system messages,
+// which tell the participants about the changes of the chat.
+type MessageKind string
+
+const (
+	TextMessage         MessageKind = "text"
+	ChatCreatedMessage  MessageKind = "chat_created"
+	UsersAddedMessage   MessageKind = "users_added"
+	UsersRemovedMessage MessageKind = "users_removed"
+	PhotoChangedMessage MessageKind = "photo_changed"
+)
+
+// SystemMessageBody is the JSON payload of a system message, the sender of the message
+// is the user who has made the change.
+type SystemMessageBody struct {
+	Name     string      `json:"name,omitempty"`
+	Users    []uuid.UUID `json:"users,omitempty"`
+	PhotoURL string      `json:"photo_url,omitempty"`
+}
+
+// NewSystemMessage returns the system message of the kind about the change the sender has made to the chat.
+func NewSystemMessage(chat Chat, sender User, kind MessageKind, body SystemMessageBody) (Message, error) {
+	payload, err := json.Marshal(&body)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		ChatID:    chat.ChatID,
+		MsgID:     uuid.New(),
+		SenderID:  sender.ID,
+		Kind:      kind,
+		Payload:   string(payload),
+		CreatedAt: time.Now().Unix(),
+	}, nil
+}
+
 type Message struct {
 	ChatID    uuid.UUID `json:"chat_id" bson:"chat_id"`
 	MsgID     uuid.UUID `json:"msg_id,omitempty" bson:"msg_id"`
 	SenderID  uuid.UUID `json:"sender_id" bson:"sender_id"`
 	Payload   string    `json:"payload" bson:"payload"`
 	CreatedAt int64     `json:"created_at,omitempty" bson:"created_at"`
+	// Seq orders the messages of the chat, it grows with every message sent to the chat.
+	Seq int64 `json:"seq,omitempty" bson:"seq"`
+	// Kind is empty for the messages queued before the kinds were introduced, they are text messages.
+	Kind MessageKind `json:"kind,omitempty" bson:"kind"`
+	// Trace carries the trace context of the sender while the message waits for the flusher in redis.
+	Trace map[string]string `json:"trace,omitempty" bson:"-"`
+}
+
+// KindOrText returns the kind of the message, the messages of no kind are text messages.
+func (m Message) KindOrText() MessageKind {
+	if m.Kind == "" {
+		return TextMessage
+	}
+	return m.Kind
 }
 
 type Messages []Message
 
 func (m Messages) Len() int           { return len(m) }
 func (m Messages) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
-func (m Messages) Less(i, j int) bool { return m[i].CreatedAt > m[j].CreatedAt }
+func (m Messages) Less(i, j int) bool { return m[i].Seq > m[j].Seq }
+
+// Window drops the duplicates and the messages out of the cursor of opts, which must be
+// resolved to a seq, and keeps at most opts.Limit messages closest to the cursor,
+// sorted from the newest to the oldest.
+func (m Messages) Window(opts Opts) Messages {
+	seen := make(map[uuid.UUID]struct{}, len(m))
+	window := make(Messages, 0, len(m))
+	for _, msg := range m {
+		if _, ok := seen[msg.MsgID]; ok {
+			continue
+		}
+		if opts.Before.Seq != 0 && msg.Seq >= opts.Before.Seq {
+			continue
+		}
+		if opts.After.Seq != 0 && msg.Seq <= opts.After.Seq {
+			continue
+		}
+		seen[msg.MsgID] = struct{}{}
+		window = append(window, msg)
+	}
+	sort.Sort(window)
+
+	if opts.Limit <= 0 || int64(len(window)) <= opts.Limit {
+		return window
+	}
+	if opts.After.IsSet() {
+		return window[int64(len(window))-opts.Limit:]
+	}
+	return window[:opts.Limit]
+}
+
+// NextCursor returns the seq the window described by opts is continued from,
+// nil if the window is the last one. Messages must be sorted.
+func (m Messages) NextCursor(opts Opts) *int64 {
+	if len(m) == 0 || int64(len(m)) < opts.Limit {
+		return nil
+	}
+	if opts.After.IsSet() {
+		return &m[0].Seq
+	}
+	return &m[len(m)-1].Seq
+}
@@ -0,0 +1,85 @@
+package models
+
+import "github.com/google/uuid"
+
+type NotificationType string
+
+const (
+	InfoMessage   NotificationType = "info"
+	ChatMessage   NotificationType = "chat"
+	ErrorMessage  NotificationType = "error"
+	TypingMessage NotificationType = "typing"
+	ReadMessage   NotificationType = "read"
+	AckMessage    NotificationType = "ack"
+	RoleMessage   NotificationType = "role"
+	UpdateMessage NotificationType = "chat_update"
+)
+
+// Notification is a type that gets encoded into a json document when communicating
+// with peer service. Type field describes whether the notification has service info
+// purpose or it is a message. Body may contain Message or other service structures.
+// The same documents are published on the chat_<id> channels of the message bus,
+// where Trace carries the trace context of the publisher. It is not sent to the peers.
+type Notification struct {
+	Type  NotificationType  `json:"type,omitempty"`
+	Body  interface{}       `json:"body,omitempty"`
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+type TypingState string
+
+const (
+	TypingStarted TypingState = "started"
+	TypingStopped TypingState = "stopped"
+)
+
+// TypingEvent is the body of a TypingMessage notification. ExpiresIn tells the
+// receivers in how many seconds the state should be considered stopped
+// if no other event comes.
+type TypingEvent struct {
+	ChatID    uuid.UUID   `json:"chat_id"`
+	UserID    uuid.UUID   `json:"user_id"`
+	State     TypingState `json:"state"`
+	ExpiresIn int64       `json:"expires_in,omitempty"`
+}
+
+// ReadEvent is the body of a ReadMessage notification, it tells the participants of the chat
+// that the user has read the chat up to the message with MsgID.
+type ReadEvent struct {
+	ChatID uuid.UUID `json:"chat_id"`
+	UserID uuid.UUID `json:"user_id"`
+	MsgID  uuid.UUID `json:"msg_id"`
+	Seq    int64     `json:"seq"`
+	ReadAt int64     `json:"read_at"`
+}
+
+// RoleEvent is the body of a RoleMessage notification, it tells the participants of the chat
+// that the role of the user has been changed by the participant with ChangedBy.
+type RoleEvent struct {
+	ChatID    uuid.UUID `json:"chat_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Role      ChatRole  `json:"role"`
+	ChangedBy uuid.UUID `json:"changed_by"`
+}
+
+type ChatAction string
+
+const (
+	ChatRenamedAction    ChatAction = "renamed"
+	ChatLeftAction       ChatAction = "left"
+	MembersRemovedAction ChatAction = "members_removed"
+	ChatDeletedAction    ChatAction = "deleted"
+	MessageDeletedAction ChatAction = "message_deleted"
+)
+
+// ChatUpdateEvent is the body of an UpdateMessage notification, it tells the participants
+// of the chat that the participant with UserID has changed the chat. Users, Name and MsgID
+// are set by the actions concerning them.
+type ChatUpdateEvent struct {
+	ChatID uuid.UUID   `json:"chat_id"`
+	Action ChatAction  `json:"action"`
+	UserID uuid.UUID   `json:"user_id"`
+	Users  []uuid.UUID `json:"users,omitempty"`
+	Name   string      `json:"name,omitempty"`
+	MsgID  *uuid.UUID  `json:"msg_id,omitempty"`
+}
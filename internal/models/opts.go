@@ -1,6 +1,21 @@
 package models
 
+import "github.com/google/uuid"
+
+// Cursor points to a message of the chat by its sequence number or, if Seq is 0, by its id.
+type Cursor struct {
+	Seq   int64
+	MsgID uuid.UUID
+}
+
+func (c Cursor) IsSet() bool {
+	return c.Seq != 0 || c.MsgID != uuid.Nil
+}
+
+// Opts describes a window of chat messages: at most Limit messages right before
+// or right after the cursor. The newest messages are fetched if no cursor is set.
 type Opts struct {
-	Page  int64
-	Limit int64
+	Limit  int64
+	Before Cursor
+	After  Cursor
 }
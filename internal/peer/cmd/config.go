@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/tracing"
+	"our-little-chatik/internal/pkg/validator"
+)
+
+// Config is the configuration of peer service.
+type Config struct {
+	Port     int             `config:"port" env:"PEER_PORT" required:"true" min:"1" max:"65535" usage:"HTTP port"`
+	Auth     config.Auth     `config:"auth"`
+	Redis    config.Redis    `config:"redis"`
+	Chats    ChatsConfig     `config:"chats"`
+	Tracing  tracing.Config  `config:"tracing"`
+	Shutdown graceful.Config `config:"shutdown"`
+	// Origins is parsed by AllowedOrigins.
+	Origins string `config:"allowed_origins" env:"PEER_ALLOWED_ORIGINS" usage:"comma-separated origins of the other sites allowed to connect with the Token cookie"`
+}
+
+// ChatsConfig is the address of the gRPC server of chat service.
+type ChatsConfig struct {
+	Host string `config:"host" env:"GRPC_CHATS_SERVER_HOST" required:"true"`
+	Port string `config:"port" env:"GRPC_CHATS_SERVER_PORT" required:"true" usage:"port in the :port form"`
+}
+
+// AllowedOrigins returns the origins of the other sites allowed to connect with the Token cookie.
+func (c *Config) AllowedOrigins() []string {
+	origins := make([]string, 0)
+	for _, origin := range strings.Split(c.Origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+func (c *Config) Validate(v *validator.Validator) {
+	v.Check(c.Chats.Port == "" || config.IsPortAddr(c.Chats.Port), "chats.port", "must be in the :port form")
+	for _, origin := range c.AllowedOrigins() {
+		u, err := url.Parse(origin)
+		v.Check(err == nil && u.Scheme != "" && u.Host != "", "allowed_origins", "must be origins like https://example.com")
+	}
+}
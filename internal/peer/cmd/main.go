@@ -1,80 +1,103 @@
 package main
 
 import (
+	"context"
 	"github.com/go-redis/redis"
 	"github.com/gorilla/mux"
 	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"log"
 	"net/http"
 	"os"
+	"our-little-chatik/internal/middleware"
 	"our-little-chatik/internal/peer/internal/delivery"
 	"our-little-chatik/internal/peer/internal/repo"
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/health"
+	"our-little-chatik/internal/pkg/metrics"
+	"our-little-chatik/internal/pkg/proto/chats"
+	"our-little-chatik/internal/pkg/tracing"
+	"strconv"
 )
 
-type DBConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-}
-
-type AppConfig struct {
-	Port  string
-	Redis DBConfig
-}
-
 func main() {
-	appConfig := AppConfig{}
-	redisHost := os.Getenv("REDIS_HOST")
-	if redisHost == "" {
-		panic("empty redis host")
-	}
-	redisPort := os.Getenv("REDIS_PORT")
-	if redisPort == "" {
-		panic("empty redis port")
-	}
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	if redisPassword == "" {
-		panic("empty redis password")
-	}
-	peerPort := os.Getenv("PEER_PORT")
-	if peerPort == "" {
-		panic("empty peer port")
+	if err := run(); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	appConfig.Port = peerPort
-	appConfig.Redis.Port = redisPort
-	appConfig.Redis.Host = redisHost
-	appConfig.Redis.Password = redisPassword
+func run() error {
+	cfg := Config{}
+	config.MustLoad("peer", &cfg)
 
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 
+	shutdownTracing, err := tracing.Init(context.Background(), "peer", cfg.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	redisClient := redis.NewClient(&redis.Options{
-		Addr:     appConfig.Redis.Host + ":" + appConfig.Redis.Port,
-		Password: appConfig.Redis.Password,
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
 	})
-	err := redisClient.Ping().Err()
+	err = redisClient.Ping().Err()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	// Set up a connection to the chat service for membership checks.
+	conn, err := grpc.Dial(cfg.Chats.Host+cfg.Chats.Port,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor(), tracing.UnaryClientInterceptor()))
+	if err != nil {
+		log.Fatalf("did not connect: %v", err)
 	}
+	defer conn.Close()
+	chatsClient := repo.NewChatDataClient(chats.NewChatsClient(conn))
+
+	ctx, stop := graceful.NotifyContext(context.Background())
+	defer stop()
+
 	peerRepo := repo.NewPeerRepository(redisClient)
-	peerHandler := delivery.NewPeerHandler(peerRepo, peerRepo)
+	sessions := delivery.NewSessionRegistry(peerRepo)
+	go sessions.Listen(ctx)
+
+	upgrader := delivery.NewUpgrader(cfg.AllowedOrigins())
+	peerHandler := delivery.NewPeerHandler(peerRepo, peerRepo, chatsClient, sessions, upgrader)
 
 	diffRepo := repo.NewDiffRepository(redisClient)
 
-	diffHandler := delivery.NewDiffHandler(peerRepo, diffRepo)
+	diffHandler := delivery.NewDiffHandler(peerRepo, diffRepo, chatsClient, sessions, upgrader)
 
 	r := mux.NewRouter()
+	r.Use(metrics.MuxMiddleware, tracing.MuxMiddleware)
+	r.Handle(metrics.Path, metrics.Handler())
+
+	checker := health.NewChecker()
+	checker.Add("redis", health.RedisV6(redisClient))
+	checker.Add("chats", health.GRPC(conn, chats.Chats_ServiceDesc.ServiceName))
+	r.Handle(health.LivePath, health.LiveHandler())
+	r.Handle(health.ReadyPath, checker)
+
+	ws := r.PathPrefix("/ws").Subrouter()
+	// Peers are identified by the same JWT the chat and users services issue,
+	// so the connection is rejected before the websocket handshake.
+	ws.Use(middleware.HTTPAuth([]byte(cfg.Auth.JWTKey)))
 
-	r.HandleFunc("/ws/chat", peerHandler.ConnectToChat)
-	r.HandleFunc("/ws/diff", diffHandler.ConnectToDiff)
+	ws.HandleFunc("/chat", peerHandler.ConnectToChat)
+	ws.HandleFunc("/diff", diffHandler.ConnectToDiff)
 
-	slog.Info("service started", "port", appConfig.Port)
+	slog.Info("service started", "port", cfg.Port)
 
 	srv := &http.Server{
 		Handler: r,
-		Addr:    ":" + appConfig.Port,
+		Addr:    ":" + strconv.Itoa(cfg.Port),
 	}
+	// The websockets are hijacked from the server, so it does not wait for them.
+	srv.RegisterOnShutdown(sessions.Shutdown)
 
-	log.Fatal(srv.ListenAndServe())
+	return graceful.Run(ctx, cfg.Shutdown.Timeout, graceful.HTTP("http", srv))
 }
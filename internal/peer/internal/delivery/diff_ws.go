@@ -4,91 +4,113 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"golang.org/x/exp/slog"
 	"log"
 	"net/http"
+	"our-little-chatik/internal/middleware"
 	"our-little-chatik/internal/models"
 	"our-little-chatik/internal/peer/internal"
 	models2 "our-little-chatik/internal/peer/internal/models"
 	"sync"
+	"time"
 )
 
 type DiffHandler struct {
 	peersMap sync.Map
 	repo     internal.PeerRepo
 	diffRepo internal.DiffRepo
+	chats    internal.ChatDataInteractor
+	sessions *SessionRegistry
+	upgrader *websocket.Upgrader
 }
 
-func NewDiffHandler(repo internal.PeerRepo, diffRepo internal.DiffRepo) *DiffHandler {
+func NewDiffHandler(repo internal.PeerRepo, diffRepo internal.DiffRepo,
+	chats internal.ChatDataInteractor, sessions *SessionRegistry, upgrader *websocket.Upgrader) *DiffHandler {
 	return &DiffHandler{
 		repo:     repo,
 		diffRepo: diffRepo,
+		chats:    chats,
+		sessions: sessions,
+		upgrader: upgrader,
 	}
 }
 
 func (h *DiffHandler) ConnectToDiff(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
-	peer, err := upgrader.Upgrade(w, r, nil)
+	peer, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Fatal("websocket conn failed", err)
+		// Upgrade has already replied to the client with an HTTP error.
+		slog.Error("websocket conn failed", "err", err.Error())
+		return
 	}
 
-	chatSession := NewDiffSession(userID, peer, h.repo, h.diffRepo)
+	chatSession := NewDiffSession(userID.String(), peer, h.repo, h.diffRepo, h.chats, h.sessions)
 	chatSession.Start()
 }
 
 // DiffSession represents a connected/active chats diff user
 type DiffSession struct {
-	userID   string
+	userID string
+	// connID tells apart the sessions of one user opened from different devices.
+	connID   string
 	peer     *websocket.Conn
 	repo     internal.PeerRepo
 	diffRepo internal.DiffRepo
+	chats    internal.ChatDataInteractor
+	sessions *SessionRegistry
+
+	// subscribed holds the chats of the current subscription the user is allowed to see.
+	subscribed map[uuid.UUID]struct{}
+	mu         sync.Mutex
+	writeMu    sync.Mutex
+	closeOnce  sync.Once
+	// done is closed once the session is over.
+	done chan struct{}
 }
 
 // NewDiffSession returns a new DiffSession
 func NewDiffSession(userID string, peer *websocket.Conn,
-	repo internal.PeerRepo, diffRepo internal.DiffRepo) *DiffSession {
-	return &DiffSession{userID: userID, peer: peer, repo: repo, diffRepo: diffRepo}
+	repo internal.PeerRepo, diffRepo internal.DiffRepo,
+	chats internal.ChatDataInteractor, sessions *SessionRegistry) *DiffSession {
+	return &DiffSession{
+		userID:     userID,
+		connID:     uuid.NewString(),
+		peer:       peer,
+		repo:       repo,
+		diffRepo:   diffRepo,
+		chats:      chats,
+		sessions:   sessions,
+		subscribed: make(map[uuid.UUID]struct{}),
+		done:       make(chan struct{}),
+	}
 }
 
 // Start starts the chat by reading messages sent by the peer and broadcasting the to redis pub-sub channel
 func (s *DiffSession) Start() {
-	usernameTaken, err := s.repo.CheckUserExists(context.Background(),
-		s.userID, fmt.Sprintf(models2.CommonFormat, "diff_users", s.userID))
+	connections, err := s.repo.AddConnection(context.Background(),
+		s.connID, fmt.Sprintf(models2.CommonFormat, "diff_users", s.userID))
 	if err != nil {
-		log.Println("unable to determine whether user exists -", s.userID)
-		s.notifyPeer(models2.Failed, map[string]any{
-			"description": retryMessage,
-		})
-		s.peer.Close()
-		return
-	}
-	if usernameTaken {
-		msg := fmt.Sprintf(usernameHasBeenTaken, s.userID)
-		s.notifyPeer(models2.Conflict, map[string]any{
-			"description": msg,
-		})
-		s.peer.Close()
-		return
-	}
-
-	err = s.repo.CreateUser(context.Background(),
-		s.userID, fmt.Sprintf(models2.CommonFormat, "diff_users", s.userID))
-	if err != nil {
-		log.Println("failed to add user to list of active chat diff users", s.userID)
+		log.Println("failed to add connection to list of active chat diff connections", s.userID)
 		s.notifyPeer(models2.Failed, map[string]any{
 			"description": retryMessage,
 		})
 		s.peer.Close()
 		return
 	}
+	s.sessions.addDiffSession(s)
+	go keepConnection(s.done, s.repo, s.connID, fmt.Sprintf(models2.CommonFormat, "diff_users", s.userID))
+	s.notifyPeer(models2.Established, map[string]any{
+		"connected_user_id": s.userID,
+		"connection_id":     s.connID,
+		"user_connections":  connections,
+	})
 
 	/*
 		this go-routine will exit when:
@@ -104,18 +126,27 @@ func (s *DiffSession) Start() {
 				_, ok := err.(*websocket.CloseError)
 				if ok {
 					log.Println("connection closed by user")
-					s.disconnect()
 				}
+				s.disconnect()
 				cancel()
 				return
 			}
 
+			var chatList []models.Chat
+			err = json.Unmarshal(bMsg, &chatList)
+			if err != nil {
+				continue
+			}
+
 			cancel()
 			ctx, cancel = context.WithCancel(context.Background())
 
-			var chatList []models.Chat
-			err = json.Unmarshal(bMsg, &chatList)
+			chatList, err = s.filterUserChats(ctx, chatList)
 			if err != nil {
+				slog.Error(err.Error())
+				continue
+			}
+			if len(chatList) == 0 {
 				continue
 			}
 
@@ -125,52 +156,175 @@ func (s *DiffSession) Start() {
 				continue
 			}
 
-			go func() {
+			go func(ctx context.Context) {
 				for {
 					select {
-					case msg := <-msgChan:
-						fmt.Printf("got your message: %s from %s\n", msg.Payload, msg.SenderID.String())
-						bMsg, err := json.Marshal(&msg)
-						if err != nil {
-							log.Println("failed to write message", err)
-							continue
-						}
-						err = s.peer.WriteMessage(websocket.TextMessage, bMsg)
-						if err != nil {
-							log.Println("failed to write message", err)
-						}
+					case notification := <-msgChan:
+						s.forward(notification)
 					case <-ctx.Done():
 						return
 					}
 				}
-			}()
+			}(ctx)
 		}
 	}()
 }
 
+// filterUserChats quietly drops the chats the user does not participate in
+// and remembers the rest as the current subscription.
+func (s *DiffSession) filterUserChats(ctx context.Context, chatList []models.Chat) ([]models.Chat, error) {
+	userID, err := uuid.Parse(s.userID)
+	if err != nil {
+		return nil, err
+	}
+	userChats, err := s.chats.GetUserChats(ctx, models.User{ID: userID})
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[uuid.UUID]struct{}, len(userChats))
+	for _, chat := range userChats {
+		allowed[chat.ChatID] = struct{}{}
+	}
+
+	subscribed := make(map[uuid.UUID]struct{})
+	filtered := make([]models.Chat, 0, len(chatList))
+	for _, chat := range chatList {
+		if _, ok := allowed[chat.ChatID]; !ok {
+			continue
+		}
+		if _, ok := subscribed[chat.ChatID]; ok {
+			continue
+		}
+		subscribed[chat.ChatID] = struct{}{}
+		filtered = append(filtered, chat)
+	}
+
+	s.mu.Lock()
+	s.subscribed = subscribed
+	s.mu.Unlock()
+	return filtered, nil
+}
+
+func (s *DiffSession) isSubscribed(chatID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.subscribed[chatID]
+	return ok
+}
+
+// forward writes the messages and the updates of the subscribed chats to the peer. The messages
+// are written as they are, the updates are wrapped into notifications.
+func (s *DiffSession) forward(notification models.Notification) {
+	var payload interface{}
+	switch body := notification.Body.(type) {
+	case *models.Message:
+		if !s.isSubscribed(body.ChatID) {
+			return
+		}
+		payload = body
+	case *models.ChatUpdateEvent:
+		if !s.isSubscribed(body.ChatID) {
+			return
+		}
+		// the peer learns why the chat is gone from the update itself
+		if s.losesAccess(body) {
+			s.forgetChat(body.ChatID)
+		}
+		payload = notification
+	default:
+		return
+	}
+	bMsg, err := json.Marshal(payload)
+	if err != nil {
+		log.Println("failed to write message", err)
+		return
+	}
+	err = s.write(bMsg)
+	if err != nil {
+		log.Println("failed to write message", err)
+	}
+}
+
+// losesAccess tells whether the update takes the chat away from the user of the session.
+func (s *DiffSession) losesAccess(event *models.ChatUpdateEvent) bool {
+	switch event.Action {
+	case models.ChatDeletedAction:
+		return true
+	case models.ChatLeftAction:
+		return event.UserID.String() == s.userID
+	case models.MembersRemovedAction:
+		for _, userID := range event.Users {
+			if userID.String() == s.userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forgetChat removes the chat from the subscription, it tells whether the chat has been there.
+func (s *DiffSession) forgetChat(chatID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.subscribed[chatID]
+	delete(s.subscribed, chatID)
+	return ok
+}
+
+// dropChat stops delivering the updates of the chat the user has lost access to.
+func (s *DiffSession) dropChat(chatID uuid.UUID) {
+	if s.forgetChat(chatID) {
+		s.notifyPeer(models2.Kicked, map[string]any{
+			"description": kickedMessage,
+			"chat_id":     chatID.String(),
+		})
+	}
+}
+
 func (s *DiffSession) notifyPeer(statusType models2.ConnectionStatusType,
 	properties map[string]any) {
 	status := models2.PeerConnectionStatus{
 		Status:     statusType,
 		Properties: properties,
 	}
-	notification := models2.Notification{
-		Type: models2.InfoMessage,
+	notification := models.Notification{
+		Type: models.InfoMessage,
 		Body: &status,
 	}
 	bNotification, _ := json.Marshal(notification)
-	err := s.peer.WriteMessage(websocket.TextMessage, bNotification)
+	err := s.write(bNotification)
 	if err != nil {
 		log.Println("failed to write message", err)
 	}
 }
 
+// write serializes writes to the websocket, as the connection supports only one concurrent writer.
+func (s *DiffSession) write(bMsg []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.peer.WriteMessage(websocket.TextMessage, bMsg)
+}
+
+// goAway tells the peer the service is shutting down and closes the session.
+func (s *DiffSession) goAway() {
+	err := s.peer.WriteControl(websocket.CloseMessage, goingAway, time.Now().Add(closeWriteTimeout))
+	if err != nil {
+		log.Println("failed to write close frame", err)
+	}
+	s.disconnect()
+}
+
 // Invoked when the user disconnects (websocket connection is closed). It performs cleanup activities
 func (s *DiffSession) disconnect() {
-	//remove user from SET
-	s.repo.RemoveUser(context.Background(),
-		s.userID, fmt.Sprintf(models2.CommonFormat, "diff_users", s.userID))
+	s.closeOnce.Do(func() {
+		s.sessions.removeDiffSession(s)
+		close(s.done)
 
-	//close websocket
-	s.peer.Close()
+		//remove the connection from ZSET, other devices of the user stay online
+		s.repo.RemoveConnection(context.Background(),
+			s.connID, fmt.Sprintf(models2.CommonFormat, "diff_users", s.userID))
+
+		//close websocket
+		s.peer.Close()
+	})
 }
@@ -0,0 +1,331 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"our-little-chatik/internal/models"
+	models2 "our-little-chatik/internal/peer/internal/models"
+	"our-little-chatik/internal/pkg/seq"
+	"our-little-chatik/internal/pkg/validator"
+	"time"
+)
+
+var tracer = otel.Tracer("our-little-chatik/internal/peer/internal/delivery")
+
+// startFrameSpan starts the trace of the frame sent by the peer. The frames come over
+// a long-living connection, hence every one of them starts a trace of its own.
+func (s *ChatSession) startFrameSpan(frameType models2.FrameType) (context.Context, trace.Span) {
+	return tracer.Start(s.ctx, string(frameType),
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("chat.id", s.chatID),
+			attribute.String("user.id", s.userID),
+		))
+}
+
+// handleFrame validates the frame sent by the peer and dispatches it to the handler of its type.
+func (s *ChatSession) handleFrame(bMsg []byte) {
+	frame := models2.DecodeFrame(bMsg)
+
+	v := validator.New()
+	models2.ValidateFrame(v, frame)
+	if !v.Valid() {
+		s.notifyError(models2.FrameError{
+			Code:   models2.InvalidFrame,
+			Frame:  frame.Type,
+			Errors: v.Errors,
+		})
+		return
+	}
+
+	switch frame.Type {
+	case models2.SendMessageFrame:
+		body, ok := decodeFrameBody(s, frame, models2.ValidateSendMessageBody)
+		if ok {
+			s.sendMessage(body)
+		}
+	case models2.TypingFrame:
+		body, ok := decodeFrameBody(s, frame, models2.ValidateTypingBody)
+		if ok {
+			s.setTyping(body.State)
+		}
+	case models2.TypingStartedFrame:
+		s.setTyping(models.TypingStarted)
+	case models2.TypingStoppedFrame:
+		s.setTyping(models.TypingStopped)
+	case models2.ReadFrame:
+		body, ok := decodeFrameBody(s, frame, models2.ValidateReadBody)
+		if ok {
+			s.markRead(body)
+		}
+	case models2.DeleteFrame:
+		body, ok := decodeFrameBody(s, frame, models2.ValidateDeleteBody)
+		if ok {
+			s.deleteMessage(body)
+		}
+	}
+}
+
+// decodeFrameBody decodes and validates the body of the frame. The peer is notified
+// about the error if the body is malformed or invalid.
+func decodeFrameBody[T any](s *ChatSession, frame models2.Frame,
+	validate func(v *validator.Validator, body T)) (T, bool) {
+	var body T
+	if len(frame.Body) == 0 {
+		s.notifyError(models2.FrameError{
+			Code:        models2.MalformedFrame,
+			Frame:       frame.Type,
+			Description: "body must be provided",
+		})
+		return body, false
+	}
+	err := json.Unmarshal(frame.Body, &body)
+	if err != nil {
+		s.notifyError(models2.FrameError{
+			Code:        models2.MalformedFrame,
+			Frame:       frame.Type,
+			Description: err.Error(),
+		})
+		return body, false
+	}
+
+	v := validator.New()
+	validate(v, body)
+	if !v.Valid() {
+		s.notifyError(models2.FrameError{
+			Code:   models2.InvalidFrame,
+			Frame:  frame.Type,
+			Errors: v.Errors,
+		})
+		return body, false
+	}
+	return body, true
+}
+
+func (s *ChatSession) setTyping(state models.TypingState) {
+	if state == models.TypingStarted {
+		s.startTyping()
+		return
+	}
+	s.stopTyping()
+}
+
+// dedupeWindow is the period a client generated message id is remembered for.
+const dedupeWindow = 10 * time.Minute
+
+const saveFailedReason = "failed to save the message. please try again"
+
+const channelPostDescription = "only the admins post to the channel"
+
+// sendMessage persists the message, acknowledges it to the sender and broadcasts it to the chat.
+// The members of a channel may not post to it, their messages are rejected.
+// A message retried with the same client id within dedupeWindow is acknowledged again
+// with the original id and is neither saved nor broadcast twice.
+func (s *ChatSession) sendMessage(body models2.SendMessageBody) {
+	ctx, span := s.startFrameSpan(models2.SendMessageFrame)
+	defer span.End()
+
+	if !s.canPost() {
+		s.notifyError(models2.FrameError{
+			Code:        models2.ForbiddenFrame,
+			Frame:       models2.SendMessageFrame,
+			Description: channelPostDescription,
+		})
+		return
+	}
+
+	chatID, err := uuid.Parse(s.chatID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	senderID, err := uuid.Parse(s.userID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	msg := models.Message{
+		MsgID:     uuid.New(),
+		Payload:   body.Payload,
+		Kind:      models.TextMessage,
+		ChatID:    chatID,
+		SenderID:  senderID,
+		CreatedAt: time.Now().Unix(),
+	}
+	msg.Seq, err = s.nextSeq(chatID)
+	if err != nil {
+		slog.Error("failed to assign message seq", "err", err.Error())
+		s.ackMessage(models2.AckFailed, body.ClientMsgID, msg, saveFailedReason)
+		return
+	}
+
+	clientMsgKey := ""
+	if body.ClientMsgID != "" {
+		clientMsgKey = fmt.Sprintf(models2.ClientMsgKeyFormat, s.userID, body.ClientMsgID)
+		original, reserved, err := s.repo.ReserveClientMsgID(s.ctx, clientMsgKey, msg, dedupeWindow)
+		if err != nil {
+			slog.Error("failed to reserve client message id", "err", err.Error())
+			s.ackMessage(models2.AckFailed, body.ClientMsgID, msg, saveFailedReason)
+			return
+		}
+		if !reserved {
+			s.ackMessage(models2.Duplicate, body.ClientMsgID, original, "")
+			return
+		}
+	}
+
+	// persist message
+	err = s.repo.SaveMessage(ctx, msg)
+	if err != nil {
+		slog.Error(err.Error())
+		if clientMsgKey != "" {
+			s.repo.ReleaseClientMsgID(s.ctx, clientMsgKey)
+		}
+		s.ackMessage(models2.AckFailed, body.ClientMsgID, msg, saveFailedReason)
+		return
+	}
+	// the ack goes before the broadcast, so the sender can match the broadcast message by its id
+	s.ackMessage(models2.Accepted, body.ClientMsgID, msg, "")
+	// the message ends the typing
+	s.stopTyping()
+	// Send via message bus
+	s.msgBus.SendMessageToChannel(ctx,
+		msg, fmt.Sprintf(models2.CommonFormat, "chat", s.chatID))
+}
+
+// nextSeq assigns the sequence number to a new message of the chat.
+func (s *ChatSession) nextSeq(chatID uuid.UUID) (int64, error) {
+	return seq.Next(s.ctx, s.repo, chatID, func(ctx context.Context, chatID uuid.UUID) (int64, error) {
+		return s.chats.GetLastSeq(ctx, models.Chat{ChatID: chatID})
+	})
+}
+
+// ackMessage tells the sender the message has been accepted or has failed.
+func (s *ChatSession) ackMessage(ackStatus models2.AckStatus, clientMsgID string,
+	msg models.Message, reason string) {
+	notification := models.Notification{
+		Type: models.AckMessage,
+		Body: &models2.MessageAck{
+			Status:      ackStatus,
+			ClientMsgID: clientMsgID,
+			MsgID:       msg.MsgID,
+			ChatID:      msg.ChatID,
+			CreatedAt:   msg.CreatedAt,
+			Seq:         msg.Seq,
+			Reason:      reason,
+		},
+	}
+	bNotification, _ := json.Marshal(notification)
+	err := s.write(bNotification)
+	if err != nil {
+		log.Println("failed to write message", err)
+	}
+}
+
+// markRead moves the read cursor of the user, chat service broadcasts the read event to the chat.
+func (s *ChatSession) markRead(body models2.ReadBody) {
+	ctx, span := s.startFrameSpan(models2.ReadFrame)
+	defer span.End()
+
+	chatID, err := uuid.Parse(s.chatID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	userID, err := uuid.Parse(s.userID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	err = s.chats.MarkRead(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: *body.MsgID})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			s.notifyError(models2.FrameError{
+				Code:   models2.InvalidFrame,
+				Frame:  models2.ReadFrame,
+				Errors: map[string]string{"msg_id": "message not found in the chat"},
+			})
+			return
+		}
+		slog.Error("failed to mark chat as read", "err", err.Error())
+		s.notifyError(models2.FrameError{
+			Code:        models2.FrameFailed,
+			Frame:       models2.ReadFrame,
+			Description: "failed to mark the chat as read. please try again",
+		})
+	}
+}
+
+// deleteMessage asks chat service to delete the message, chat service tells the participants about it.
+func (s *ChatSession) deleteMessage(body models2.DeleteBody) {
+	ctx, span := s.startFrameSpan(models2.DeleteFrame)
+	defer span.End()
+
+	chatID, err := uuid.Parse(s.chatID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	userID, err := uuid.Parse(s.userID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	err = s.chats.DeleteMessage(ctx, models.Chat{ChatID: chatID}, models.User{ID: userID},
+		models.Message{MsgID: *body.MsgID})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound:
+		s.notifyError(models2.FrameError{
+			Code:   models2.InvalidFrame,
+			Frame:  models2.DeleteFrame,
+			Errors: map[string]string{"msg_id": "message not found in the chat"},
+		})
+	case codes.PermissionDenied:
+		s.notifyError(models2.FrameError{
+			Code:        models2.ForbiddenFrame,
+			Frame:       models2.DeleteFrame,
+			Description: "only the sender and the admins delete the message",
+		})
+	default:
+		slog.Error("failed to delete message", "err", err.Error())
+		s.notifyError(models2.FrameError{
+			Code:        models2.FrameFailed,
+			Frame:       models2.DeleteFrame,
+			Description: "failed to delete the message. please try again",
+		})
+	}
+}
+
+// notifyError tells the peer its frame has been rejected.
+func (s *ChatSession) notifyError(frameErr models2.FrameError) {
+	notification := models.Notification{
+		Type: models.ErrorMessage,
+		Body: &frameErr,
+	}
+	bNotification, _ := json.Marshal(notification)
+	err := s.write(bNotification)
+	if err != nil {
+		log.Println("failed to write message", err)
+	}
+}
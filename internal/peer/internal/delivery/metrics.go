@@ -0,0 +1,16 @@
+package delivery
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	chatSessionKind = "chat"
+	diffSessionKind = "diff"
+)
+
+var activeSessions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "peer_active_sessions",
+	Help: "Number of the live websocket sessions by their kind.",
+}, []string{"kind"})
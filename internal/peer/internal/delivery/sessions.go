@@ -0,0 +1,200 @@
+package delivery
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"golang.org/x/exp/slog"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/peer/internal"
+	models2 "our-little-chatik/internal/peer/internal/models"
+	"sync"
+	"time"
+)
+
+// goingAway is the close frame the peers receive when the service shuts down,
+// so that they reconnect to another instance.
+var goingAway = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+
+// closeWriteTimeout bounds the time the close frame is written in.
+const closeWriteTimeout = time.Second
+
+// SessionRegistry keeps track of the live sessions of the service, so that they
+// can be closed when their users lose access to a chat.
+type SessionRegistry struct {
+	mu           sync.Mutex
+	chatSessions map[string]map[*ChatSession]struct{}
+	diffSessions map[string]map[*DiffSession]struct{}
+	events       internal.EventBus
+}
+
+func NewSessionRegistry(events internal.EventBus) *SessionRegistry {
+	return &SessionRegistry{
+		chatSessions: make(map[string]map[*ChatSession]struct{}),
+		diffSessions: make(map[string]map[*DiffSession]struct{}),
+		events:       events,
+	}
+}
+
+// Listen handles chat events until the context is cancelled.
+func (r *SessionRegistry) Listen(ctx context.Context) {
+	eventChan := r.events.SubscribeOnChatEvents(ctx)
+	for {
+		select {
+		case event := <-eventChan:
+			r.handleEvent(event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Shutdown sends the going away close frame to the live sessions and closes them.
+func (r *SessionRegistry) Shutdown() {
+	r.mu.Lock()
+	var chatSessions []*ChatSession
+	for _, userSessions := range r.chatSessions {
+		for s := range userSessions {
+			chatSessions = append(chatSessions, s)
+		}
+	}
+	var diffSessions []*DiffSession
+	for _, userSessions := range r.diffSessions {
+		for s := range userSessions {
+			diffSessions = append(diffSessions, s)
+		}
+	}
+	r.mu.Unlock()
+
+	slog.Info("closing sessions", "chat", len(chatSessions), "diff", len(diffSessions))
+	for _, s := range chatSessions {
+		s.goAway()
+	}
+	for _, s := range diffSessions {
+		s.goAway()
+	}
+}
+
+func (r *SessionRegistry) handleEvent(event models.ChatEvent) {
+	switch event.Type {
+	case models.UsersRemovedFromChat:
+		for _, userID := range event.Users {
+			for _, s := range r.chatSessionsOf(userID.String()) {
+				if s.chatID == event.ChatID.String() {
+					slog.Info("kicking user from chat", "user", s.userID, "chat", s.chatID)
+					s.kick()
+				}
+			}
+			for _, s := range r.diffSessionsOf(userID.String()) {
+				s.dropChat(event.ChatID)
+			}
+		}
+	case models.ChatRenamedForUsers:
+		// the rename is personal, so it is not published on the chat channel
+		// and only the sessions of the users who renamed the chat learn about it
+		for _, userID := range event.Users {
+			notification := models.Notification{
+				Type: models.UpdateMessage,
+				Body: &models.ChatUpdateEvent{
+					ChatID: event.ChatID,
+					Action: models.ChatRenamedAction,
+					UserID: userID,
+					Name:   event.Name,
+				},
+			}
+			for _, s := range r.chatSessionsOf(userID.String()) {
+				if s.chatID == event.ChatID.String() {
+					_ = s.forwardToPeer(notification)
+				}
+			}
+		}
+	}
+}
+
+func (r *SessionRegistry) addChatSession(s *ChatSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.chatSessions[s.userID]; !ok {
+		r.chatSessions[s.userID] = make(map[*ChatSession]struct{})
+	}
+	if _, ok := r.chatSessions[s.userID][s]; !ok {
+		activeSessions.WithLabelValues(chatSessionKind).Inc()
+	}
+	r.chatSessions[s.userID][s] = struct{}{}
+}
+
+func (r *SessionRegistry) removeChatSession(s *ChatSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.chatSessions[s.userID][s]; ok {
+		activeSessions.WithLabelValues(chatSessionKind).Dec()
+	}
+	delete(r.chatSessions[s.userID], s)
+	if len(r.chatSessions[s.userID]) == 0 {
+		delete(r.chatSessions, s.userID)
+	}
+}
+
+func (r *SessionRegistry) chatSessionsOf(userID string) []*ChatSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]*ChatSession, 0, len(r.chatSessions[userID]))
+	for s := range r.chatSessions[userID] {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+func (r *SessionRegistry) addDiffSession(s *DiffSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.diffSessions[s.userID]; !ok {
+		r.diffSessions[s.userID] = make(map[*DiffSession]struct{})
+	}
+	if _, ok := r.diffSessions[s.userID][s]; !ok {
+		activeSessions.WithLabelValues(diffSessionKind).Inc()
+	}
+	r.diffSessions[s.userID][s] = struct{}{}
+}
+
+func (r *SessionRegistry) removeDiffSession(s *DiffSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.diffSessions[s.userID][s]; ok {
+		activeSessions.WithLabelValues(diffSessionKind).Dec()
+	}
+	delete(r.diffSessions[s.userID], s)
+	if len(r.diffSessions[s.userID]) == 0 {
+		delete(r.diffSessions, s.userID)
+	}
+}
+
+func (r *SessionRegistry) diffSessionsOf(userID string) []*DiffSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]*DiffSession, 0, len(r.diffSessions[userID]))
+	for s := range r.diffSessions[userID] {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// connectionHeartbeat is how often a live session refreshes its entry in the user connections,
+// a few heartbeats may fail before the entry expires.
+const connectionHeartbeat = models2.ConnectionTTL / 3
+
+// keepConnection refreshes the entry of the connection in connSet until done is closed.
+func keepConnection(done <-chan struct{}, repo internal.PeerRepo, connID string, connSet string) {
+	ticker := time.NewTicker(connectionHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := repo.RefreshConnection(context.Background(), connID, connSet)
+			if err != nil {
+				slog.Error("failed to refresh connection", "conn", connID, "err", err.Error())
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slog"
+	"our-little-chatik/internal/models"
+	models2 "our-little-chatik/internal/peer/internal/models"
+	"time"
+)
+
+// typingTimeout is the period of silence after which the user is not considered typing anymore.
+// Clients are expected to repeat typing_started while the user keeps typing.
+const typingTimeout = 5 * time.Second
+
+// startTyping tells the other participants the user is typing and (re)arms
+// the timer that stops the typing after typingTimeout of silence.
+func (s *ChatSession) startTyping() {
+	s.typingMu.Lock()
+	defer s.typingMu.Unlock()
+
+	if s.typingTimer != nil {
+		s.typingTimer.Stop()
+	}
+	s.typingSeq++
+	seq := s.typingSeq
+	s.typingTimer = time.AfterFunc(typingTimeout, func() {
+		s.expireTyping(seq)
+	})
+	s.publishTyping(models.TypingStarted)
+}
+
+// stopTyping tells the other participants the user has stopped typing.
+// Nothing is published if the user was not typing.
+func (s *ChatSession) stopTyping() {
+	s.typingMu.Lock()
+	defer s.typingMu.Unlock()
+
+	if s.typingTimer == nil {
+		return
+	}
+	s.typingTimer.Stop()
+	s.typingTimer = nil
+	s.publishTyping(models.TypingStopped)
+}
+
+// expireTyping stops the typing unless the user has sent another typing frame since the timer was armed.
+func (s *ChatSession) expireTyping(seq uint64) {
+	s.typingMu.Lock()
+	defer s.typingMu.Unlock()
+
+	if s.typingTimer == nil || s.typingSeq != seq {
+		return
+	}
+	s.typingTimer = nil
+	s.publishTyping(models.TypingStopped)
+}
+
+// publishTyping sends the typing event to the chat channel. Typing events are
+// short-living, so they are never persisted.
+func (s *ChatSession) publishTyping(state models.TypingState) {
+	chatID, err := uuid.Parse(s.chatID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	userID, err := uuid.Parse(s.userID)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	event := models.TypingEvent{
+		ChatID: chatID,
+		UserID: userID,
+		State:  state,
+	}
+	if state == models.TypingStarted {
+		event.ExpiresIn = int64(typingTimeout / time.Second)
+	}
+	s.msgBus.PublishNotification(context.Background(), models.Notification{
+		Type: models.TypingMessage,
+		Body: &event,
+	}, fmt.Sprintf(models2.CommonFormat, "chat", s.chatID))
+}
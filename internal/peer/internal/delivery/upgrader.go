@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"our-little-chatik/internal/middleware"
+)
+
+// NewUpgrader returns the upgrader of the chat and the diff websockets. The browsers send
+// the Token cookie along with the requests of any site, so a connection authenticated by
+// the cookie is only accepted from the origin of the peer itself or an allowed one.
+func NewUpgrader(allowedOrigins []string) *websocket.Upgrader {
+	allowed := make(map[string]struct{}, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[normalizeOrigin(origin)] = struct{}{}
+	}
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		// The token subprotocol must be echoed back to the client who used it
+		// for authentication, otherwise browsers drop the connection.
+		Subprotocols: []string{middleware.TokenSubprotocol},
+		CheckOrigin: func(r *http.Request) bool {
+			return checkOrigin(r, allowed)
+		},
+	}
+}
+
+// checkOrigin lets any origin connect with the token passed explicitly, a foreign site
+// does not know it, and lets the clients that are not browsers connect without an origin.
+func checkOrigin(r *http.Request, allowed map[string]struct{}) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !middleware.AuthenticatedByCookie(r.Context()) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	_, ok := allowed[normalizeOrigin(origin)]
+	return ok
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+}
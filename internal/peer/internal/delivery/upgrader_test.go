@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"our-little-chatik/internal/middleware"
+	"our-little-chatik/internal/pkg"
+)
+
+func TestNewUpgrader(t *testing.T) {
+	testKey := []byte("test")
+	claims := &pkg.JwtCustomClaims{
+		UserID: uuid.NewString(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upgrader := NewUpgrader([]string{"https://allowed.example.com/"})
+	srv := httptest.NewServer(middleware.HTTPAuth(testKey)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	})))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		wantCode int
+	}{
+		{
+			name:     "cookie from a foreign site",
+			header:   http.Header{"Cookie": {"Token=" + token}, "Origin": {"https://evil.example.com"}},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "cookie from the same origin",
+			header:   http.Header{"Cookie": {"Token=" + token}, "Origin": {srv.URL}},
+			wantCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:     "cookie from an allowed origin",
+			header:   http.Header{"Cookie": {"Token=" + token}, "Origin": {"https://Allowed.example.com"}},
+			wantCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name:     "cookie without an origin",
+			header:   http.Header{"Cookie": {"Token=" + token}},
+			wantCode: http.StatusSwitchingProtocols,
+		},
+		{
+			name: "token subprotocol from a foreign site",
+			header: http.Header{"Sec-WebSocket-Protocol": {middleware.TokenSubprotocol + ", " + token},
+				"Origin": {"https://evil.example.com"}},
+			wantCode: http.StatusSwitchingProtocols,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := websocket.DefaultDialer.Dial(wsURL, tt.header)
+			if conn != nil {
+				conn.Close()
+			}
+			if resp == nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("Dial() status = %v, want %v", resp.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}
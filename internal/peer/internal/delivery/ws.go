@@ -9,110 +9,143 @@ import (
 	"golang.org/x/exp/slog"
 	"log"
 	"net/http"
+	"our-little-chatik/internal/middleware"
 	"our-little-chatik/internal/models"
 	"our-little-chatik/internal/peer/internal"
 	models2 "our-little-chatik/internal/peer/internal/models"
+	"sync"
 	"time"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
-
 type PeerHandler struct {
-	repo   internal.PeerRepo
-	msgBus internal.MessageBus
+	repo     internal.PeerRepo
+	msgBus   internal.MessageBus
+	chats    internal.ChatDataInteractor
+	sessions *SessionRegistry
+	upgrader *websocket.Upgrader
 }
 
-func NewPeerHandler(repo internal.PeerRepo, msgBus internal.MessageBus) *PeerHandler {
+func NewPeerHandler(repo internal.PeerRepo, msgBus internal.MessageBus,
+	chats internal.ChatDataInteractor, sessions *SessionRegistry, upgrader *websocket.Upgrader) *PeerHandler {
 	return &PeerHandler{
-		repo:   repo,
-		msgBus: msgBus,
+		repo:     repo,
+		msgBus:   msgBus,
+		chats:    chats,
+		sessions: sessions,
+		upgrader: upgrader,
 	}
 }
 
 func (h *PeerHandler) ConnectToChat(w http.ResponseWriter, r *http.Request) {
-	chatID := r.URL.Query().Get("chat_id")
-	userID := r.URL.Query().Get("user_id")
-
-	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	chatIDStr := r.URL.Query().Get("chat_id")
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
 
-	if chatID == "" || userID == "" {
+	chatID, err := uuid.Parse(chatIDStr)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	peer, err := upgrader.Upgrade(w, r, nil)
+	// Non-members and unknown chats look the same to the peer.
+	membership, err := h.chats.GetMembership(r.Context(), models.Chat{ChatID: chatID}, models.User{ID: userID})
 	if err != nil {
-		log.Fatal("websocket conn failed", err)
+		slog.Error("failed to check chat membership", "err", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !membership.IsMember {
+		w.WriteHeader(http.StatusForbidden)
+		return
 	}
 
-	chatSession := NewChatSession(userID, peer, chatID, h.repo, h.msgBus)
+	peer, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client with an HTTP error.
+		slog.Error("websocket conn failed", "err", err.Error())
+		return
+	}
+
+	chatSession := NewChatSession(userID.String(), peer, chatID.String(), membership,
+		h.repo, h.msgBus, h.chats, h.sessions)
 	chatSession.Start()
 }
 
 // ChatSession represents a connected/active chat user
 type ChatSession struct {
-	userID   string
+	userID string
+	// connID tells apart the sessions of one user opened from different devices.
+	connID   string
 	peerConn *websocket.Conn
 	repo     internal.PeerRepo
 	chatID   string
 	msgBus   internal.MessageBus
+	chats    internal.ChatDataInteractor
+	sessions *SessionRegistry
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+
+	// typingMu guards the typing state of the user, typingSeq tells apart
+	// the expiration timers of consequent typing frames.
+	typingMu    sync.Mutex
+	typingTimer *time.Timer
+	typingSeq   uint64
+
+	// membershipMu guards the membership, the role of the user changes along the session.
+	membershipMu sync.Mutex
+	membership   models2.Membership
 }
 
 // NewChatSession returns a new ChatSession
-func NewChatSession(userID string, peerConn *websocket.Conn, chatID string,
-	repo internal.PeerRepo, msgBus internal.MessageBus) *ChatSession {
+func NewChatSession(userID string, peerConn *websocket.Conn, chatID string, membership models2.Membership,
+	repo internal.PeerRepo, msgBus internal.MessageBus, chats internal.ChatDataInteractor,
+	sessions *SessionRegistry) *ChatSession {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ChatSession{
-		userID:   userID,
-		peerConn: peerConn,
-		chatID:   chatID,
-		repo:     repo,
-		msgBus:   msgBus,
+		userID:     userID,
+		connID:     uuid.NewString(),
+		peerConn:   peerConn,
+		chatID:     chatID,
+		repo:       repo,
+		msgBus:     msgBus,
+		chats:      chats,
+		sessions:   sessions,
+		ctx:        ctx,
+		cancel:     cancel,
+		membership: membership,
 	}
 }
 
-const usernameHasBeenTaken = "username %s is already taken. please retry with a different name"
 const retryMessage = "failed to connect. please try again"
+const kickedMessage = "you are not a member of the chat anymore"
 const welcome = "Welcome %s!"
 
 // Start starts the chat by reading messages sent by the peer and broadcasting the to redis pub-sub channel
 func (s *ChatSession) Start() {
-	usernameTaken, err := s.repo.CheckUserExists(context.Background(),
-		s.userID, fmt.Sprintf(models2.CommonFormat, "users", s.userID))
+	connections, err := s.repo.AddConnection(context.Background(),
+		s.connID, fmt.Sprintf(models2.CommonFormat, "users", s.userID))
 	if err != nil {
-		log.Println("unable to determine whether user exists -", s.userID)
-		s.notifyPeer(models2.Failed, map[string]any{
-			"description": retryMessage,
-		})
-		s.peerConn.Close()
-		return
-	}
-	if usernameTaken {
-		msg := fmt.Sprintf(usernameHasBeenTaken, s.userID)
-		s.notifyPeer(models2.Conflict, map[string]any{
-			"description": msg,
-		})
-		s.peerConn.Close()
-		return
-	}
-
-	err = s.repo.CreateUser(context.Background(),
-		s.userID, fmt.Sprintf(models2.CommonFormat, "users", s.userID))
-	if err != nil {
-		log.Println("failed to add user to list of active chat users", s.userID)
+		log.Println("failed to add connection to list of active chat connections", s.userID)
 		s.notifyPeer(models2.Failed, map[string]any{
 			"description": retryMessage,
 		})
 		s.peerConn.Close()
 		return
 	}
+	s.sessions.addChatSession(s)
+	go keepConnection(s.ctx.Done(), s.repo, s.connID, fmt.Sprintf(models2.CommonFormat, "users", s.userID))
 
 	/*
 		this go-routine will exit when:
 		(1) the user disconnects from chat manually
-		(2) the app is closed
+		(2) the user is kicked from the chat
+		(3) the app is closed
 	*/
 	go func() {
 		log.Println("user joined", s.userID)
@@ -123,54 +156,34 @@ func (s *ChatSession) Start() {
 				_, ok := err.(*websocket.CloseError)
 				if ok {
 					log.Println("connection closed by user")
-					s.disconnect()
 				}
+				s.disconnect()
 				return
 			}
 
-			chatID, err := uuid.Parse(s.chatID)
-			if err != nil {
-				slog.Error(err.Error())
-				return
-			}
-			senderID, err := uuid.Parse(s.userID)
-			if err != nil {
-				slog.Error(err.Error())
-				return
-			}
-
-			msg := models.Message{
-				MsgID:     uuid.New(),
-				Payload:   string(bMsg),
-				ChatID:    chatID,
-				SenderID:  senderID,
-				CreatedAt: time.Now().Unix(),
-			}
-			// persist message
-			err = s.repo.SaveMessage(msg)
-			if err != nil {
-				slog.Error(err.Error())
-			}
-			// Send via message bus
-			s.msgBus.SendMessageToChannel(context.Background(),
-				msg, fmt.Sprintf(models2.CommonFormat, "chat", s.chatID))
+			s.handleFrame(bMsg)
 		}
 	}()
 
 	readyChan := make(chan struct{})
 	go func() {
-		// subscribe on messages from the message bus
-		msgChan := s.msgBus.SubscribeOnChatMessages(context.Background(),
+		// subscribe on messages and other notifications from the message bus
+		notificationChan := s.msgBus.SubscribeOnChatNotifications(s.ctx,
 			fmt.Sprintf(models2.CommonFormat, "chat", s.chatID), readyChan)
 
 		for {
 			select {
-			case msg := <-msgChan:
-				err := sendMessageToPeer(s.peerConn, msg)
+			case notification := <-notificationChan:
+				if s.isOwnTyping(notification) {
+					continue
+				}
+				s.trackOwnRole(notification)
+				err := s.forwardToPeer(notification)
 				if err != nil {
 					slog.Error(err.Error())
-					break
 				}
+			case <-s.ctx.Done():
+				return
 			}
 		}
 	}()
@@ -179,21 +192,67 @@ func (s *ChatSession) Start() {
 	s.notifyPeer(models2.Established, map[string]any{
 		"connected_user_id": s.userID,
 		"connected_chat_id": s.chatID,
+		"connection_id":     s.connID,
+		"user_connections":  connections,
 	})
 }
 
-func sendMessageToPeer(peer *websocket.Conn, msg models.Message) error {
-	notification := models2.Notification{
-		Type: models2.ChatMessage,
-		Body: &msg,
+// isOwnTyping tells whether the notification is a typing event of the session user,
+// the user is not notified about their own typing on other devices.
+func (s *ChatSession) isOwnTyping(notification models.Notification) bool {
+	if notification.Type != models.TypingMessage {
+		return false
+	}
+	body, ok := notification.Body.(json.RawMessage)
+	if !ok {
+		return false
+	}
+	event := models.TypingEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		slog.Error(err.Error())
+		return true
+	}
+	return event.UserID.String() == s.userID
+}
+
+// trackOwnRole keeps the role of the session user up to date, as it tells whether they may post.
+func (s *ChatSession) trackOwnRole(notification models.Notification) {
+	if notification.Type != models.RoleMessage {
+		return
+	}
+	body, ok := notification.Body.(json.RawMessage)
+	if !ok {
+		return
+	}
+	event := models.RoleEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	if event.UserID.String() != s.userID {
+		return
 	}
+	s.membershipMu.Lock()
+	defer s.membershipMu.Unlock()
+	s.membership.Role = event.Role
+}
+
+// canPost tells whether the session user may send messages to the chat.
+func (s *ChatSession) canPost() bool {
+	s.membershipMu.Lock()
+	defer s.membershipMu.Unlock()
+	return s.membership.CanPost()
+}
+
+// forwardToPeer writes the notification to the peer, the trace context of the bus is not sent along.
+func (s *ChatSession) forwardToPeer(notification models.Notification) error {
+	notification.Trace = nil
 	bMsg, err := json.Marshal(&notification)
 	if err != nil {
 		slog.Error(err.Error())
 		return err
 	}
-	peer.WriteMessage(websocket.TextMessage, bMsg)
-	return nil
+	return s.write(bMsg)
 }
 
 func (s *ChatSession) notifyPeer(statusType models2.ConnectionStatusType,
@@ -202,23 +261,55 @@ func (s *ChatSession) notifyPeer(statusType models2.ConnectionStatusType,
 		Status:     statusType,
 		Properties: properties,
 	}
-	notification := models2.Notification{
-		Type: models2.InfoMessage,
+	notification := models.Notification{
+		Type: models.InfoMessage,
 		Body: &status,
 	}
 	bNotification, _ := json.Marshal(notification)
-	err := s.peerConn.WriteMessage(websocket.TextMessage, bNotification)
+	err := s.write(bNotification)
 	if err != nil {
 		log.Println("failed to write message", err)
 	}
 }
 
+// write serializes writes to the websocket, as the connection supports only one concurrent writer.
+func (s *ChatSession) write(bMsg []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.peerConn.WriteMessage(websocket.TextMessage, bMsg)
+}
+
+// kick notifies the peer it has lost access to the chat and closes the session.
+func (s *ChatSession) kick() {
+	s.notifyPeer(models2.Kicked, map[string]any{
+		"description": kickedMessage,
+		"chat_id":     s.chatID,
+	})
+	s.disconnect()
+}
+
+// goAway tells the peer the service is shutting down and closes the session.
+func (s *ChatSession) goAway() {
+	err := s.peerConn.WriteControl(websocket.CloseMessage, goingAway, time.Now().Add(closeWriteTimeout))
+	if err != nil {
+		log.Println("failed to write close frame", err)
+	}
+	s.disconnect()
+}
+
 // Invoked when the user disconnects (websocket connection is closed). It performs cleanup activities
 func (s *ChatSession) disconnect() {
-	//remove user from SET
-	s.repo.RemoveUser(context.Background(),
-		s.userID, fmt.Sprintf(models2.CommonFormat, "users", s.userID))
+	s.closeOnce.Do(func() {
+		s.sessions.removeChatSession(s)
+		s.stopTyping()
+
+		//stop the subscription and the heartbeat, then remove the connection from ZSET,
+		//other devices of the user stay online
+		s.cancel()
+		s.repo.RemoveConnection(context.Background(),
+			s.connID, fmt.Sprintf(models2.CommonFormat, "users", s.userID))
 
-	//close websocket
-	s.peerConn.Close()
+		//close websocket
+		s.peerConn.Close()
+	})
 }
@@ -3,20 +3,40 @@ package internal
 import (
 	"context"
 	"our-little-chatik/internal/models"
+	models2 "our-little-chatik/internal/peer/internal/models"
+	"our-little-chatik/internal/pkg/seq"
+	"time"
 )
 
 type PeerRepo interface {
-	CheckUserExists(ctx context.Context, user string, userSet string) (bool, error)
-	CreateUser(ctx context.Context, user string, userSet string) error
-	RemoveUser(ctx context.Context, user string, userSet string)
-	SaveMessage(message models.Message) error
+	AddConnection(ctx context.Context, connID string, connSet string) (int64, error)
+	RemoveConnection(ctx context.Context, connID string, connSet string)
+	RefreshConnection(ctx context.Context, connID string, connSet string) error
+	SaveMessage(ctx context.Context, message models.Message) error
+	ReserveClientMsgID(ctx context.Context, key string, message models.Message,
+		window time.Duration) (models.Message, bool, error)
+	ReleaseClientMsgID(ctx context.Context, key string)
+	seq.Counter
 }
 
 type MessageBus interface {
-	SubscribeOnChatMessages(ctx context.Context, chatChannel string, readyChan chan struct{}) chan models.Message
+	SubscribeOnChatNotifications(ctx context.Context, chatChannel string, readyChan chan struct{}) chan models.Notification
 	SendMessageToChannel(ctx context.Context, msg models.Message, chatChannel string)
+	PublishNotification(ctx context.Context, notification models.Notification, chatChannel string)
 }
 
 type DiffRepo interface {
-	SubscribeToChats(ctx context.Context, chats []models.Chat) (chan models.Message, error)
+	SubscribeToChats(ctx context.Context, chats []models.Chat) (chan models.Notification, error)
+}
+
+type ChatDataInteractor interface {
+	GetMembership(ctx context.Context, chat models.Chat, user models.User) (models2.Membership, error)
+	GetUserChats(ctx context.Context, user models.User) ([]models.Chat, error)
+	MarkRead(ctx context.Context, chat models.Chat, user models.User, message models.Message) error
+	GetLastSeq(ctx context.Context, chat models.Chat) (int64, error)
+	DeleteMessage(ctx context.Context, chat models.Chat, user models.User, message models.Message) error
+}
+
+type EventBus interface {
+	SubscribeOnChatEvents(ctx context.Context) chan models.ChatEvent
 }
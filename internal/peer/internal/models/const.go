@@ -1,3 +1,13 @@
 package models
 
+import "time"
+
 const CommonFormat = "%s_%s"
+
+// ClientMsgKeyFormat is the key of the message sent by the user (first) with
+// the client generated id (second).
+const ClientMsgKeyFormat = "client_msg_%s_%s"
+
+// ConnectionTTL is how long an entry of the user connections outlives the last heartbeat
+// of its session, so the connections of a crashed instance do not stay online forever.
+const ConnectionTTL = 90 * time.Second
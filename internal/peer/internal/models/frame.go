@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"our-little-chatik/internal/models"
+	"our-little-chatik/internal/pkg/validator"
+)
+
+// ProtocolVersion is the version of the client-to-server protocol the service speaks.
+const ProtocolVersion = 1
+
+const maxPayloadLength = 4096
+
+const maxClientMsgIDLength = 64
+
+type FrameType string
+
+const (
+	SendMessageFrame   FrameType = "send_message"
+	TypingFrame        FrameType = "typing"
+	TypingStartedFrame FrameType = "typing_started"
+	TypingStoppedFrame FrameType = "typing_stopped"
+	ReadFrame          FrameType = "read"
+	DeleteFrame        FrameType = "delete"
+)
+
+// Frame is a type that gets decoded from a json document a peer sends to the service.
+// It mirrors models.Notification: Type field describes the action the peer asks for and Body
+// holds the arguments of the action, e.g. SendMessageBody.
+type Frame struct {
+	Version int             `json:"version,omitempty"`
+	Type    FrameType       `json:"type"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// SendMessageBody holds the text of the message and the optional id generated by the client.
+// Messages retried with the same ClientMsgID are not duplicated.
+type SendMessageBody struct {
+	Payload     string `json:"payload"`
+	ClientMsgID string `json:"client_msg_id,omitempty"`
+}
+
+type TypingBody struct {
+	State models.TypingState `json:"state"`
+}
+
+type ReadBody struct {
+	MsgID *uuid.UUID `json:"msg_id"`
+}
+
+type DeleteBody struct {
+	MsgID *uuid.UUID `json:"msg_id"`
+}
+
+// DecodeFrame decodes a frame sent by a peer. The clients that are not aware of the
+// protocol send the text of a message as is, so everything that is not a json object
+// with a type is treated as a plain text message.
+func DecodeFrame(bMsg []byte) Frame {
+	frame := Frame{}
+	err := json.Unmarshal(bMsg, &frame)
+	if err != nil || frame.Type == "" {
+		body, _ := json.Marshal(&SendMessageBody{Payload: string(bMsg)})
+		return Frame{Version: ProtocolVersion, Type: SendMessageFrame, Body: body}
+	}
+	if frame.Version == 0 {
+		frame.Version = ProtocolVersion
+	}
+	return frame
+}
+
+func ValidateFrame(v *validator.Validator, frame Frame) {
+	v.Check(frame.Version == ProtocolVersion, "version", "unsupported protocol version")
+	v.Check(validator.In(string(frame.Type), string(SendMessageFrame), string(TypingFrame),
+		string(TypingStartedFrame), string(TypingStoppedFrame), string(ReadFrame),
+		string(DeleteFrame)), "type", "unknown frame type")
+}
+
+func ValidateSendMessageBody(v *validator.Validator, body SendMessageBody) {
+	v.Check(body.Payload != "", "payload", "must be provided")
+	v.Check(len(body.Payload) <= maxPayloadLength, "payload", "must not be more than 4096 bytes")
+	v.Check(len(body.ClientMsgID) <= maxClientMsgIDLength, "client_msg_id", "must not be more than 64 bytes")
+}
+
+func ValidateTypingBody(v *validator.Validator, body TypingBody) {
+	v.Check(validator.In(string(body.State), string(models.TypingStarted), string(models.TypingStopped)),
+		"state", "must be either started or stopped")
+}
+
+func ValidateReadBody(v *validator.Validator, body ReadBody) {
+	v.Check(body.MsgID != nil, "msg_id", "must be provided")
+	if body.MsgID != nil {
+		v.Check(*body.MsgID != uuid.Nil, "msg_id", "must be a correct uuid value")
+	}
+}
+
+func ValidateDeleteBody(v *validator.Validator, body DeleteBody) {
+	v.Check(body.MsgID != nil, "msg_id", "must be provided")
+	if body.MsgID != nil {
+		v.Check(*body.MsgID != uuid.Nil, "msg_id", "must be a correct uuid value")
+	}
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"our-little-chatik/internal/pkg/validator"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeFrame(t *testing.T) {
+	plainBody, _ := json.Marshal(&SendMessageBody{Payload: "hello"})
+	jsonTextBody, _ := json.Marshal(&SendMessageBody{Payload: `{"payload":"hello"}`})
+
+	tests := []struct {
+		name string
+		bMsg []byte
+		want Frame
+	}{
+		{
+			name: "typed frame",
+			bMsg: []byte(`{"version":1,"type":"send_message","body":{"payload":"hello"}}`),
+			want: Frame{
+				Version: ProtocolVersion,
+				Type:    SendMessageFrame,
+				Body:    json.RawMessage(`{"payload":"hello"}`),
+			},
+		},
+		{
+			name: "version defaults to the current one",
+			bMsg: []byte(`{"type":"typing","body":{"state":"started"}}`),
+			want: Frame{
+				Version: ProtocolVersion,
+				Type:    TypingFrame,
+				Body:    json.RawMessage(`{"state":"started"}`),
+			},
+		},
+		{
+			name: "plain text falls back to a message",
+			bMsg: []byte("hello"),
+			want: Frame{
+				Version: ProtocolVersion,
+				Type:    SendMessageFrame,
+				Body:    plainBody,
+			},
+		},
+		{
+			name: "json without a type falls back to a message",
+			bMsg: []byte(`{"payload":"hello"}`),
+			want: Frame{
+				Version: ProtocolVersion,
+				Type:    SendMessageFrame,
+				Body:    jsonTextBody,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeFrame(tt.bMsg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeFrame() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFrame(t *testing.T) {
+	testMsgID := uuid.New()
+	nilMsgID := uuid.Nil
+
+	tests := []struct {
+		name      string
+		validate  func(v *validator.Validator)
+		wantValid bool
+	}{
+		{
+			name: "valid frame",
+			validate: func(v *validator.Validator) {
+				ValidateFrame(v, Frame{Version: ProtocolVersion, Type: ReadFrame})
+			},
+			wantValid: true,
+		},
+		{
+			name: "typing frame without body",
+			validate: func(v *validator.Validator) {
+				ValidateFrame(v, Frame{Version: ProtocolVersion, Type: TypingStartedFrame})
+			},
+			wantValid: true,
+		},
+		{
+			name: "unsupported version",
+			validate: func(v *validator.Validator) {
+				ValidateFrame(v, Frame{Version: ProtocolVersion + 1, Type: ReadFrame})
+			},
+			wantValid: false,
+		},
+		{
+			name: "unknown type",
+			validate: func(v *validator.Validator) {
+				ValidateFrame(v, Frame{Version: ProtocolVersion, Type: "unknown"})
+			},
+			wantValid: false,
+		},
+		{
+			name: "empty message",
+			validate: func(v *validator.Validator) {
+				ValidateSendMessageBody(v, SendMessageBody{})
+			},
+			wantValid: false,
+		},
+		{
+			name: "too long client message id",
+			validate: func(v *validator.Validator) {
+				ValidateSendMessageBody(v, SendMessageBody{
+					Payload:     "hello",
+					ClientMsgID: strings.Repeat("a", maxClientMsgIDLength+1),
+				})
+			},
+			wantValid: false,
+		},
+		{
+			name: "unknown typing state",
+			validate: func(v *validator.Validator) {
+				ValidateTypingBody(v, TypingBody{State: "thinking"})
+			},
+			wantValid: false,
+		},
+		{
+			name: "read without message",
+			validate: func(v *validator.Validator) {
+				ValidateReadBody(v, ReadBody{})
+			},
+			wantValid: false,
+		},
+		{
+			name: "delete of a nil message",
+			validate: func(v *validator.Validator) {
+				ValidateDeleteBody(v, DeleteBody{MsgID: &nilMsgID})
+			},
+			wantValid: false,
+		},
+		{
+			name: "valid delete",
+			validate: func(v *validator.Validator) {
+				ValidateDeleteBody(v, DeleteBody{MsgID: &testMsgID})
+			},
+			wantValid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			tt.validate(v)
+			if v.Valid() != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v, errors %v", v.Valid(), tt.wantValid, v.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import "our-little-chatik/internal/models"
+
+// Membership tells whether the user participates in the chat and, if so,
+// the kind of the chat along with the role of the user in it.
+type Membership struct {
+	IsMember bool
+	Kind     models.ChatKind
+	Role     models.ChatRole
+}
+
+// CanPost tells whether the user may send messages to the chat.
+func (m Membership) CanPost() bool {
+	return m.IsMember && m.Kind.CanPost(m.Role)
+}
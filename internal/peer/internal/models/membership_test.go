@@ -0,0 +1,47 @@
+package models
+
+import (
+	"our-little-chatik/internal/models"
+	"testing"
+)
+
+func TestMembership_CanPost(t *testing.T) {
+	tests := []struct {
+		name       string
+		membership Membership
+		want       bool
+	}{
+		{
+			name:       "member of a group",
+			membership: Membership{IsMember: true, Kind: models.GroupChat, Role: models.MemberRole},
+			want:       true,
+		},
+		{
+			name:       "member of a channel",
+			membership: Membership{IsMember: true, Kind: models.ChannelChat, Role: models.MemberRole},
+			want:       false,
+		},
+		{
+			name:       "admin of a channel",
+			membership: Membership{IsMember: true, Kind: models.ChannelChat, Role: models.AdminRole},
+			want:       true,
+		},
+		{
+			name:       "owner of a channel",
+			membership: Membership{IsMember: true, Kind: models.ChannelChat, Role: models.OwnerRole},
+			want:       true,
+		},
+		{
+			name:       "not a member",
+			membership: Membership{},
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.membership.CanPost(); got != tt.want {
+				t.Errorf("CanPost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
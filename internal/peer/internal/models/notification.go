@@ -1,19 +1,6 @@
 package models
 
-type NotificationType string
-
-const (
-	InfoMessage NotificationType = "info"
-	ChatMessage NotificationType = "chat"
-)
-
-// Notification is a type that gets encoded into a json document when communicating
-// with peer service. Type field describes whether the notification has service info
-// purpose or it is a message. Body may contain models.Message or other service structures.
-type Notification struct {
-	Type NotificationType `json:"type,omitempty"`
-	Body interface{}      `json:"body,omitempty"`
-}
+import "github.com/google/uuid"
 
 type ConnectionStatusType string
 
@@ -21,6 +8,7 @@ const (
 	Established ConnectionStatusType = "established"
 	Failed      ConnectionStatusType = "failed"
 	Conflict    ConnectionStatusType = "conflict"
+	Kicked      ConnectionStatusType = "kicked"
 )
 
 // PeerConnectionStatus is a type for notifying peer about a connection status.
@@ -28,3 +16,42 @@ type PeerConnectionStatus struct {
 	Status     ConnectionStatusType `json:"status"`
 	Properties map[string]any       `json:"properties,omitempty"`
 }
+
+type AckStatus string
+
+const (
+	Accepted  AckStatus = "accepted"
+	Duplicate AckStatus = "duplicate"
+	AckFailed AckStatus = "failed"
+)
+
+// MessageAck is a type for notifying the sender about the fate of its message.
+// ClientMsgID is the id the client has generated for the message, MsgID, CreatedAt and Seq
+// are the canonical values assigned by the service. Reason is set for failed messages only.
+type MessageAck struct {
+	Status      AckStatus `json:"status"`
+	ClientMsgID string    `json:"client_msg_id,omitempty"`
+	MsgID       uuid.UUID `json:"msg_id"`
+	ChatID      uuid.UUID `json:"chat_id"`
+	CreatedAt   int64     `json:"created_at,omitempty"`
+	Seq         int64     `json:"seq,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+type FrameErrorCode string
+
+const (
+	MalformedFrame FrameErrorCode = "malformed_frame"
+	InvalidFrame   FrameErrorCode = "invalid_frame"
+	FrameFailed    FrameErrorCode = "failed"
+	ForbiddenFrame FrameErrorCode = "forbidden"
+)
+
+// FrameError is a type for notifying peer that the frame it has sent was rejected.
+// Errors holds the validation errors of the frame fields, if there are any.
+type FrameError struct {
+	Code        FrameErrorCode    `json:"code"`
+	Frame       FrameType         `json:"frame,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"golang.org/x/exp/slog"
+	"our-little-chatik/internal/models"
+	models2 "our-little-chatik/internal/peer/internal/models"
+	"our-little-chatik/internal/pkg/proto/chats"
+)
+
+type ChatDataClient struct {
+	cl chats.ChatsClient
+}
+
+func NewChatDataClient(cl chats.ChatsClient) *ChatDataClient {
+	return &ChatDataClient{
+		cl: cl,
+	}
+}
+
+// GetMembership asks chat service whether the user participates in the chat and what they may do in it.
+func (c ChatDataClient) GetMembership(ctx context.Context, chat models.Chat,
+	user models.User) (models2.Membership, error) {
+	resp, err := c.cl.IsChatMember(ctx, &chats.ChatMemberRequest{
+		ChatID: chat.ChatID.String(),
+		UserID: user.ID.String(),
+	})
+	if err != nil {
+		return models2.Membership{}, err
+	}
+	return models2.Membership{
+		IsMember: resp.IsMember,
+		Kind:     models.ChatKind(resp.Kind),
+		Role:     models.ChatRole(resp.Role),
+	}, nil
+}
+
+// GetUserChats returns the list of chats the user participates in.
+func (c ChatDataClient) GetUserChats(ctx context.Context, user models.User) ([]models.Chat, error) {
+	resp, err := c.cl.GetUserChats(ctx, &chats.GetUserChatsRequest{UserID: user.ID.String()})
+	if err != nil {
+		return nil, err
+	}
+	chatList := make([]models.Chat, 0, len(resp.ChatIDs))
+	for _, chatIDStr := range resp.ChatIDs {
+		chatID, err := uuid.Parse(chatIDStr)
+		if err != nil {
+			slog.Error(err.Error())
+			continue
+		}
+		chatList = append(chatList, models.Chat{ChatID: chatID})
+	}
+	return chatList, nil
+}
+
+// MarkRead asks chat service to move the read cursor of the user to the message.
+func (c ChatDataClient) MarkRead(ctx context.Context, chat models.Chat,
+	user models.User, message models.Message) error {
+	_, err := c.cl.MarkRead(ctx, &chats.MarkReadRequest{
+		ChatID: chat.ChatID.String(),
+		UserID: user.ID.String(),
+		MsgID:  message.MsgID.String(),
+	})
+	return err
+}
+
+// DeleteMessage asks chat service to delete the message on behalf of the user.
+func (c ChatDataClient) DeleteMessage(ctx context.Context, chat models.Chat,
+	user models.User, message models.Message) error {
+	_, err := c.cl.DeleteMessage(ctx, &chats.DeleteMessageRequest{
+		ChatID: chat.ChatID.String(),
+		UserID: user.ID.String(),
+		MsgID:  message.MsgID.String(),
+	})
+	return err
+}
+
+// GetLastSeq returns the sequence number of the newest persisted message of the chat.
+func (c ChatDataClient) GetLastSeq(ctx context.Context, chat models.Chat) (int64, error) {
+	resp, err := c.cl.GetLastSeq(ctx, &chats.GetLastSeqRequest{ChatID: chat.ChatID.String()})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Seq, nil
+}
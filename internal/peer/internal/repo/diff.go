@@ -20,29 +20,62 @@ func NewDiffRepository(cl *redis.Client) *DiffRepository {
 	}
 }
 
+// SubscribeToChats relays the messages and the updates of the chats, the bodies of the notifications
+// are *models.Message and *models.ChatUpdateEvent respectively.
 func (r *DiffRepository) SubscribeToChats(ctx context.Context,
-	chats []models.Chat) (chan models.Message, error) {
+	chats []models.Chat) (chan models.Notification, error) {
 	chatChannels := make([]string, 0)
 	for _, chat := range chats {
 		chatChannels = append(chatChannels, fmt.Sprintf(models2.CommonFormat, "chat", chat.ChatID.String()))
 	}
 	sub := r.cl.PSubscribe(chatChannels...)
-	userMsgChan := make(chan models.Message)
+	userMsgChan := make(chan models.Notification)
 	msgChan := sub.Channel()
 	go func() {
+		defer func() {
+			err := sub.Close()
+			if err != nil {
+				slog.Error(err.Error())
+			}
+		}()
 		for {
 			select {
-			case redisMsg := <-msgChan:
-				msg := models.Message{}
-				bMsg := redisMsg.Payload
-				err := json.Unmarshal([]byte(bMsg), &msg)
+			case redisMsg, ok := <-msgChan:
+				if !ok {
+					return
+				}
+				notification, err := parseNotification(redisMsg.Payload)
 				if err != nil {
 					slog.Error(err.Error())
 					continue
 				}
-				userMsgChan <- msg
+				// Chat lists are interested only in the messages and the changes of the chats,
+				// typing and other short-living notifications are skipped.
+				var body interface{}
+				switch notification.Type {
+				case models.ChatMessage:
+					body = &models.Message{}
+				case models.UpdateMessage:
+					body = &models.ChatUpdateEvent{}
+				default:
+					continue
+				}
+				err = json.Unmarshal(notification.Body.(json.RawMessage), body)
+				if err != nil {
+					slog.Error(err.Error())
+					continue
+				}
+				span := startReceiveSpan(ctx, redisMsg.Channel, notification.Trace)
+				select {
+				case userMsgChan <- models.Notification{Type: notification.Type, Body: body}:
+					span.End()
+				case <-ctx.Done():
+					span.End()
+					return
+				}
 			case <-ctx.Done():
 				slog.Warn("finish by context")
+				return
 			}
 		}
 	}()
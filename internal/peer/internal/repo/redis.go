@@ -5,121 +5,295 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slog"
 	"log"
 	"our-little-chatik/internal/models"
+	models2 "our-little-chatik/internal/peer/internal/models"
+	"our-little-chatik/internal/pkg/seq"
+	"our-little-chatik/internal/pkg/tracing"
+	"time"
 )
 
+var tracer = otel.Tracer("our-little-chatik/internal/peer/internal/repo")
+
+// startReceiveSpan starts the span of a document received from the channel, continuing
+// the trace of the publisher. It ends once the document is handed over to the subscriber.
+func startReceiveSpan(ctx context.Context, channel string, carrier map[string]string) trace.Span {
+	_, span := tracer.Start(tracing.Extract(ctx, carrier), "receive "+channel,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(semconv.MessagingSystem("redis"), semconv.MessagingDestinationName(channel)))
+	return span
+}
+
 type PeerRepository struct {
+	*seq.RedisV6Counter
 	cl *redis.Client
 }
 
 func NewPeerRepository(cl *redis.Client) *PeerRepository {
 	return &PeerRepository{
-		cl: cl,
+		RedisV6Counter: seq.NewRedisV6Counter(cl),
+		cl:             cl,
 	}
 }
 
-func parseMessage(msgStr string) (*models.Message, error) {
-	msg := models.Message{}
-	err := json.Unmarshal([]byte(msgStr), &msg)
+// busNotification is a models.Notification as it is published on a chat channel.
+// The body is kept raw, so that it can be forwarded to peers as is.
+type busNotification struct {
+	Type  models.NotificationType `json:"type"`
+	Body  json.RawMessage         `json:"body"`
+	Trace map[string]string       `json:"trace"`
+}
+
+func parseNotification(notificationStr string) (*models.Notification, error) {
+	notification := busNotification{}
+	err := json.Unmarshal([]byte(notificationStr), &notification)
 	if err != nil {
 		return nil, err
 	}
-	return &msg, nil
+	return &models.Notification{
+		Type:  notification.Type,
+		Body:  notification.Body,
+		Trace: notification.Trace,
+	}, nil
 }
 
-func (r *PeerRepository) SubscribeOnChatMessages(ctx context.Context,
-	chatChannel string, readyChan chan struct{}) chan models.Message {
+// SubscribeOnChatNotifications subscribes on messages and other notifications of the chat,
+// the body of every notification is a json.RawMessage.
+func (r *PeerRepository) SubscribeOnChatNotifications(ctx context.Context,
+	chatChannel string, readyChan chan struct{}) chan models.Notification {
 	/*
-		this goroutine exits when the application shuts down. When the pusub connection is closed,
-		the channel range loop terminates, hence terminating the goroutine
+		this goroutine exits when the context is cancelled or the application shuts down.
+		When the pubsub connection is closed, the channel loop terminates, hence terminating the goroutine
 	*/
-	messageChan := make(chan models.Message)
+	notificationChan := make(chan models.Notification)
 	go func() {
 		log.Println("starting subscriber...", chatChannel)
 		sub := r.cl.Subscribe(chatChannel)
+		defer func() {
+			err := sub.Close()
+			if err != nil {
+				slog.Error(err.Error())
+			}
+		}()
 		messages := sub.Channel()
 
 		readyChan <- struct{}{}
 		log.Println("LISTENING")
-		for message := range messages {
-			msg, err := parseMessage(message.Payload)
-			log.Println("got one", msg.Payload)
-			if err != nil {
-				slog.Error(err.Error())
-				continue
+		for {
+			select {
+			case message, ok := <-messages:
+				if !ok {
+					log.Println("SUBSCRIBER IS DOWN")
+					return
+				}
+				notification, err := parseNotification(message.Payload)
+				if err != nil {
+					slog.Error(err.Error())
+					continue
+				}
+				span := startReceiveSpan(ctx, chatChannel, notification.Trace)
+				select {
+				case notificationChan <- *notification:
+					span.End()
+				case <-ctx.Done():
+					span.End()
+					return
+				}
+			case <-ctx.Done():
+				return
 			}
-			messageChan <- *msg
 		}
-		log.Println("SUBSCRIBER IS DOWN")
-		select {
-		case <-ctx.Done():
-			err := sub.Unsubscribe(chatChannel)
+	}()
+	return notificationChan
+}
+
+// SubscribeOnChatEvents listens to the changes of chats published by chat service.
+func (r *PeerRepository) SubscribeOnChatEvents(ctx context.Context) chan models.ChatEvent {
+	eventChan := make(chan models.ChatEvent)
+	go func() {
+		sub := r.cl.Subscribe(models.ChatEventsChannel)
+		defer func() {
+			err := sub.Close()
 			if err != nil {
 				slog.Error(err.Error())
 			}
-			return
-		default:
+		}()
+		events := sub.Channel()
+		for {
+			select {
+			case message, ok := <-events:
+				if !ok {
+					return
+				}
+				event := models.ChatEvent{}
+				err := json.Unmarshal([]byte(message.Payload), &event)
+				if err != nil {
+					slog.Error(err.Error())
+					continue
+				}
+				span := startReceiveSpan(ctx, models.ChatEventsChannel, event.Trace)
+				select {
+				case eventChan <- event:
+					span.End()
+				case <-ctx.Done():
+					span.End()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
-	return messageChan
+	return eventChan
 }
 
-// SendMessageToChannel pusblishes on a redis pubsub channel
+// SendMessageToChannel pusblishes the message on a redis pubsub channel
 func (r *PeerRepository) SendMessageToChannel(ctx context.Context,
 	msg models.Message, chatChannel string) {
 	log.Println(msg.Payload, "sent to ", chatChannel)
-	bMsg, err := json.Marshal(&msg)
+	// the notification carries the trace context itself
+	msg.Trace = nil
+	r.PublishNotification(ctx, models.Notification{
+		Type: models.ChatMessage,
+		Body: &msg,
+	}, chatChannel)
+}
+
+// PublishNotification pusblishes the notification on a redis pubsub channel
+// along with the trace context of ctx.
+func (r *PeerRepository) PublishNotification(ctx context.Context,
+	notification models.Notification, chatChannel string) {
+	ctx, span := tracer.Start(ctx, "publish "+chatChannel,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystem("redis"), semconv.MessagingDestinationName(chatChannel)))
+	defer span.End()
+
+	notification.Trace = tracing.Inject(ctx)
+	bNotification, err := json.Marshal(&notification)
 	if err != nil {
 		slog.Error(err.Error())
 		return
 	}
-	err = r.cl.Publish(chatChannel, string(bMsg)).Err()
+	err = r.cl.Publish(chatChannel, string(bNotification)).Err()
 	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		log.Println("could not publish to channel", err)
 	}
 }
 
-// CheckUserExists checks whether the user exists in the SET of active chat users
-func (r *PeerRepository) CheckUserExists(ctx context.Context,
-	user string, userSet string) (bool, error) {
-	usernameTaken, err := r.cl.SIsMember(userSet, user).Result()
-	if err != nil {
-		return false, err
-	}
-	return usernameTaken, nil
+// touchConnectionScript scores the connection (ARGV[1]) in the ZSET of the user connections (KEYS[1])
+// with the time of the heartbeat (ARGV[2]), drops the entries not refreshed since ARGV[3]
+// and returns the number of the connections left. The sets of the older instances are replaced.
+const touchConnectionScript = `
+if redis.call("TYPE", KEYS[1]).ok ~= "zset" then
+	redis.call("DEL", KEYS[1])
+end
+redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", "(" .. ARGV[3])
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return redis.call("ZCARD", KEYS[1])
+`
+
+var touchConnection = redis.NewScript(touchConnectionScript)
+
+// AddConnection adds the connection to the ZSET of active connections of the user
+// and returns the number of the connections the user has.
+func (r *PeerRepository) AddConnection(ctx context.Context,
+	connID string, connSet string) (int64, error) {
+	now := time.Now()
+	return touchConnection.Run(r.cl.WithContext(ctx), []string{connSet}, connID, now.Unix(),
+		now.Add(-models2.ConnectionTTL).Unix(), models2.ConnectionTTL.Milliseconds()).Int64()
 }
 
-// CreateUser creates a new user in the SET of active chat users
-func (r *PeerRepository) CreateUser(ctx context.Context,
-	user string, userSet string) error {
-	err := r.cl.SAdd(userSet, user).Err()
-	if err != nil {
-		return err
-	}
-	return nil
+// RefreshConnection is the heartbeat of the connection, it keeps the connection
+// in the ZSET of active connections of the user for another ConnectionTTL.
+func (r *PeerRepository) RefreshConnection(ctx context.Context, connID string, connSet string) error {
+	_, err := r.AddConnection(ctx, connID, connSet)
+	return err
 }
 
-func (r *PeerRepository) RemoveUser(ctx context.Context,
-	user string, userSet string) {
-	err := r.cl.SRem(userSet, user).Err()
+// RemoveConnection removes only the given connection from the ZSET of active connections
+// of the user, so that the other devices of the user stay online.
+func (r *PeerRepository) RemoveConnection(ctx context.Context,
+	connID string, connSet string) {
+	err := r.cl.ZRem(connSet, connID).Err()
 	if err != nil {
-		log.Println("failed to remove user:", user)
+		log.Println("failed to remove connection:", connID)
 		return
 	}
-	log.Println("removed user from redis:", user)
+	log.Println("removed connection from redis:", connID)
 }
 
-func (r *PeerRepository) SaveMessage(message models.Message) error {
+// SaveMessage adds the message to the ZSET of the chat messages waiting for the flusher
+// and marks the chat as pending in the same transaction. The message carries the trace
+// context of ctx, so that the flush of the message can be linked to it.
+func (r *PeerRepository) SaveMessage(ctx context.Context, message models.Message) (err error) {
+	ctx, span := tracer.Start(ctx, "save_message",
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(semconv.DBSystemRedis))
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	message.Trace = tracing.Inject(ctx)
 	bMsg, err := json.Marshal(&message)
 	if err != nil {
 		return err
 	}
-	key := fmt.Sprintf("%s_%s", message.ChatID.String(), message.MsgID.String())
-	err = r.cl.Set(key, string(bMsg), 0).Err()
+	chatID := message.ChatID.String()
+	pipe := r.cl.TxPipeline()
+	pipe.ZAdd(fmt.Sprintf(models.MessagesKeyFormat, chatID), redis.Z{
+		Score:  float64(message.Seq),
+		Member: string(bMsg),
+	})
+	pipe.HSet(fmt.Sprintf(models.MessageSeqsKeyFormat, chatID), message.MsgID.String(), message.Seq)
+	pipe.SAdd(models.PendingChatsKey, chatID)
+	_, err = pipe.Exec()
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// ReserveClientMsgID remembers the message under the client key for the dedupe window.
+// If the key has already been reserved, the message it was reserved for is returned and false is reported.
+func (r *PeerRepository) ReserveClientMsgID(ctx context.Context, key string,
+	message models.Message, window time.Duration) (models.Message, bool, error) {
+	bMsg, err := json.Marshal(&message)
+	if err != nil {
+		return models.Message{}, false, err
+	}
+	reserved, err := r.cl.SetNX(key, string(bMsg), window).Result()
+	if err != nil {
+		return models.Message{}, false, err
+	}
+	if reserved {
+		return message, true, nil
+	}
+
+	val, err := r.cl.Get(key).Result()
+	if err != nil {
+		return models.Message{}, false, err
+	}
+	original := models.Message{}
+	err = json.Unmarshal([]byte(val), &original)
+	if err != nil {
+		return models.Message{}, false, err
+	}
+	return original, false, nil
+}
+
+// ReleaseClientMsgID drops the reservation, so the client may retry the message.
+func (r *PeerRepository) ReleaseClientMsgID(ctx context.Context, key string) {
+	err := r.cl.Del(key).Err()
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
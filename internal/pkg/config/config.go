@@ -0,0 +1,337 @@
+// Package config loads the typed configuration of a service from a YAML or TOML file,
+// the environment and the command line flags, and validates it before the service starts.
+//
+// A config is a struct, its fields are described by the tags:
+//
+//	config:"key"      the key of the field in the file and the name of its flag, the keys of
+//	                  the fields of a nested struct are prefixed with its key, e.g. redis.host
+//	env:"NAME"        the environment variable the field is read from
+//	default:"value"   the value of the field unless a source sets it
+//	required:"true"   one of the sources must set the field
+//	min:"1" max:"10"  the bounds of a numeric or a duration field
+//	secret:"true"     the value is redacted when the config is printed
+//	usage:"text"      the description of the flag
+//
+// The sources override each other in the order: defaults, file, environment, flags.
+// The supported types of the fields are string, bool, int, int64, float64 and time.Duration.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"our-little-chatik/internal/pkg/validator"
+)
+
+// FileEnv is the environment variable the path to the config file may be passed in,
+// the -config flag takes precedence over it.
+const FileEnv = "CONFIG_FILE"
+
+// Validator is implemented by the configs, or their nested structs, that need checks
+// the tags cannot express. The errors are reported under the keys of the struct.
+type Validator interface {
+	Validate(v *validator.Validator)
+}
+
+// Error lists every problem found in the config by its key.
+type Error struct {
+	Errors map[string]string
+}
+
+func (e *Error) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	problems := make([]string, 0, len(keys))
+	for _, key := range keys {
+		problems = append(problems, key+": "+e.Errors[key])
+	}
+	return "invalid configuration: " + strings.Join(problems, "; ")
+}
+
+// field is a settable field of the config along with its tags.
+type field struct {
+	key      string
+	env      string
+	def      string
+	usage    string
+	min      string
+	max      string
+	required bool
+	secret   bool
+	value    reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields flattens the fields of the struct, the keys of the nested ones are prefixed with prefix.
+func fields(v reflect.Value, prefix string) []field {
+	var result []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("config")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		key = prefix + key
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			result = append(result, fields(v.Field(i), key+".")...)
+			continue
+		}
+		result = append(result, field{
+			key:      key,
+			env:      sf.Tag.Get("env"),
+			def:      sf.Tag.Get("default"),
+			usage:    sf.Tag.Get("usage"),
+			min:      sf.Tag.Get("min"),
+			max:      sf.Tag.Get("max"),
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			value:    v.Field(i),
+		})
+	}
+	return result
+}
+
+// set parses the raw value into the field.
+func (f field) set(raw string) error {
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(raw)
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.Int || f.value.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.value.SetInt(n)
+	case f.value.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		f.value.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// compare compares the value of the field with the bound, which is parsed as the field is.
+func (f field) compare(bound string) (int, error) {
+	b := field{value: reflect.New(f.value.Type()).Elem()}
+	if err := b.set(bound); err != nil {
+		return 0, err
+	}
+	switch f.value.Kind() {
+	case reflect.Int, reflect.Int64:
+		return compareOrdered(f.value.Int(), b.value.Int()), nil
+	case reflect.Float64:
+		return compareOrdered(f.value.Float(), b.value.Float()), nil
+	}
+	return 0, fmt.Errorf("bounds are not supported for %s", f.value.Type())
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// sources tells the user where the field can be set.
+func (f field) sources() string {
+	sources := []string{fmt.Sprintf("%q in the config file", f.key)}
+	if f.env != "" {
+		sources = append(sources, f.env)
+	}
+	sources = append(sources, "-"+f.key)
+	return strings.Join(sources, ", ")
+}
+
+// flagValue collects the raw values of the flags, they are applied after the other sources.
+type flagValue struct {
+	raw    map[string]string
+	key    string
+	isBool bool
+}
+
+func (v *flagValue) String() string { return "" }
+
+func (v *flagValue) Set(s string) error {
+	v.raw[v.key] = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// Load fills cfg, a pointer to a config struct, from the sources and validates it.
+// args are the command line arguments without the program name. The problems with
+// the values are reported all at once as an *Error.
+func Load(name string, cfg interface{}, args []string) error {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config must be a pointer to a struct, got %T", cfg)
+	}
+	all := fields(rv.Elem(), "")
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	file := flags.String("config", os.Getenv(FileEnv), "path to the YAML or TOML config file")
+	rawFlags := make(map[string]string)
+	for _, f := range all {
+		usage := f.usage
+		if f.env != "" {
+			usage = strings.TrimSpace(fmt.Sprintf("%s (env %s)", usage, f.env))
+		}
+		flags.Var(&flagValue{raw: rawFlags, key: f.key, isBool: f.value.Kind() == reflect.Bool}, f.key, usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	v := validator.New()
+	for _, f := range all {
+		if f.def == "" {
+			continue
+		}
+		if err := f.set(f.def); err != nil {
+			return fmt.Errorf("default of %s: %w", f.key, err)
+		}
+	}
+
+	if *file != "" {
+		values, err := readFile(*file)
+		if err != nil {
+			return err
+		}
+		known := make(map[string]bool, len(all))
+		for _, f := range all {
+			known[f.key] = true
+			raw, ok := values[f.key]
+			if !ok {
+				continue
+			}
+			if err := f.set(raw); err != nil {
+				v.AddError(f.key, fmt.Sprintf("%s in %s", err, *file))
+			}
+		}
+		for key := range values {
+			v.Check(known[key], key, fmt.Sprintf("unknown key in %s", *file))
+		}
+	}
+
+	for _, f := range all {
+		if f.env == "" {
+			continue
+		}
+		// empty variables are considered unset, as compose files pass them for the missing ones
+		if raw := os.Getenv(f.env); raw != "" {
+			if err := f.set(raw); err != nil {
+				v.AddError(f.key, fmt.Sprintf("%s in %s", err, f.env))
+			}
+		}
+	}
+
+	for _, f := range all {
+		if raw, ok := rawFlags[f.key]; ok {
+			if err := f.set(raw); err != nil {
+				v.AddError(f.key, fmt.Sprintf("%s in -%s", err, f.key))
+			}
+		}
+	}
+
+	for _, f := range all {
+		if _, failed := v.Errors[f.key]; failed {
+			continue
+		}
+		// the zero value of a field without a default stands for an unset one,
+		// while a field with a default has been zeroed by one of the sources
+		if f.value.IsZero() && (f.required || f.def == "") {
+			v.Check(!f.required, f.key, "must be set with "+f.sources())
+			continue
+		}
+		if f.min != "" {
+			cmp, err := f.compare(f.min)
+			if err != nil {
+				return fmt.Errorf("min of %s: %w", f.key, err)
+			}
+			v.Check(cmp >= 0, f.key, "must be at least "+f.min)
+		}
+		if f.max != "" {
+			cmp, err := f.compare(f.max)
+			if err != nil {
+				return fmt.Errorf("max of %s: %w", f.key, err)
+			}
+			v.Check(cmp <= 0, f.key, "must be at most "+f.max)
+		}
+	}
+	validate(v, rv.Elem(), "")
+
+	if !v.Valid() {
+		return &Error{Errors: v.Errors}
+	}
+	return nil
+}
+
+// validate runs the checks of the struct and of its nested structs, the keys of the errors
+// are prefixed with the key of the struct.
+func validate(v *validator.Validator, rv reflect.Value, prefix string) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key, ok := sf.Tag.Lookup("config")
+		if ok && sf.IsExported() && sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			validate(v, rv.Field(i), prefix+key+".")
+		}
+	}
+	if !rv.CanAddr() {
+		return
+	}
+	custom, ok := rv.Addr().Interface().(Validator)
+	if !ok {
+		return
+	}
+	nested := validator.New()
+	custom.Validate(nested)
+	for key, message := range nested.Errors {
+		v.AddError(prefix+key, message)
+	}
+}
+
+// MustLoad loads the config from the command line arguments of the process and prints
+// the effective config. It exits the process if the config is invalid.
+func MustLoad(name string, cfg interface{}) {
+	err := Load(name, cfg, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	Print(os.Stderr, cfg)
+}
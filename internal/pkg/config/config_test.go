@@ -0,0 +1,239 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"our-little-chatik/internal/pkg/validator"
+)
+
+type testConfig struct {
+	Port     int           `config:"port" env:"TEST_PORT" required:"true" min:"1" max:"65535"`
+	Period   time.Duration `config:"period" env:"TEST_PERIOD" default:"1m" min:"1s"`
+	Debug    bool          `config:"debug" env:"TEST_DEBUG"`
+	Name     string        `config:"name" env:"TEST_NAME" default:"chat"`
+	Redis    Redis         `config:"redis"`
+	Database Database      `config:"database"`
+	Ignored  string
+}
+
+func (c *testConfig) Validate(v *validator.Validator) {
+	v.Check(c.Name != "forbidden", "name", "must not be forbidden")
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setEnv sets the required variables of testConfig along with the given ones.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{FileEnv, "TEST_PORT", "TEST_PERIOD", "TEST_DEBUG", "TEST_NAME",
+		"REDIS_HOST", "REDIS_PORT", "REDIS_PASSWORD", "DATABASE_URL", "DATABASE_MAX_OPEN_CONNS",
+		"DATABASE_MAX_IDLE_CONNS", "DATABASE_MAX_IDLE_TIME"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("REDIS_HOST", "redis")
+	t.Setenv("REDIS_PASSWORD", "secret")
+	t.Setenv("DATABASE_URL", "postgres://user:secret@db/chats")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := writeFile(t, "chat.yaml", `
+port: 8080
+period: 30s
+redis:
+  host: yaml-redis
+  port: 6380
+`)
+	tomlFile := writeFile(t, "chat.toml", `
+port = 8081
+debug = true
+
+[database]
+max_open_conns = 20
+`)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		check   func(t *testing.T, cfg testConfig)
+		wantErr map[string]string
+	}{
+		{
+			name: "defaults and env",
+			env:  map[string]string{"TEST_PORT": "8083", "DATABASE_MAX_IDLE_TIME": "5m"},
+			check: func(t *testing.T, cfg testConfig) {
+				want := testConfig{
+					Port:   8083,
+					Period: time.Minute,
+					Name:   "chat",
+					Redis:  Redis{Host: "redis", Port: 6379, Password: "secret"},
+					Database: Database{
+						URL:          "postgres://user:secret@db/chats",
+						MaxOpenConns: 10,
+						MaxIdleConns: 10,
+						MaxIdleTime:  5 * time.Minute,
+					},
+				}
+				if !reflect.DeepEqual(cfg, want) {
+					t.Errorf("Load() = %+v, want %+v", cfg, want)
+				}
+			},
+		},
+		{
+			name: "yaml file is overridden by env and flags",
+			env:  map[string]string{"REDIS_HOST": "env-redis", "TEST_PERIOD": "45s"},
+			args: []string{"-config", yamlFile, "-period", "2m", "-debug"},
+			check: func(t *testing.T, cfg testConfig) {
+				if cfg.Port != 8080 || cfg.Redis.Port != 6380 {
+					t.Errorf("Load() did not read the file, got %+v", cfg)
+				}
+				if cfg.Redis.Host != "env-redis" {
+					t.Errorf("Load() redis.host = %v, want env-redis", cfg.Redis.Host)
+				}
+				if cfg.Period != 2*time.Minute || !cfg.Debug {
+					t.Errorf("Load() did not apply the flags, got %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "toml file from the env",
+			env:  map[string]string{FileEnv: tomlFile},
+			check: func(t *testing.T, cfg testConfig) {
+				if cfg.Port != 8081 || !cfg.Debug || cfg.Database.MaxOpenConns != 20 {
+					t.Errorf("Load() did not read the file, got %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "every problem is reported",
+			env: map[string]string{
+				"REDIS_HOST":              "",
+				"REDIS_PORT":              "redis",
+				"TEST_PERIOD":             "10ms",
+				"DATABASE_MAX_OPEN_CONNS": "0",
+			},
+			args: []string{"-name", "forbidden"},
+			wantErr: map[string]string{
+				"port":                    "must be set with \"port\" in the config file, TEST_PORT, -port",
+				"period":                  "must be at least 1s",
+				"name":                    "must not be forbidden",
+				"redis.host":              "must be set with \"redis.host\" in the config file, REDIS_HOST, -redis.host",
+				"redis.port":              "invalid integer \"redis\" in REDIS_PORT",
+				"database.max_open_conns": "must be at least 1",
+			},
+		},
+		{
+			name: "out of bounds",
+			args: []string{"-port", "70000"},
+			wantErr: map[string]string{
+				"port": "must be at most 65535",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			cfg := testConfig{}
+			err := Load("test", &cfg, tt.args)
+			if tt.wantErr != nil {
+				var cfgErr *Error
+				if !errors.As(err, &cfgErr) {
+					t.Fatalf("Load() error = %v, want *Error", err)
+				}
+				if !reflect.DeepEqual(cfgErr.Errors, tt.wantErr) {
+					t.Errorf("Load() errors = %v, want %v", cfgErr.Errors, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown key",
+			file:    "chat.yaml",
+			content: "port: 8080\nredis:\n  hots: redis\n",
+			want:    "redis.hots: unknown key",
+		},
+		{
+			name:    "unsupported extension",
+			file:    "chat.json",
+			content: "{}",
+			want:    "unsupported config file extension",
+		},
+		{
+			name:    "malformed file",
+			file:    "chat.toml",
+			content: "port = ",
+			want:    "failed to decode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, nil)
+			path := writeFile(t, tt.file, tt.content)
+			err := Load("test", &testConfig{}, []string{"-config", path})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	cfg := testConfig{
+		Port:     8080,
+		Period:   time.Minute,
+		Redis:    Redis{Host: "redis", Port: 6379, Password: "secret"},
+		Database: Database{URL: "postgres://user:secret@db/chats"},
+	}
+	buf := bytes.Buffer{}
+	Print(&buf, &cfg)
+
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Errorf("Print() revealed a secret:\n%s", out)
+	}
+	for _, line := range []string{
+		"port = 8080\n",
+		"period = 1m0s\n",
+		"redis.host = \"redis\"\n",
+		"redis.password = " + redacted + "\n",
+		"database.url = " + redacted + "\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Print() output lacks %q:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "Ignored") {
+		t.Errorf("Print() printed a field without the config tag:\n%s", out)
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes the YAML or TOML file, told apart by the extension, into the raw values
+// by their flattened keys, so that they are parsed the same way the env and the flags are.
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the config file: %w", err)
+	}
+
+	doc := make(map[string]interface{})
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &doc)
+	case ".toml":
+		err = toml.Unmarshal(content, &doc)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, use .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten(values, doc, "")
+	return values, nil
+}
+
+func flatten(values map[string]string, doc map[string]interface{}, prefix string) {
+	for key, value := range doc {
+		if section, ok := value.(map[string]interface{}); ok {
+			flatten(values, section, prefix+key+".")
+			continue
+		}
+		values[prefix+key] = fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// redacted replaces the values of the secret fields.
+const redacted = "[REDACTED]"
+
+// Print writes the effective config, one key = value line per field, with the secrets redacted.
+func Print(w io.Writer, cfg interface{}) {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	for _, f := range fields(rv, "") {
+		fmt.Fprintf(w, "%s = %s\n", f.key, f.display())
+	}
+}
+
+func (f field) display() string {
+	if f.secret && !f.value.IsZero() {
+		return redacted
+	}
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	if f.value.Kind() == reflect.String {
+		return fmt.Sprintf("%q", f.value.String())
+	}
+	return fmt.Sprint(f.value.Interface())
+}
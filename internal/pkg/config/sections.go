@@ -0,0 +1,47 @@
+package config
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redis is the connection to the redis the services share.
+type Redis struct {
+	Host     string `config:"host" env:"REDIS_HOST" required:"true"`
+	Port     int    `config:"port" env:"REDIS_PORT" default:"6379" min:"1" max:"65535"`
+	Password string `config:"password" env:"REDIS_PASSWORD" required:"true" secret:"true"`
+}
+
+// Addr is the host:port address of redis.
+func (r Redis) Addr() string {
+	return r.Host + ":" + strconv.Itoa(r.Port)
+}
+
+// Database is the pool of the connections to postgres.
+type Database struct {
+	// URL holds the credentials, hence it is a secret as a whole.
+	URL          string        `config:"url" env:"DATABASE_URL" required:"true" secret:"true"`
+	MaxOpenConns int           `config:"max_open_conns" env:"DATABASE_MAX_OPEN_CONNS" default:"10" min:"1"`
+	MaxIdleConns int           `config:"max_idle_conns" env:"DATABASE_MAX_IDLE_CONNS" default:"10" min:"0"`
+	MaxIdleTime  time.Duration `config:"max_idle_time" env:"DATABASE_MAX_IDLE_TIME" default:"10m" min:"0s"`
+}
+
+// Auth holds the key the JWT of the users are signed with.
+type Auth struct {
+	JWTKey string `config:"jwt_key" env:"JWT_SIGNED_KEY" required:"true" secret:"true"`
+}
+
+// IsPortAddr tells whether the address is in the :port form the gRPC addresses are passed in,
+// the host of a server is prepended to it as is.
+func IsPortAddr(addr string) bool {
+	return strings.HasPrefix(addr, ":") && len(addr) > 1
+}
+
+// Configure applies the limits of the pool to db.
+func (d Database) Configure(db *sql.DB) {
+	db.SetConnMaxIdleTime(d.MaxIdleTime)
+	db.SetMaxIdleConns(d.MaxIdleConns)
+	db.SetMaxOpenConns(d.MaxOpenConns)
+}
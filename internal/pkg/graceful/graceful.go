@@ -0,0 +1,151 @@
+// Package graceful stops the servers of a service on SIGINT or SIGTERM, so that they
+// stop accepting new connections and finish the requests that are in flight.
+package graceful
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+)
+
+// Config tells how long the in-flight requests are waited for.
+type Config struct {
+	// Timeout is the time the servers are given to drain, the connections still open are closed after it.
+	// It should be shorter than the time the orchestrator waits before it kills the service.
+	Timeout time.Duration `config:"timeout" env:"SHUTDOWN_TIMEOUT" default:"10s" min:"1s" usage:"time to drain the requests on shutdown"`
+}
+
+// NotifyContext returns a context that is cancelled when the service is asked to stop.
+func NotifyContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
+
+// Server is a server Run starts and stops.
+type Server struct {
+	// Name tells the server apart in the logs.
+	Name string
+	// Serve blocks until the server fails or is shut down, it returns nil in the latter case.
+	Serve func() error
+	// Shutdown stops the server from accepting new requests and waits for the in-flight ones,
+	// it closes the remaining connections once the context is done.
+	Shutdown func(ctx context.Context) error
+}
+
+// HTTP wraps the server, hijacked connections such as websockets are not waited for,
+// use RegisterOnShutdown of the server to close them.
+func HTTP(name string, srv *http.Server) Server {
+	return Server{
+		Name: name,
+		Serve: func() error {
+			return ignoreClosed(srv.ListenAndServe())
+		},
+		Shutdown: func(ctx context.Context) error {
+			err := srv.Shutdown(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				_ = srv.Close()
+			}
+			return err
+		},
+	}
+}
+
+// Echo wraps the echo server listening on the address.
+func Echo(name string, e *echo.Echo, addr string) Server {
+	return Server{
+		Name: name,
+		Serve: func() error {
+			return ignoreClosed(e.Start(addr))
+		},
+		Shutdown: func(ctx context.Context) error {
+			err := e.Shutdown(ctx)
+			if errors.Is(err, context.DeadlineExceeded) {
+				_ = e.Close()
+			}
+			return err
+		},
+	}
+}
+
+// GRPC wraps the gRPC server listening on the address.
+func GRPC(name string, s *grpc.Server, addr string) Server {
+	return Server{
+		Name: name,
+		Serve: func() error {
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			slog.Info("grpc server listening", "addr", lis.Addr().String())
+			return s.Serve(lis)
+		},
+		Shutdown: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				s.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				s.Stop()
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+func ignoreClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Run serves until the context is cancelled or one of the servers fails, then it shuts all of
+// them down within the timeout. It returns the error of the server that failed, if any.
+func Run(ctx context.Context, timeout time.Duration, servers ...Server) error {
+	failed := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv Server) {
+			err := srv.Serve()
+			if err != nil {
+				slog.Error("server failed", "server", srv.Name, "err", err.Error())
+			}
+			failed <- err
+		}(srv)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down")
+	case err = <-failed:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	done := make(chan struct{}, len(servers))
+	for _, srv := range servers {
+		go func(srv Server) {
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				slog.Error("failed to shut down gracefully", "server", srv.Name, "err", err.Error())
+			}
+			done <- struct{}{}
+		}(srv)
+	}
+	for range servers {
+		<-done
+	}
+	slog.Info("servers stopped")
+	return err
+}
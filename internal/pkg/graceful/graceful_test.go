@@ -0,0 +1,115 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// listen returns a free local address.
+func listen(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+func TestRunDrainsRequests(t *testing.T) {
+	addr := listen(t)
+	started := make(chan struct{})
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			_, _ = io.WriteString(w, "done")
+		}),
+	}
+	closed := make(chan struct{})
+	srv.RegisterOnShutdown(func() { close(closed) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- Run(ctx, time.Second, HTTP("test", srv))
+	}()
+
+	response := make(chan string, 1)
+	go func() {
+		var resp *http.Response
+		var err error
+		// the server may not listen yet
+		for i := 0; i < 50; i++ {
+			resp, err = http.Get("http://" + addr)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-started
+	cancel()
+	if got := <-response; got != "done" {
+		t.Errorf("in-flight request got %q, want done", got)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+	select {
+	case <-closed:
+	default:
+		t.Errorf("Run() did not run the shutdown hooks")
+	}
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	errListen := errors.New("address already in use")
+	stoppedServers := 0
+	failing := Server{
+		Name:     "failing",
+		Serve:    func() error { return errListen },
+		Shutdown: func(ctx context.Context) error { stoppedServers++; return nil },
+	}
+	blocked := make(chan struct{})
+	healthy := Server{
+		Name: "healthy",
+		Serve: func() error {
+			<-blocked
+			return nil
+		},
+		Shutdown: func(ctx context.Context) error {
+			close(blocked)
+			return nil
+		},
+	}
+
+	err := Run(context.Background(), time.Second, failing, healthy)
+	if !errors.Is(err, errListen) {
+		t.Errorf("Run() error = %v, want %v", err, errListen)
+	}
+	select {
+	case <-blocked:
+	default:
+		t.Errorf("Run() did not shut down the healthy server")
+	}
+	if stoppedServers != 1 {
+		t.Errorf("Run() shut down the failing server %v times, want 1", stoppedServers)
+	}
+}
@@ -0,0 +1,46 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	redisv6 "github.com/go-redis/redis"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// SQL pings the database behind the pool.
+func SQL(db *sql.DB) Check {
+	return db.PingContext
+}
+
+// Redis pings the redis server of the client.
+func Redis(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// RedisV6 pings the redis server of the client of the older go-redis the peer service uses.
+func RedisV6(client *redisv6.Client) Check {
+	return func(ctx context.Context) error {
+		return client.WithContext(ctx).Ping().Err()
+	}
+}
+
+// GRPC asks the health service of the server behind the connection whether it serves the service.
+func GRPC(conn *grpc.ClientConn, service string) Check {
+	client := grpc_health_v1.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			return fmt.Errorf("%s is %s", service, resp.GetStatus())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer implements the gRPC health checking protocol on top of the checker:
+// the server and its services are serving as long as the dependencies are reachable.
+type grpcServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	checker  *Checker
+	services map[string]struct{}
+}
+
+// RegisterGRPC registers the health service on the server, it knows the services
+// and the empty name, which stands for the whole server. Watch is not supported.
+func RegisterGRPC(s *grpc.Server, checker *Checker, services ...string) {
+	known := map[string]struct{}{"": {}}
+	for _, service := range services {
+		known[service] = struct{}{}
+	}
+	grpc_health_v1.RegisterHealthServer(s, &grpcServer{checker: checker, services: known})
+}
+
+func (s *grpcServer) Check(ctx context.Context,
+	req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if _, ok := s.services[req.GetService()]; !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	if _, ready := s.checker.Check(ctx); !ready {
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
@@ -0,0 +1,113 @@
+// Package health holds the liveness and readiness checks shared by the services.
+// Liveness tells the process is up, readiness tells its dependencies are reachable,
+// so that the traffic is not sent to a service which cannot serve it.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+
+	"our-little-chatik/internal/models"
+)
+
+// The paths every service serves the checks on.
+const (
+	LivePath  = "/healthz"
+	ReadyPath = "/readyz"
+)
+
+// statusOK is reported for a dependency that is reachable.
+const statusOK = "ok"
+
+// checkTimeout bounds a round of the checks, an unreachable dependency is reported
+// rather than waited for.
+const checkTimeout = 2 * time.Second
+
+// Check pings a dependency, it must give up once the context is done.
+type Check func(ctx context.Context) error
+
+// Checker runs the checks of the dependencies of a service.
+type Checker struct {
+	checks map[string]Check
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Add adds the check of the dependency named name.
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Check runs the checks concurrently and returns the status of every dependency,
+// which is either ok or the error of its check.
+func (c *Checker) Check(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		ready = true
+	)
+	statuses := make(map[string]string, len(c.checks))
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			status := statusOK
+			if err := check(ctx); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[name] = status
+			if status != statusOK {
+				ready = false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return statuses, ready
+}
+
+// ServeHTTP replies 200 if every dependency is reachable and 503 otherwise,
+// the statuses of the dependencies are in the body either way.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses, ready := c.Check(r.Context())
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+		failed := make([]string, 0, len(statuses))
+		for name, status := range statuses {
+			if status != statusOK {
+				failed = append(failed, name)
+			}
+		}
+		sort.Strings(failed)
+		slog.Warn("service is not ready", "dependencies", failed)
+	}
+	writeJSON(w, code, models.EnvelopIntoHttpResponse(statuses, "dependencies", code))
+}
+
+// LiveHandler replies 200 as long as the process serves HTTP.
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, models.EnvelopIntoHttpResponse(statusOK, "status", http.StatusOK))
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, response models.HttpResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	// the checks must not be cached by the proxies
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&response)
+}
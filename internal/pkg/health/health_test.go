@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"our-little-chatik/internal/models"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func TestCheckerServeHTTP(t *testing.T) {
+	redisClient, redisMock := redismock.NewClientMock()
+
+	tests := []struct {
+		name     string
+		prepare  func(c *Checker)
+		wantCode int
+		want     map[string]any
+	}{
+		{
+			name:     "no dependencies",
+			prepare:  func(c *Checker) {},
+			wantCode: http.StatusOK,
+			want:     map[string]any{},
+		},
+		{
+			name: "every dependency is reachable",
+			prepare: func(c *Checker) {
+				redisMock.ExpectPing().SetVal("PONG")
+				c.Add("postgres", ok)
+				c.Add("redis", Redis(redisClient))
+			},
+			wantCode: http.StatusOK,
+			want:     map[string]any{"postgres": "ok", "redis": "ok"},
+		},
+		{
+			name: "a dependency is gone",
+			prepare: func(c *Checker) {
+				redisMock.ExpectPing().SetErr(errors.New("connection refused"))
+				c.Add("postgres", ok)
+				c.Add("redis", Redis(redisClient))
+			},
+			wantCode: http.StatusServiceUnavailable,
+			want:     map[string]any{"postgres": "ok", "redis": "connection refused"},
+		},
+		{
+			name: "a dependency does not answer in time",
+			prepare: func(c *Checker) {
+				c.Add("users", func(ctx context.Context) error {
+					<-ctx.Done()
+					return ctx.Err()
+				})
+			},
+			wantCode: http.StatusServiceUnavailable,
+			want:     map[string]any{"users": context.DeadlineExceeded.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker()
+			tt.prepare(checker)
+			rec := httptest.NewRecorder()
+			checker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			response := models.HttpResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if got := response.Properties["dependencies"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServeHTTP() dependencies = %v, want %v", got, tt.want)
+			}
+			if err := redisMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGRPCServerCheck(t *testing.T) {
+	ready := NewChecker()
+	ready.Add("postgres", ok)
+	notReady := NewChecker()
+	notReady.Add("postgres", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	tests := []struct {
+		name     string
+		checker  *Checker
+		service  string
+		want     grpc_health_v1.HealthCheckResponse_ServingStatus
+		wantCode codes.Code
+	}{
+		{
+			name:    "whole server",
+			checker: ready,
+			want:    grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:    "registered service",
+			checker: ready,
+			service: "users.Users",
+			want:    grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:    "dependency is gone",
+			checker: notReady,
+			service: "users.Users",
+			want:    grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:     "unknown service",
+			checker:  ready,
+			service:  "chats.Chats",
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &grpcServer{checker: tt.checker, services: map[string]struct{}{"": {}, "users.Users": {}}}
+			resp, err := s.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Check() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err == nil && resp.GetStatus() != tt.want {
+				t.Errorf("Check() = %v, want %v", resp.GetStatus(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLiveHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, LivePath, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("LiveHandler() code = %v, want %v", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,35 @@
+// Package httpx holds the helpers shared by the HTTP middlewares of the services.
+package httpx
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// StatusRecorder remembers the status of the response. It lets the connection be hijacked,
+// as websocket handshakes require it, the status of a hijacked connection is 101.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Code     int
+	Hijacked bool
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w, Code: http.StatusOK}
+}
+
+func (r *StatusRecorder) WriteHeader(code int) {
+	r.Code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	r.Code = http.StatusSwitchingProtocols
+	r.Hijacked = true
+	return hijacker.Hijack()
+}
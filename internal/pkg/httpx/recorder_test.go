@@ -0,0 +1,80 @@
+package httpx
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hijackableRecorder is a response writer the connection of which can be hijacked.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (r hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestStatusRecorder(t *testing.T) {
+	tests := []struct {
+		name         string
+		w            http.ResponseWriter
+		serve        func(w http.ResponseWriter) error
+		wantCode     int
+		wantHijacked bool
+		wantErr      error
+	}{
+		{
+			name:     "status is not written",
+			w:        httptest.NewRecorder(),
+			serve:    func(w http.ResponseWriter) error { return nil },
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "status is written",
+			w:    httptest.NewRecorder(),
+			serve: func(w http.ResponseWriter) error {
+				w.WriteHeader(http.StatusNotFound)
+				return nil
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "connection is hijacked",
+			w:    hijackableRecorder{httptest.NewRecorder()},
+			serve: func(w http.ResponseWriter) error {
+				_, _, err := w.(http.Hijacker).Hijack()
+				return err
+			},
+			wantCode:     http.StatusSwitchingProtocols,
+			wantHijacked: true,
+		},
+		{
+			name: "connection cannot be hijacked",
+			w:    httptest.NewRecorder(),
+			serve: func(w http.ResponseWriter) error {
+				_, _, err := w.(http.Hijacker).Hijack()
+				return err
+			},
+			wantCode: http.StatusOK,
+			wantErr:  http.ErrNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := NewStatusRecorder(tt.w)
+			if err := tt.serve(recorder); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("serve error = %v, want %v", err, tt.wantErr)
+			}
+			if recorder.Code != tt.wantCode {
+				t.Errorf("Code = %v, want %v", recorder.Code, tt.wantCode)
+			}
+			if recorder.Hijacked != tt.wantHijacked {
+				t.Errorf("Hijacked = %v, want %v", recorder.Hijacked, tt.wantHijacked)
+			}
+		})
+	}
+}
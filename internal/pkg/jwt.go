@@ -53,3 +53,21 @@ func GenerateJWTTokenV2(user models.User, expireCookie bool) (string, error) {
 
 	return tokenString, nil
 }
+
+// ParseJWTToken verifies the token signature and expiration and returns its claims.
+func ParseJWTToken(tokenString string, signedKey []byte) (*JwtCustomClaims, error) {
+	claims := &JwtCustomClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return signedKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
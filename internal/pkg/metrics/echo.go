@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EchoMiddleware records the rate, the latency and the status of the requests handled by echo.
+func EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			// The error is turned into the response only by the error handler of echo, after the middlewares.
+			code := c.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					code = httpErr.Code
+				}
+			}
+			observeHTTP(c.Request().Method, c.Path(), code, time.Since(start))
+			return err
+		}
+	}
+}
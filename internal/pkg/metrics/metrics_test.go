@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEchoMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(EchoMiddleware())
+	e.GET("/chat/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/fail/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusTeapot)
+	})
+
+	tests := []struct {
+		name  string
+		path  string
+		route string
+		code  string
+	}{
+		{
+			name:  "route template is recorded",
+			path:  "/chat/1",
+			route: "/chat/:id",
+			code:  "200",
+		},
+		{
+			name:  "status of the returned error",
+			path:  "/fail/1",
+			route: "/fail/:id",
+			code:  "418",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.code))
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			after := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, tt.route, tt.code))
+			if after-before != 1 {
+				t.Errorf("EchoMiddleware() recorded %v requests, want 1", after-before)
+			}
+		})
+	}
+}
+
+func TestMuxMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(MuxMiddleware)
+	r.HandleFunc("/video/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	counter := httpRequests.WithLabelValues(http.MethodGet, "/video/{id}", "404")
+	before := testutil.ToFloat64(counter)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/video/1", nil))
+	if after := testutil.ToFloat64(counter); after-before != 1 {
+		t.Errorf("MuxMiddleware() recorded %v requests, want 1", after-before)
+	}
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"our-little-chatik/internal/pkg/httpx"
+)
+
+// MuxMiddleware records the rate, the latency and the status of the requests handled by a mux router.
+// The latency of the hijacked connections, i.e. websockets, is not recorded, as it is their lifetime.
+func MuxMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := httpx.NewStatusRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		if recorder.Hijacked {
+			httpRequests.WithLabelValues(r.Method, route, "101").Inc()
+			return
+		}
+		observeHTTP(r.Method, route, recorder.Code, time.Since(start))
+	})
+}
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.17.3
+// source: chats.proto
+
+package chats
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ChatsClient is the client API for Chats service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ChatsClient interface {
+	IsChatMember(ctx context.Context, in *ChatMemberRequest, opts ...grpc.CallOption) (*ChatMemberResponse, error)
+	GetUserChats(ctx context.Context, in *GetUserChatsRequest, opts ...grpc.CallOption) (*GetUserChatsResponse, error)
+	MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*MarkReadResponse, error)
+	GetLastSeq(ctx context.Context, in *GetLastSeqRequest, opts ...grpc.CallOption) (*GetLastSeqResponse, error)
+	DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*DeleteMessageResponse, error)
+}
+
+type chatsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChatsClient(cc grpc.ClientConnInterface) ChatsClient {
+	return &chatsClient{cc}
+}
+
+func (c *chatsClient) IsChatMember(ctx context.Context, in *ChatMemberRequest, opts ...grpc.CallOption) (*ChatMemberResponse, error) {
+	out := new(ChatMemberResponse)
+	err := c.cc.Invoke(ctx, "/chats.Chats/IsChatMember", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatsClient) GetUserChats(ctx context.Context, in *GetUserChatsRequest, opts ...grpc.CallOption) (*GetUserChatsResponse, error) {
+	out := new(GetUserChatsResponse)
+	err := c.cc.Invoke(ctx, "/chats.Chats/GetUserChats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatsClient) MarkRead(ctx context.Context, in *MarkReadRequest, opts ...grpc.CallOption) (*MarkReadResponse, error) {
+	out := new(MarkReadResponse)
+	err := c.cc.Invoke(ctx, "/chats.Chats/MarkRead", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatsClient) GetLastSeq(ctx context.Context, in *GetLastSeqRequest, opts ...grpc.CallOption) (*GetLastSeqResponse, error) {
+	out := new(GetLastSeqResponse)
+	err := c.cc.Invoke(ctx, "/chats.Chats/GetLastSeq", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatsClient) DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*DeleteMessageResponse, error) {
+	out := new(DeleteMessageResponse)
+	err := c.cc.Invoke(ctx, "/chats.Chats/DeleteMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChatsServer is the server API for Chats service.
+// All implementations must embed UnimplementedChatsServer
+// for forward compatibility
+type ChatsServer interface {
+	IsChatMember(context.Context, *ChatMemberRequest) (*ChatMemberResponse, error)
+	GetUserChats(context.Context, *GetUserChatsRequest) (*GetUserChatsResponse, error)
+	MarkRead(context.Context, *MarkReadRequest) (*MarkReadResponse, error)
+	GetLastSeq(context.Context, *GetLastSeqRequest) (*GetLastSeqResponse, error)
+	DeleteMessage(context.Context, *DeleteMessageRequest) (*DeleteMessageResponse, error)
+	mustEmbedUnimplementedChatsServer()
+}
+
+// UnimplementedChatsServer must be embedded to have forward compatible implementations.
+type UnimplementedChatsServer struct {
+}
+
+func (UnimplementedChatsServer) IsChatMember(context.Context, *ChatMemberRequest) (*ChatMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsChatMember not implemented")
+}
+func (UnimplementedChatsServer) GetUserChats(context.Context, *GetUserChatsRequest) (*GetUserChatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserChats not implemented")
+}
+func (UnimplementedChatsServer) MarkRead(context.Context, *MarkReadRequest) (*MarkReadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MarkRead not implemented")
+}
+func (UnimplementedChatsServer) GetLastSeq(context.Context, *GetLastSeqRequest) (*GetLastSeqResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLastSeq not implemented")
+}
+func (UnimplementedChatsServer) DeleteMessage(context.Context, *DeleteMessageRequest) (*DeleteMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMessage not implemented")
+}
+func (UnimplementedChatsServer) mustEmbedUnimplementedChatsServer() {}
+
+// UnsafeChatsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChatsServer will
+// result in compilation errors.
+type UnsafeChatsServer interface {
+	mustEmbedUnimplementedChatsServer()
+}
+
+func RegisterChatsServer(s grpc.ServiceRegistrar, srv ChatsServer) {
+	s.RegisterService(&Chats_ServiceDesc, srv)
+}
+
+func _Chats_IsChatMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChatMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatsServer).IsChatMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chats.Chats/IsChatMember",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatsServer).IsChatMember(ctx, req.(*ChatMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chats_GetUserChats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserChatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatsServer).GetUserChats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chats.Chats/GetUserChats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatsServer).GetUserChats(ctx, req.(*GetUserChatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chats_MarkRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarkReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatsServer).MarkRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chats.Chats/MarkRead",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatsServer).MarkRead(ctx, req.(*MarkReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chats_GetLastSeq_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLastSeqRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatsServer).GetLastSeq(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chats.Chats/GetLastSeq",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatsServer).GetLastSeq(ctx, req.(*GetLastSeqRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chats_DeleteMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatsServer).DeleteMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chats.Chats/DeleteMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatsServer).DeleteMessage(ctx, req.(*DeleteMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Chats_ServiceDesc is the grpc.ServiceDesc for Chats service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Chats_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "chats.Chats",
+	HandlerType: (*ChatsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IsChatMember",
+			Handler:    _Chats_IsChatMember_Handler,
+		},
+		{
+			MethodName: "GetUserChats",
+			Handler:    _Chats_GetUserChats_Handler,
+		},
+		{
+			MethodName: "MarkRead",
+			Handler:    _Chats_MarkRead_Handler,
+		},
+		{
+			MethodName: "GetLastSeq",
+			Handler:    _Chats_GetLastSeq_Handler,
+		},
+		{
+			MethodName: "DeleteMessage",
+			Handler:    _Chats_DeleteMessage_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "chats.proto",
+}
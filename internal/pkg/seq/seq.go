@@ -0,0 +1,118 @@
+// Package seq assigns the per-chat sequence numbers of the messages. The counters are
+// shared by every service that queues messages, so they must be advanced the same way.
+package seq
+
+import (
+	"context"
+	"fmt"
+
+	redisv6 "github.com/go-redis/redis"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"our-little-chatik/internal/models"
+)
+
+// IncrExistingScript increments the sequence counter only if it exists, 0 tells it has to be seeded first.
+const IncrExistingScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("INCR", KEYS[1])
+end
+return 0
+`
+
+// SeedScript sets the counter (KEYS[1]) unless it has been seeded meanwhile. The counter starts
+// from the newest persisted message (ARGV[1]) or from the newest queued message of the chat (KEYS[2])
+// if that one is newer, e.g. when the counter has been lost while the messages are still queued.
+const SeedScript = `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local seed = tonumber(ARGV[1])
+local newest = redis.call("ZREVRANGE", KEYS[2], 0, 0, "WITHSCORES")
+if #newest == 2 and tonumber(newest[2]) > seed then
+	seed = tonumber(newest[2])
+end
+redis.call("SET", KEYS[1], seed)
+return 1
+`
+
+// Counter keeps the sequence counters of the chats.
+type Counter interface {
+	// NextMessageSeq increments the counter, it returns 0 if the counter has not been seeded yet.
+	NextMessageSeq(ctx context.Context, seqKey string) (int64, error)
+	// SeedMessageSeq sets the counter of the chat unless it has been seeded meanwhile,
+	// the queued messages of the chat newer than lastSeq are taken into account.
+	SeedMessageSeq(ctx context.Context, chatID uuid.UUID, lastSeq int64) error
+}
+
+// LastSeqFunc returns the seq of the newest persisted message of the chat.
+type LastSeqFunc func(ctx context.Context, chatID uuid.UUID) (int64, error)
+
+// Key returns the key of the counter of the chat.
+func Key(chatID uuid.UUID) string {
+	return fmt.Sprintf(models.SeqKeyFormat, chatID.String())
+}
+
+// seedKeys returns the keys of SeedScript for the chat.
+func seedKeys(chatID uuid.UUID) []string {
+	return []string{Key(chatID), fmt.Sprintf(models.MessagesKeyFormat, chatID.String())}
+}
+
+// Next assigns the sequence number to a new message of the chat. The counter is seeded
+// from the newest persisted message when it is missing. The numbers may have gaps,
+// e.g. after failed or duplicate messages, but never go back.
+func Next(ctx context.Context, counter Counter, chatID uuid.UUID, lastSeq LastSeqFunc) (int64, error) {
+	seqKey := Key(chatID)
+	seq, err := counter.NextMessageSeq(ctx, seqKey)
+	if err != nil || seq != 0 {
+		return seq, err
+	}
+	last, err := lastSeq(ctx, chatID)
+	if err != nil {
+		return 0, err
+	}
+	err = counter.SeedMessageSeq(ctx, chatID, last)
+	if err != nil {
+		return 0, err
+	}
+	return counter.NextMessageSeq(ctx, seqKey)
+}
+
+// RedisCounter keeps the counters in redis.
+type RedisCounter struct {
+	cl     *redis.Client
+	script *redis.Script
+	seed   *redis.Script
+}
+
+func NewRedisCounter(cl *redis.Client) *RedisCounter {
+	return &RedisCounter{cl: cl, script: redis.NewScript(IncrExistingScript), seed: redis.NewScript(SeedScript)}
+}
+
+func (c *RedisCounter) NextMessageSeq(ctx context.Context, seqKey string) (int64, error) {
+	return c.script.Run(ctx, c.cl, []string{seqKey}).Int64()
+}
+
+func (c *RedisCounter) SeedMessageSeq(ctx context.Context, chatID uuid.UUID, lastSeq int64) error {
+	return c.seed.Run(ctx, c.cl, seedKeys(chatID), lastSeq).Err()
+}
+
+// RedisV6Counter keeps the counters in redis through the older go-redis the peer service uses.
+type RedisV6Counter struct {
+	cl     *redisv6.Client
+	script *redisv6.Script
+	seed   *redisv6.Script
+}
+
+func NewRedisV6Counter(cl *redisv6.Client) *RedisV6Counter {
+	return &RedisV6Counter{cl: cl, script: redisv6.NewScript(IncrExistingScript), seed: redisv6.NewScript(SeedScript)}
+}
+
+func (c *RedisV6Counter) NextMessageSeq(ctx context.Context, seqKey string) (int64, error) {
+	return c.script.Run(c.cl.WithContext(ctx), []string{seqKey}).Int64()
+}
+
+func (c *RedisV6Counter) SeedMessageSeq(ctx context.Context, chatID uuid.UUID, lastSeq int64) error {
+	return c.seed.Run(c.cl.WithContext(ctx), seedKeys(chatID), lastSeq).Err()
+}
@@ -0,0 +1,88 @@
+package seq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNext(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	counter := NewRedisCounter(db)
+	hash := redis.NewScript(IncrExistingScript).Hash()
+	seedHash := redis.NewScript(SeedScript).Hash()
+
+	chatID := uuid.New()
+	key := Key(chatID)
+	lastSeq := func(ctx context.Context, id uuid.UUID) (int64, error) {
+		if id != chatID {
+			return 0, errors.New("unexpected chat")
+		}
+		return 41, nil
+	}
+	failingLastSeq := func(ctx context.Context, id uuid.UUID) (int64, error) {
+		return 0, errors.New("chat service is down")
+	}
+
+	tests := []struct {
+		name    string
+		pre     func()
+		lastSeq LastSeqFunc
+		want    int64
+		wantErr bool
+	}{
+		{
+			name: "counter exists",
+			pre: func() {
+				mock.ExpectEvalSha(hash, []string{key}).SetVal(int64(5))
+			},
+			lastSeq: failingLastSeq,
+			want:    5,
+		},
+		{
+			name: "counter is seeded with the last persisted seq",
+			pre: func() {
+				mock.ExpectEvalSha(hash, []string{key}).SetVal(int64(0))
+				mock.ExpectEvalSha(seedHash, seedKeys(chatID), int64(41)).SetVal(int64(1))
+				mock.ExpectEvalSha(hash, []string{key}).SetVal(int64(42))
+			},
+			lastSeq: lastSeq,
+			want:    42,
+		},
+		{
+			name: "last persisted seq is unknown",
+			pre: func() {
+				mock.ExpectEvalSha(hash, []string{key}).SetVal(int64(0))
+			},
+			lastSeq: failingLastSeq,
+			wantErr: true,
+		},
+		{
+			name: "redis failure",
+			pre: func() {
+				mock.ExpectEvalSha(hash, []string{key}).SetErr(errors.New("connection refused"))
+			},
+			lastSeq: lastSeq,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pre()
+			got, err := Next(context.Background(), counter, chatID, tt.lastSeq)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Next() got = %v, want %v", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Inject returns the trace context of ctx as the carrier embedded in the documents published
+// on the message bus or queued in redis, nil if ctx carries no span.
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx along with the remote trace context stored in the carrier by Inject.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// Link links a span to the trace the carrier comes from. It is used by the spans that handle
+// many documents at once, e.g. a flush of the queued messages, as they can have one parent only.
+func Link(carrier map[string]string) (trace.Link, bool) {
+	spanCtx := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	return trace.Link{SpanContext: spanCtx}, spanCtx.IsValid()
+}
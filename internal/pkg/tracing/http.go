@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"our-little-chatik/internal/pkg/httpx"
+)
+
+const instrumentation = "our-little-chatik/internal/pkg/tracing"
+
+// startHTTPSpan starts the server span of the request, continuing the trace of the caller
+// passed in the traceparent header.
+func startHTTPSpan(r *http.Request, route string) (*http.Request, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, fmt.Sprintf("%s %s", r.Method, route),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.HTTPRoute(route),
+			semconv.HTTPTarget(r.URL.Path),
+		))
+	return r.WithContext(ctx), span
+}
+
+func endHTTPSpan(span trace.Span, code int) {
+	span.SetAttributes(semconv.HTTPStatusCode(code))
+	if code >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(code))
+	}
+	span.End()
+}
+
+// EchoMiddleware traces the requests handled by echo, the span is passed down in the request context.
+func EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			r, span := startHTTPSpan(c.Request(), c.Path())
+			c.SetRequest(r)
+			err := next(c)
+			// The error is turned into the response only by the error handler of echo, after the middlewares.
+			code := c.Response().Status
+			if err != nil {
+				span.RecordError(err)
+				code = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					code = httpErr.Code
+				}
+			}
+			endHTTPSpan(span, code)
+			return err
+		}
+	}
+}
+
+// MuxMiddleware traces the requests handled by a mux router, the span is passed down in the request context.
+// The span of a websocket ends with the handshake, the messages sent over it start traces of their own.
+func MuxMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		r, span := startHTTPSpan(r, route)
+		recorder := httpx.NewStatusRecorder(w)
+		next.ServeHTTP(recorder, r)
+		endHTTPSpan(span, recorder.Code)
+	})
+}
@@ -0,0 +1,112 @@
+// Package tracing holds the OpenTelemetry instrumentation shared by the services:
+// the exporter setup, the HTTP and gRPC propagation and the carriers embedded
+// in the documents passed over the message bus.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+
+	"our-little-chatik/internal/pkg/validator"
+)
+
+// The exporters OTEL_TRACES_EXPORTER may name.
+const (
+	OTLPExporter   = "otlp"
+	StdoutExporter = "stdout"
+	FileExporter   = "file"
+	NoneExporter   = "none"
+)
+
+// Config tells where the spans are exported to.
+type Config struct {
+	// Exporter is one of the exporters above, the spans are not recorded with NoneExporter.
+	// It defaults to OTLPExporter if OTEL_EXPORTER_OTLP_ENDPOINT is set and to NoneExporter otherwise,
+	// the OTLP exporter reads its endpoint, headers and OTEL_EXPORTER_OTLP_INSECURE by itself.
+	Exporter string `config:"exporter" env:"OTEL_TRACES_EXPORTER" usage:"otlp, stdout, file or none"`
+	// File is the file FileExporter appends the spans to.
+	File string `config:"file" env:"OTEL_TRACES_FILE"`
+}
+
+func (c *Config) Validate(v *validator.Validator) {
+	v.Check(c.Exporter == "" || validator.In(c.Exporter, OTLPExporter, StdoutExporter, FileExporter, NoneExporter),
+		"exporter", "must be one of otlp, stdout, file or none")
+	v.Check(c.Exporter != FileExporter || c.File != "", "file", "must be set for the file exporter")
+}
+
+func (c Config) exporter() string {
+	if c.Exporter != "" {
+		return c.Exporter
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" {
+		return OTLPExporter
+	}
+	return NoneExporter
+}
+
+// Init installs the global tracer provider of the service and the W3C trace context propagator.
+// OTEL_SERVICE_NAME overrides the service name. The returned function flushes the spans
+// that have not been exported yet, it must be called before the service exits.
+func Init(ctx context.Context, service string, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+	switch cfg.exporter() {
+	case NoneExporter:
+		return func(context.Context) error { return nil }, nil
+	case OTLPExporter:
+		exporter, err = otlptracegrpc.New(ctx)
+	case StdoutExporter:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case FileExporter:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("the file must be set for the %s exporter", FileExporter)
+		}
+		var file *os.File
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		closer = file
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		service = name
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
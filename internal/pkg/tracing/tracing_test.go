@@ -0,0 +1,184 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans installs a tracer provider that records the ended spans.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+	return recorder
+}
+
+func TestConfigExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		endpoint string
+		want     string
+	}{
+		{
+			name: "disabled by default",
+			want: NoneExporter,
+		},
+		{
+			name:     "otlp if the endpoint is set",
+			endpoint: "collector:4317",
+			want:     OTLPExporter,
+		},
+		{
+			name:     "exporter set explicitly",
+			exporter: StdoutExporter,
+			endpoint: "collector:4317",
+			want:     StdoutExporter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", tt.endpoint)
+			if got := (Config{Exporter: tt.exporter}).exporter(); got != tt.want {
+				t.Errorf("exporter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	if _, err := Init(context.Background(), "test", Config{Exporter: FileExporter}); err == nil {
+		t.Errorf("Init() expected an error for the file exporter without a file")
+	}
+	if _, err := Init(context.Background(), "test", Config{Exporter: "jaeger"}); err == nil {
+		t.Errorf("Init() expected an error for an unknown exporter")
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	recordSpans(t)
+
+	if carrier := Inject(context.Background()); carrier != nil {
+		t.Errorf("Inject() = %v, want nil without a span", carrier)
+	}
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "publish")
+	defer span.End()
+	carrier := Inject(ctx)
+	got := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	if got.TraceID() != span.SpanContext().TraceID() || got.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("Extract() = %v, want %v", got, span.SpanContext())
+	}
+
+	link, ok := Link(carrier)
+	if !ok || link.SpanContext.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("Link() = %v, %v, want a link to %v", link, ok, span.SpanContext())
+	}
+	if _, ok := Link(nil); ok {
+		t.Errorf("Link() reported a link for an empty carrier")
+	}
+}
+
+func TestEchoMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+	e := echo.New()
+	e.Use(EchoMiddleware())
+	e.GET("/chat/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/chat/1", nil)
+	req.Header.Set("traceparent", traceparent)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("EchoMiddleware() recorded %v spans, want 1", len(spans))
+	}
+	if got := spans[0].Name(); got != "GET /chat/:id" {
+		t.Errorf("EchoMiddleware() span name = %v, want GET /chat/:id", got)
+	}
+	if got := spans[0].Parent().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("EchoMiddleware() trace = %v, want the trace of the caller", got)
+	}
+}
+
+func TestMuxMiddleware(t *testing.T) {
+	recorder := recordSpans(t)
+	r := mux.NewRouter()
+	r.Use(MuxMiddleware)
+	r.HandleFunc("/video/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Errorf("MuxMiddleware() did not pass the span down")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/video/1", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("MuxMiddleware() recorded %v spans, want 1", len(spans))
+	}
+	if got := spans[0].Name(); got != "GET /video/{id}" {
+		t.Errorf("MuxMiddleware() span name = %v, want GET /video/{id}", got)
+	}
+	if got := spans[0].Status().Code.String(); got != "Error" {
+		t.Errorf("MuxMiddleware() span status = %v, want Error", got)
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	recorder := recordSpans(t)
+	server := UnaryServerInterceptor()
+	client := UnaryClientInterceptor()
+	const method = "/users.Users/GetUser"
+
+	// the invoker passes the outgoing metadata to the server as a transport would
+	invoker := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		_, err := server(metadata.NewIncomingContext(context.Background(), md), req,
+			&grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+		return err
+	}
+	err := client(context.Background(), method, nil, nil, nil, invoker)
+	if err != nil {
+		t.Fatalf("UnaryClientInterceptor() error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("interceptors recorded %v spans, want 2", len(spans))
+	}
+	serverSpan, clientSpan := spans[0], spans[1]
+	if serverSpan.SpanKind() != trace.SpanKindServer || clientSpan.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("interceptors recorded %v and %v spans", serverSpan.SpanKind(), clientSpan.SpanKind())
+	}
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Errorf("server span parent = %v, want the client span %v",
+			serverSpan.Parent().SpanID(), clientSpan.SpanContext().SpanID())
+	}
+}
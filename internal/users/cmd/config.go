@@ -0,0 +1,22 @@
+package main
+
+import (
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/tracing"
+	"our-little-chatik/internal/pkg/validator"
+)
+
+// Config is the configuration of users service.
+type Config struct {
+	Port     int             `config:"port" env:"USER_DATA_PORT" required:"true" min:"1" max:"65535" usage:"HTTP port"`
+	GRPCPort string          `config:"grpc_port" env:"GRPC_USERS_SERVER_PORT" required:"true" usage:"gRPC server address in the :port form"`
+	Auth     config.Auth     `config:"auth"`
+	Database config.Database `config:"database"`
+	Tracing  tracing.Config  `config:"tracing"`
+	Shutdown graceful.Config `config:"shutdown"`
+}
+
+func (c *Config) Validate(v *validator.Validator) {
+	v.Check(c.GRPCPort == "" || config.IsPortAddr(c.GRPCPort), "grpc_port", "must be in the :port form")
+}
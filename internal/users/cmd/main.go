@@ -3,121 +3,61 @@ package main
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"google.golang.org/grpc"
 	"log"
-	"net"
 	"os"
 	middleware2 "our-little-chatik/internal/middleware"
 	"our-little-chatik/internal/pkg"
+	"our-little-chatik/internal/pkg/config"
+	"our-little-chatik/internal/pkg/graceful"
+	"our-little-chatik/internal/pkg/health"
+	"our-little-chatik/internal/pkg/metrics"
 	"our-little-chatik/internal/pkg/proto/users"
+	"our-little-chatik/internal/pkg/tracing"
 	"our-little-chatik/internal/users/internal/delivery"
 	"our-little-chatik/internal/users/internal/repo"
 	"our-little-chatik/internal/users/internal/usecase"
 	"strconv"
-	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/exp/slog"
 )
 
-type AppConfig struct {
-	Port string
-}
-
-type dbConfig struct {
-	dsn          string
-	maxOpenConns int
-	maxIdleConns int
-	maxIdleTime  time.Duration
-}
-
-var (
-	defaultMaxOpenConns = 10
-	defaultMaxIdleConns = 10
-	defaultMaxIdleTime  = time.Minute * 10
-)
-
-func lookUpDatabaseConfig() *dbConfig {
-	dbCfg := &dbConfig{}
-	key, ok := os.LookupEnv("DATABASE_URL")
-	if !ok {
-		panic(errors.New("connection string not found"))
-	}
-	dbCfg.dsn = key
-
-	key, ok = os.LookupEnv("DATABASE_MAX_OPEN_CONNS")
-	if !ok {
-		dbCfg.maxOpenConns = defaultMaxOpenConns
-	} else {
-		val, err := strconv.Atoi(key)
-		if err != nil {
-			panic(err.Error())
-		}
-		dbCfg.maxOpenConns = val
-	}
-
-	key, ok = os.LookupEnv("DATABASE_MAX_IDLE_CONNS")
-	if !ok {
-		dbCfg.maxIdleConns = defaultMaxIdleConns
-	} else {
-		val, err := strconv.Atoi(key)
-		if err != nil {
-			panic(err.Error())
-		}
-		dbCfg.maxIdleConns = val
-	}
-
-	key, ok = os.LookupEnv("DATABASE_MAX_IDLE_TIME")
-	if !ok {
-		dbCfg.maxIdleTime = defaultMaxIdleTime
-	} else {
-		duration, err := time.ParseDuration(key)
-		if err != nil {
-			panic(err.Error())
-		}
-		dbCfg.maxIdleTime = duration
-	}
-
-	return dbCfg
-}
-
 func main() {
-	log.Fatal(run())
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func run() error {
-	appConfig := &AppConfig{}
-	port := os.Getenv("USER_DATA_PORT")
-	if port == "" {
-		panic("empty USER_DATA_PORT")
-	}
-	appConfig.Port = port
-
-	dbCfg := lookUpDatabaseConfig()
+	cfg := Config{}
+	config.MustLoad("users", &cfg)
 
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 
-	pool, err := pgxpool.New(context.Background(), dbCfg.dsn)
+	shutdownTracing, err := tracing.Init(context.Background(), "users", cfg.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	pool, err := pgxpool.New(context.Background(), cfg.Database.URL)
 	if err != nil {
 		log.Fatal("ERROR: : " + err.Error())
 	} else {
-		slog.Info("Connected to postgres: %s", dbCfg.dsn)
+		slog.Info("Connected to postgres")
 	}
 	defer pool.Close()
 
-	db, err := sql.Open("pgx", dbCfg.dsn)
+	db, err := sql.Open("pgx", cfg.Database.URL)
 	if err != nil {
 		panic(err)
 	}
-
-	db.SetConnMaxIdleTime(dbCfg.maxIdleTime)
-	db.SetMaxIdleConns(dbCfg.maxIdleConns)
-	db.SetMaxOpenConns(dbCfg.maxOpenConns)
+	cfg.Database.Configure(db)
 
 	userRepo := repo.NewUserRepo(db)
 	useCase := usecase.NewUserUsecase(userRepo)
@@ -132,23 +72,27 @@ func run() error {
 		Format: "method=${method}, uri=${uri}, status=${status}\n",
 	}))
 	e.Use(middleware.Recover())
+	e.Use(metrics.EchoMiddleware())
+	e.Use(tracing.EchoMiddleware())
+	e.GET(metrics.Path, echo.WrapHandler(metrics.Handler()))
+
+	checker := health.NewChecker()
+	checker.Add("postgres", health.SQL(db))
+	e.GET(health.LivePath, echo.WrapHandler(health.LiveHandler()))
+	e.GET(health.ReadyPath, echo.WrapHandler(checker))
 
-	key, err := pkg.GetSignedKey()
-	if err != nil {
-		panic(err.Error())
-	}
 	// Configure middleware with the custom claims type
-	config := echojwt.Config{
+	jwtConfig := echojwt.Config{
 		NewClaimsFunc: func(c echo.Context) jwt.Claims {
 			return new(pkg.JwtCustomClaims)
 		},
-		SigningKey:  []byte(key),
+		SigningKey:  []byte(cfg.Auth.JWTKey),
 		TokenLookup: "cookie:Token",
 	}
 
 	// Restricted group
 	authRouter := e.Group("/api/v1/auth")
-	commonRouter := e.Group("/api/v1/user", echojwt.WithConfig(config), middleware2.Auth)
+	commonRouter := e.Group("/api/v1/user", echojwt.WithConfig(jwtConfig), middleware2.Auth)
 
 	// Common API
 	// Get info about the user which calls the method.
@@ -169,27 +113,16 @@ func run() error {
 	authRouter.POST("/login", authHandler.Login)
 	// Log out method.
 	authRouter.DELETE("/logout", authHandler.Logout,
-		echojwt.WithConfig(config), middleware2.Auth)
-
-	go func() {
-		//TODO graceful shutdown + intercepting signals
-		usersGRPCPort := os.Getenv("GRPC_USERS_SERVER_PORT")
-		if usersGRPCPort == "" {
-			panic("no variable GRPC_USERS_SERVER_PORT passed")
-		}
-
-		lis, err := net.Listen("tcp", usersGRPCPort)
-		if err != nil {
-			log.Fatalf("failed to listen: %v", err)
-		}
-		s := grpc.NewServer()
-		users.RegisterUsersServer(s, grpcHandler)
-		log.Printf("grpc server listening at %v", lis.Addr())
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("failed to serve: %v", err)
-		}
-	}()
-
-	e.Logger.Fatal(e.Start(":" + appConfig.Port))
-	return nil
+		echojwt.WithConfig(jwtConfig), middleware2.Auth)
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		metrics.UnaryServerInterceptor(), tracing.UnaryServerInterceptor()))
+	users.RegisterUsersServer(s, grpcHandler)
+	health.RegisterGRPC(s, checker, users.Users_ServiceDesc.ServiceName)
+
+	ctx, stop := graceful.NotifyContext(context.Background())
+	defer stop()
+	return graceful.Run(ctx, cfg.Shutdown.Timeout,
+		graceful.Echo("http", e, ":"+strconv.Itoa(cfg.Port)),
+		graceful.GRPC("grpc", s, cfg.GRPCPort))
 }
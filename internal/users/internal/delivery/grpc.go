@@ -30,6 +30,31 @@ func (h UserGRPCHandler) GetUser(ctx context.Context, request *users.GetUserRequ
 	if status != models2.OK {
 		return nil, fmt.Errorf("failed to get user")
 	}
+	return userResponse(user), nil
+}
+
+// GetUsers returns the users found for the ids, the unknown ones are missing from the response.
+func (h UserGRPCHandler) GetUsers(ctx context.Context, request *users.GetUsersRequest) (*users.UsersResponse, error) {
+	ids := make([]uuid.UUID, 0, len(request.UserIDs))
+	for _, idStr := range request.UserIDs {
+		userID, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, userID)
+	}
+	found, status := h.useCase.GetUsers(models.GetUsersRequest{UserIDs: ids})
+	if status != models2.OK {
+		return nil, fmt.Errorf("failed to get users")
+	}
+	resp := &users.UsersResponse{Users: make([]*users.UserResponse, 0, len(found))}
+	for _, user := range found {
+		resp.Users = append(resp.Users, userResponse(user))
+	}
+	return resp, nil
+}
+
+func userResponse(user models2.User) *users.UserResponse {
 	return &users.UserResponse{
 		UserID:    user.ID.String(),
 		Name:      user.Name,
@@ -37,5 +62,5 @@ func (h UserGRPCHandler) GetUser(ctx context.Context, request *users.GetUserRequ
 		Nickname:  user.Nickname,
 		Activated: user.Activated,
 		Avatar:    user.Avatar,
-	}, nil
+	}
 }
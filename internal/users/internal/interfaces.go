@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"github.com/google/uuid"
 	internalmodels "our-little-chatik/internal/models"
 	"our-little-chatik/internal/users/internal/models"
 )
@@ -8,6 +9,7 @@ import (
 type UserRepo interface {
 	CreateUser(user internalmodels.User) (internalmodels.User, internalmodels.StatusCode)
 	GetUserForItsID(user internalmodels.User) (internalmodels.User, internalmodels.StatusCode)
+	GetUsersForTheirIDs(ids []uuid.UUID) ([]internalmodels.User, internalmodels.StatusCode)
 	GetUserForItsNickname(user internalmodels.User) (internalmodels.User, internalmodels.StatusCode)
 	DeactivateUser(user internalmodels.User) internalmodels.StatusCode
 	UpdateUser(user internalmodels.User) (internalmodels.User, internalmodels.StatusCode)
@@ -18,6 +20,7 @@ type UserUsecase interface {
 	SignUp(request models.SignUpPersonRequest) (internalmodels.User, internalmodels.StatusCode)
 	Login(request models.LoginRequest) (internalmodels.User, internalmodels.StatusCode)
 	GetUser(request models.GetUserRequest) (internalmodels.User, internalmodels.StatusCode)
+	GetUsers(request models.GetUsersRequest) ([]internalmodels.User, internalmodels.StatusCode)
 	DeactivateUser(user internalmodels.User) internalmodels.StatusCode
 	UpdateUser(userToUpdate internalmodels.User,
 		request models.UpdateUserRequest) (internalmodels.User, internalmodels.StatusCode)
@@ -13,6 +13,7 @@ import (
 	models0 "our-little-chatik/internal/users/internal/models"
 	reflect "reflect"
 
+	uuid "github.com/google/uuid"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -113,6 +114,21 @@ func (mr *MockUserRepoMockRecorder) GetUserForItsNickname(user any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserForItsNickname", reflect.TypeOf((*MockUserRepo)(nil).GetUserForItsNickname), user)
 }
 
+// GetUsersForTheirIDs mocks base method.
+func (m *MockUserRepo) GetUsersForTheirIDs(ids []uuid.UUID) ([]models.User, models.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersForTheirIDs", ids)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(models.StatusCode)
+	return ret0, ret1
+}
+
+// GetUsersForTheirIDs indicates an expected call of GetUsersForTheirIDs.
+func (mr *MockUserRepoMockRecorder) GetUsersForTheirIDs(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersForTheirIDs", reflect.TypeOf((*MockUserRepo)(nil).GetUsersForTheirIDs), ids)
+}
+
 // UpdateUser mocks base method.
 func (m *MockUserRepo) UpdateUser(user models.User) (models.User, models.StatusCode) {
 	m.ctrl.T.Helper()
@@ -195,6 +211,21 @@ func (mr *MockUserUsecaseMockRecorder) GetUser(request any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserUsecase)(nil).GetUser), request)
 }
 
+// GetUsers mocks base method.
+func (m *MockUserUsecase) GetUsers(request models0.GetUsersRequest) ([]models.User, models.StatusCode) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", request)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(models.StatusCode)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockUserUsecaseMockRecorder) GetUsers(request any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockUserUsecase)(nil).GetUsers), request)
+}
+
 // Login mocks base method.
 func (m *MockUserUsecase) Login(request models0.LoginRequest) (models.User, models.StatusCode) {
 	m.ctrl.T.Helper()
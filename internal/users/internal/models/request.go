@@ -93,3 +93,7 @@ type GetUserRequest struct {
 func ValidateGetUserRequest(v *validator.Validator, request GetUserRequest) {
 	v.Check(request.UserID != uuid.Nil, "UserID", "must be a correct value")
 }
+
+type GetUsersRequest struct {
+	UserIDs []uuid.UUID
+}
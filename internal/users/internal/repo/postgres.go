@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"github.com/google/uuid"
 	"golang.org/x/exp/slog"
 	models2 "our-little-chatik/internal/models"
 )
@@ -16,6 +17,7 @@ const (
 	GetQuery       = "SELECT user_id, nickname, user_name, surname, password, registered, avatar  FROM users WHERE user_id=$1;"
 	GetNameQuery   = "SELECT user_id, nickname, user_name, surname, password, registered, avatar  FROM users WHERE nickname=$1;"
 	FindUsersQuery = "SELECT user_id, nickname, user_name, surname, avatar FROM users WHERE nickname LIKE LOWER($1 || '%') LIMIT 10"
+	GetUsersQuery  = "SELECT user_id, nickname, user_name, surname, avatar, activated FROM users WHERE user_id = ANY($1::uuid[])"
 )
 
 type UserRepo struct {
@@ -100,6 +102,35 @@ func (pr *UserRepo) GetUserForItsNickname(user models2.User) (models2.User, mode
 	return user, models2.OK
 }
 
+// GetUsersForTheirIDs returns the users found for the ids in one query, the unknown ids are skipped.
+func (pr *UserRepo) GetUsersForTheirIDs(ids []uuid.UUID) ([]models2.User, models2.StatusCode) {
+	params := make([]string, 0, len(ids))
+	for _, id := range ids {
+		params = append(params, id.String())
+	}
+	rows, err := pr.pool.QueryContext(context.Background(), GetUsersQuery, params)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, models2.InternalError
+	}
+	defer rows.Close()
+	list := make([]models2.User, 0, len(ids))
+	for rows.Next() {
+		user := models2.User{}
+		err = rows.Scan(&user.ID, &user.Nickname, &user.Name, &user.Surname, &user.Avatar, &user.Activated)
+		if err != nil {
+			slog.Error(err.Error())
+			return nil, models2.InternalError
+		}
+		list = append(list, user)
+	}
+	if err = rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, models2.InternalError
+	}
+	return list, models2.OK
+}
+
 func (pr *UserRepo) FindUsers(name string) ([]models2.User, models2.StatusCode) {
 	rows, err := pr.pool.QueryContext(context.Background(), FindUsersQuery, name)
 	if err != nil {
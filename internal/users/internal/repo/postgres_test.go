@@ -2,6 +2,7 @@ package repo
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
 	"our-little-chatik/internal/models"
@@ -302,6 +303,86 @@ func TestUserRepo_GetUser(t *testing.T) {
 	}
 }
 
+// arrayConverter passes the array params through, as the pgx driver takes them as they are.
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if ids, ok := v.([]string); ok {
+		return ids, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestUserRepo_GetUsersForTheirIDs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	first := models.User{ID: uuid.New(), Nickname: "first", Name: "test", Surname: "test", Avatar: "avatar.png", Activated: true}
+	second := models.User{ID: uuid.New(), Nickname: "second", Name: "test", Surname: "test"}
+	ids := []uuid.UUID{first.ID, second.ID}
+	params := []string{first.ID.String(), second.ID.String()}
+
+	columns := []string{"user_id", "nickname", "user_name", "surname", "avatar", "activated"}
+
+	tests := []struct {
+		name  string
+		pre   func()
+		want  []models.User
+		want1 models.StatusCode
+	}{
+		{
+			name: "users are found",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetUsersQuery)).
+					WithArgs(params).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(first.ID.String(), first.Nickname, first.Name, first.Surname, first.Avatar, first.Activated).
+					AddRow(second.ID.String(), second.Nickname, second.Name, second.Surname, second.Avatar, second.Activated))
+			},
+			want:  []models.User{first, second},
+			want1: models.OK,
+		},
+		{
+			name: "unknown users are skipped",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetUsersQuery)).
+					WithArgs(params).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want:  []models.User{},
+			want1: models.OK,
+		},
+		{
+			name: "db failure",
+			pre: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(GetUsersQuery)).
+					WithArgs(params).WillReturnError(sql.ErrConnDone)
+			},
+			want:  nil,
+			want1: models.InternalError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := &UserRepo{
+				pool: db,
+			}
+			tt.pre()
+			got, got1 := pr.GetUsersForTheirIDs(ids)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUsersForTheirIDs() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("GetUsersForTheirIDs() got1 = %v, want %v", got1, tt.want1)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
 func TestUserRepo_GetUserForItsNickname(t *testing.T) {
 	type fields struct {
 		pool *sql.DB
@@ -61,6 +61,11 @@ func (uc *UserUsecase) GetUser(request models2.GetUserRequest) (models.User, mod
 	return uc.repo.GetUserForItsID(models.User{ID: request.UserID})
 }
 
+// GetUsers returns the users found for the ids, the unknown ones are missing from the result.
+func (uc *UserUsecase) GetUsers(request models2.GetUsersRequest) ([]models.User, models.StatusCode) {
+	return uc.repo.GetUsersForTheirIDs(request.UserIDs)
+}
+
 func (uc *UserUsecase) DeactivateUser(user models.User) models.StatusCode {
 	return uc.repo.DeactivateUser(user)
 }